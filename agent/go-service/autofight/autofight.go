@@ -1,20 +1,26 @@
 package autofight
 
 import (
+	"encoding/json"
 	"fmt"
 	"image"
-	"image/png"
-	"os"
-	"path/filepath"
+	"math/rand/v2"
+	"slices"
 	"sort"
 	"time"
 
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/debugimg"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/i18n"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/maafocus"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/override"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/runguard"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/screenshot"
 	"github.com/MaaXYZ/maa-framework-go/v4"
 	"github.com/rs/zerolog/log"
 )
 
 func getCharactorLevelShow(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
-	detail, err := ctx.RunRecognition("__AutoFightRecognitionCharactorLevelShow", arg.Img)
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionCharactorLevelShow")
 	if err != nil || detail == nil {
 		log.Error().Err(err).Msg("Failed to run recognition for combo notice")
 		return false
@@ -23,27 +29,13 @@ func getCharactorLevelShow(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool
 }
 
 func getComboUsable(ctx *maa.Context, arg *maa.CustomRecognitionArg, index int) bool {
-	var roiX int
-	switch index {
-	case 1:
-		roiX = 28
-	case 2:
-		roiX = 105
-	case 3:
-		roiX = 184
-	case 4:
-		roiX = 262
-	default:
+	if index < 1 || index > 4 {
 		log.Warn().Int("index", index).Msg("Invalid combo index")
 		return false
 	}
 
-	override := map[string]any{
-		"__AutoFightRecognitionComboUsable": map[string]any{
-			"roi": maa.Rect{roiX, 657, 56, 4},
-		},
-	}
-	detail, err := ctx.RunRecognition("__AutoFightRecognitionComboUsable", arg.Img, override)
+	o := override.Node("__AutoFightRecognitionComboUsable").Set("roi", frameRect(arg, comboRoi(index))).Map()
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionComboUsable", o)
 	if err != nil {
 		log.Error().Err(err).Int("index", index).Msg("Failed to run recognition for combo usable")
 		return false
@@ -53,13 +45,10 @@ func getComboUsable(ctx *maa.Context, arg *maa.CustomRecognitionArg, index int)
 
 func getEndSkillUsable(ctx *maa.Context, arg *maa.CustomRecognitionArg) []int {
 	usableIndexes := []int{}
-	const roiX, roiWidth = 1010, 270
-	override := map[string]any{
-		"__AutoFightRecognitionEndSkill": map[string]any{
-			"roi": maa.Rect{roiX, 535, roiWidth, 65},
-		},
-	}
-	detail, err := ctx.RunRecognition("__AutoFightRecognitionEndSkill", arg.Img, override)
+	roi := frameRect(arg, maa.Rect{1010, 535, 270, 65})
+	roiX, roiWidth := roi.X(), roi.Width()
+	o := override.Node("__AutoFightRecognitionEndSkill").Set("roi", roi).Map()
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionEndSkill", o)
 	if err != nil || detail == nil {
 		log.Error().Err(err).Msg("Failed to run recognition for end skill")
 		return usableIndexes
@@ -95,8 +84,26 @@ func getEndSkillUsable(ctx *maa.Context, arg *maa.CustomRecognitionArg) []int {
 	return usableIndexes
 }
 
+func isFightVictory(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionVictory")
+	if err != nil || detail == nil {
+		log.Error().Err(err).Msg("Failed to run recognition for AutoFightRecognitionVictory")
+		return false
+	}
+	return detail.Hit
+}
+
+func isFightDefeat(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionDefeat")
+	if err != nil || detail == nil {
+		log.Error().Err(err).Msg("Failed to run recognition for AutoFightRecognitionDefeat")
+		return false
+	}
+	return detail.Hit
+}
+
 func hasComboShow(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
-	detail, err := ctx.RunRecognition("__AutoFightRecognitionComboNotice", arg.Img)
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionComboNotice")
 	if err != nil || detail == nil {
 		log.Error().Err(err).Msg("Failed to run recognition for combo notice")
 		return false
@@ -105,7 +112,7 @@ func hasComboShow(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
 }
 
 func hasEnemyAttack(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
-	detail, err := ctx.RunRecognition("__AutoFightRecognitionEnemyAttack", arg.Img)
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionEnemyAttack")
 	if err != nil || detail == nil {
 		log.Error().Err(err).Msg("Failed to run recognition for enemy attack")
 		return false
@@ -113,8 +120,46 @@ func hasEnemyAttack(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
 	return detail.Hit
 }
 
+func isTargetLocked(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionTargetLocked")
+	if err != nil || detail == nil {
+		log.Error().Err(err).Msg("Failed to run recognition for AutoFightRecognitionTargetLocked")
+		return false
+	}
+	return detail.Hit
+}
+
+// hasEnemyInScreen 识别屏幕上是否有敌人。
+// 尚未发现敌人时，未命中后重新截图重试 enemyRetries 次，避免单帧误判推迟遭遇战开始时的锁定。
 func hasEnemyInScreen(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
-	detail, err := ctx.RunRecognition("__AutoFightRecognitionEnemyInScreen", arg.Img)
+	if enemyInScreenOnce(ctx, arg) {
+		return true
+	}
+	if enemyInScreen {
+		return false
+	}
+	for attempt := 1; attempt <= enemyRetries; attempt++ {
+		if ctx.GetTasker().Stopping() {
+			return false
+		}
+		img, err := screenshot.FreshFrame(ctx)
+		if err != nil {
+			log.Warn().Err(err).Int("attempt", attempt).Msg("Failed to capture fresh image for enemy retry")
+			continue
+		}
+		// 新截图不命中帧内缓存，也不会写入缓存
+		retryArg := *arg
+		retryArg.Img = img
+		if enemyInScreenOnce(ctx, &retryArg) {
+			log.Debug().Int("attempt", attempt).Int("retries", enemyRetries).Msg("Enemy found on fresh screencap")
+			return true
+		}
+	}
+	return false
+}
+
+func enemyInScreenOnce(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionEnemyInScreen")
 	if err != nil || detail == nil {
 		log.Error().Err(err).Msg("Failed to run recognition for enemy in screen")
 		return false
@@ -122,19 +167,46 @@ func hasEnemyInScreen(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
 	return detail.Hit
 }
 
+// countEnemies 按敌人血条的连通区域数估算画面内的敌人数量，识别失败时返回 -1, false
+func countEnemies(ctx *maa.Context, arg *maa.CustomRecognitionArg) (int, bool) {
+	markers, ok := enemyMarkers(ctx, arg)
+	if !ok {
+		return -1, false
+	}
+	return len(markers), true
+}
+
+// energyCellNodes 能量条每格对应的识别节点，ROI 定义在 pipeline 中。
+// 第 3 格尚未按截图标定 ROI，暂不识别，能量最多计为 2 格
+var energyCellNodes = []string{
+	"__AutoFightRecognitionEnergyLevel1",
+	"__AutoFightRecognitionEnergyLevel2",
+}
+
+// getEnergyLevel 返回已充满的能量格数，无法识别能量条时返回 -1
 func getEnergyLevel(ctx *maa.Context, arg *maa.CustomRecognitionArg) int {
-	// 第一格能量满
-	detail, err := ctx.RunRecognition("__AutoFightRecognitionEnergyLevel1", arg.Img)
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to run recognition for AutoFightRecognitionEnergyLevel1")
-		return -1
+	level := 0
+	for i, node := range energyCellNodes {
+		detail, err := runRecognition(ctx, arg, node)
+		if err != nil {
+			log.Error().Err(err).Str("node", node).Msg("Failed to run recognition for energy cell")
+			if i == 0 {
+				return -1
+			}
+			break
+		}
+		// 能量按格依次充满，遇到未满的格子即停止
+		if detail == nil || !detail.Hit {
+			break
+		}
+		level++
 	}
-	if detail != nil && detail.Hit {
-		return 1
+	if level > 0 {
+		return level
 	}
 
 	// 第一格能量空
-	detail, err = ctx.RunRecognition("__AutoFightRecognitionEnergyLevel0", arg.Img)
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionEnergyLevel0")
 	if err != nil {
 		return -1
 	}
@@ -145,7 +217,7 @@ func getEnergyLevel(ctx *maa.Context, arg *maa.CustomRecognitionArg) int {
 }
 
 func hasCharacterBar(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
-	detail, err := ctx.RunRecognition("__AutoFightRecognitionSwitchOperatorsTip", arg.Img)
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionSwitchOperatorsTip")
 	if err != nil || detail == nil {
 		log.Error().Err(err).Msg("Failed to run recognition for AutoFightRecognitionSwitchOperatorsTip")
 		return false
@@ -154,7 +226,7 @@ func hasCharacterBar(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
 }
 
 func inFightSpace(ctx *maa.Context, arg *maa.CustomRecognitionArg) bool {
-	detail, err := ctx.RunRecognition("__AutoFightRecognitionFightSpace", arg.Img)
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionFightSpace")
 	if err != nil || detail == nil {
 		log.Error().Err(err).Msg("Failed to run recognition for AutoFightRecognitionFightSpace")
 		return false
@@ -188,11 +260,13 @@ func (r *AutoFightEntryRecognition) Run(ctx *maa.Context, arg *maa.CustomRecogni
 	if arg == nil || arg.Img == nil {
 		return nil, false
 	}
+	resetFrameCache(arg.Img)
 	if !isEntryFightScene(ctx, arg) {
 		return nil, false
 	}
+	applyAutoFightParam(arg.CustomRecognitionParam, true)
 
-	detail, err := ctx.RunRecognition("__AutoFightRecognitionFightSkill", arg.Img)
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionFightSkill")
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to run recognition for AutoFightRecognitionFightSkill")
 		return nil, false
@@ -206,6 +280,21 @@ func (r *AutoFightEntryRecognition) Run(ctx *maa.Context, arg *maa.CustomRecogni
 		log.Warn().Int("matchCount", len(detail.Results.Filtered)).Msg("Unexpected match count for AutoFightRecognitionFightSkill, expected 4")
 		return nil, false
 	}
+	if err := runguard.Acquire(runGuardModule, arg.TaskID); err != nil {
+		// Entry 每次识别都会重试，同一个被阻塞的任务只提示一次
+		if blockedTaskID != arg.TaskID {
+			blockedTaskID = arg.TaskID
+			log.Warn().Err(err).Int64("taskID", arg.TaskID).Msg("AutoFight is already running in another task")
+			maafocus.Print(ctx, i18n.T("autofight.already_running"))
+		}
+		return nil, false
+	}
+	blockedTaskID = 0
+	resetSessionState(ctx)
+	runTaskID = arg.TaskID
+	startActionRecord()
+	fightStartedAt = time.Now()
+	logActiveStance()
 
 	return &maa.CustomRecognitionResult{
 		Box:    arg.Roi,
@@ -213,31 +302,21 @@ func (r *AutoFightEntryRecognition) Run(ctx *maa.Context, arg *maa.CustomRecogni
 	}, true
 }
 
-var pauseNotInFightSince time.Time
+var (
+	pauseNotInFightSince time.Time
+	fightStartedAt       time.Time // 通过入口识别进入战斗的时间，用于战斗时长上限
+)
 
 // saveExitImage 将当前画面保存到 debug/autofight_exit 目录，用于排查退出时的画面。
 func saveExitImage(img image.Image, reason string) {
-	if img == nil {
-		return
-	}
-	dir := filepath.Join("debug", "autofight_exit")
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Debug().Err(err).Str("dir", dir).Msg("Failed to create debug dir for exit image")
-		return
-	}
-	name := fmt.Sprintf("%s_%s.png", reason, time.Now().Format("20060102_150405"))
-	path := filepath.Join(dir, name)
-	f, err := os.Create(path)
+	path, err := debugimg.Save(img, "autofight_exit", reason)
 	if err != nil {
-		log.Debug().Err(err).Str("path", path).Msg("Failed to create file for exit image")
+		log.Debug().Err(err).Str("reason", reason).Msg("Failed to save exit image")
 		return
 	}
-	defer f.Close()
-	if err := png.Encode(f, img); err != nil {
-		log.Debug().Err(err).Str("path", path).Msg("Failed to encode exit image")
-		return
+	if path != "" {
+		log.Info().Str("path", path).Str("reason", reason).Msg("Saved exit frame to disk")
 	}
-	log.Info().Str("path", path).Str("reason", reason).Msg("Saved exit frame to disk")
 }
 
 type AutoFightExitRecognition struct{}
@@ -246,22 +325,48 @@ func (r *AutoFightExitRecognition) Run(ctx *maa.Context, arg *maa.CustomRecognit
 	if arg == nil || arg.Img == nil {
 		return nil, false
 	}
-	// 暂停超时（不在战斗空间超过 10 秒），直接退出
-	if !pauseNotInFightSince.IsZero() && time.Since(pauseNotInFightSince) >= 10*time.Second {
-		log.Info().Dur("elapsed", time.Since(pauseNotInFightSince)).Msg("Pause timeout, exiting fight")
+	resetFrameCache(arg.Img)
+	applyAutoFightParam(arg.CustomRecognitionParam, false)
+
+	// 暂停超时（不在战斗空间超过 pauseTimeout），直接退出
+	if !pauseNotInFightSince.IsZero() && time.Since(pauseNotInFightSince) >= pauseTimeout {
+		log.Info().Dur("elapsed", time.Since(pauseNotInFightSince)).Dur("timeout", pauseTimeout).Msg("Pause timeout, exiting fight")
 		pauseNotInFightSince = time.Time{}
-		enemyInScreen = false // 下次进入 entry 后首次 Execute 再执行 LockTarget
+		resetFightState(ctx, "pause_timeout")
 		return &maa.CustomRecognitionResult{
 			Box:    arg.Roi,
 			Detail: `{"custom": "exit pause timeout"}`,
 		}, true
 	}
 
+	// 战斗时长超过上限，强制退出
+	if maxFightDuration > 0 && !fightStartedAt.IsZero() && time.Since(fightStartedAt) >= maxFightDuration {
+		log.Warn().Dur("elapsed", time.Since(fightStartedAt)).Dur("limit", maxFightDuration).Msg("Fight time limit reached, forcing exit")
+		if saveTimeoutImage {
+			saveExitImage(arg.Img, "fight_time_limit")
+		}
+		return exitFightResult(ctx, arg, "time_limit"), true
+	}
+
+	// 长时间未发现敌人，按 no_enemy_action 退出
+	if noEnemyExitPending {
+		noEnemyExitPending = false
+		return exitFightResult(ctx, arg, "no_enemy"), true
+	}
+
+	// 结算画面，退出战斗并清空未执行的动作
+	if isFightVictory(ctx, arg) {
+		return exitFightResult(ctx, arg, "victory"), true
+	}
+	if isFightDefeat(ctx, arg) {
+		return exitFightResult(ctx, arg, "defeat"), true
+	}
+
 	// 显示角色等级，退出战斗
 	// 只要在战斗，一定会显示左下角干员条
 	if getCharactorLevelShow(ctx, arg) {
 		// saveExitImage(arg.Img, "character_level_show")
-		enemyInScreen = false // 下次进入 entry 后首次 Execute 再执行 LockTarget
+		resetFightState(ctx, "charactor_level_show")
 		return &maa.CustomRecognitionResult{
 			Box:    arg.Roi,
 			Detail: `{"custom": "charactor level show"}`,
@@ -271,24 +376,90 @@ func (r *AutoFightExitRecognition) Run(ctx *maa.Context, arg *maa.CustomRecognit
 	return nil, false
 }
 
+// exitFightResult 处理带退出原因的退出：重置战斗状态，失败时按配置设置重试锚点
+func exitFightResult(ctx *maa.Context, arg *maa.CustomRecognitionArg, reason string) *maa.CustomRecognitionResult {
+	log.Info().Str("reason", reason).Msg("AutoFight exiting fight")
+	resetFightState(ctx, reason)
+
+	if reason == "defeat" && defeatRetryNode != "" {
+		if err := ctx.SetAnchor("__AutoFightDefeatAnchor", defeatRetryNode); err != nil {
+			log.Error().Err(err).Str("node", defeatRetryNode).Msg("Failed to set defeat retry anchor")
+		} else {
+			log.Info().Str("node", defeatRetryNode).Msg("Routing to defeat retry node")
+		}
+	}
+
+	detail, _ := json.Marshal(map[string]any{
+		"custom": "fight result",
+		"reason": reason,
+	})
+	return &maa.CustomRecognitionResult{
+		Box:    arg.Roi,
+		Detail: string(detail),
+	}
+}
+
+// detectPopup 依次识别 popupNodes 中的弹窗节点，返回第一个命中的节点名
+func detectPopup(ctx *maa.Context, arg *maa.CustomRecognitionArg) (string, bool) {
+	for _, node := range popupNodes {
+		detail, err := runRecognition(ctx, arg, node)
+		if err != nil || detail == nil {
+			log.Error().Err(err).Str("node", node).Msg("Failed to run recognition for popup")
+			continue
+		}
+		if detail.Hit {
+			return node, true
+		}
+	}
+	return "", false
+}
+
+// enqueueDismissPopup 入队关闭弹窗动作，队列中已有未执行的关闭动作时不重复入队
+func enqueueDismissPopup(node string) {
+	for _, fa := range actionQueue {
+		if fa.action == ActionDismissPopup {
+			return
+		}
+	}
+	log.Info().Str("node", node).Msg("Popup detected during fight, dismissing")
+	enqueueAction(fightAction{
+		executeAt: time.Now(),
+		action:    ActionDismissPopup,
+		trigger:   node,
+	})
+}
+
 type AutoFightPauseRecognition struct{}
 
 func (r *AutoFightPauseRecognition) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (*maa.CustomRecognitionResult, bool) {
 	if arg == nil || arg.Img == nil {
 		return nil, false
 	}
+	resetFrameCache(arg.Img)
+	applyAutoFightParam(arg.CustomRecognitionParam, false)
+
 	if inFightSpace(ctx, arg) {
 		pauseNotInFightSince = time.Time{}
 		return nil, false
 	}
 
+	// 战斗中弹窗可恢复，不计入暂停：入队关闭动作后交给 Execute 出队执行
+	if node, ok := detectPopup(ctx, arg); ok {
+		if !pauseNotInFightSince.IsZero() {
+			log.Info().Dur("elapsed", time.Since(pauseNotInFightSince)).Msg("Popup detected, reset pause timer")
+		}
+		pauseNotInFightSince = time.Time{}
+		enqueueDismissPopup(node)
+		return nil, false
+	}
+
 	if pauseNotInFightSince.IsZero() {
 		pauseNotInFightSince = time.Now()
-		log.Info().Msg("Not in fight space, start pause timer")
+		log.Info().Dur("timeout", pauseTimeout).Msg("Not in fight space, start pause timer")
 	}
 
-	if time.Since(pauseNotInFightSince) >= 10*time.Second {
-		log.Info().Dur("elapsed", time.Since(pauseNotInFightSince)).Msg("Pause timeout, falling through to exit")
+	if time.Since(pauseNotInFightSince) >= pauseTimeout {
+		log.Info().Dur("elapsed", time.Since(pauseNotInFightSince)).Dur("timeout", pauseTimeout).Msg("Pause timeout, falling through to exit")
 		return nil, false
 	}
 
@@ -308,7 +479,14 @@ const (
 	ActionEndSkillKeyUp
 	ActionLockTarget
 	ActionDodge
+	ActionSearch
+	ActionDismissPopup
+	ActionAttackHoldDown
+	ActionAttackHoldUp
 	ActionSleep
+	ActionTapTarget
+
+	actionTypeCount // 动作类型数量，新增类型需放在此行之前
 )
 
 func (t ActionType) String() string {
@@ -327,26 +505,215 @@ func (t ActionType) String() string {
 		return "LockTarget"
 	case ActionDodge:
 		return "Dodge"
+	case ActionSearch:
+		return "Search"
+	case ActionDismissPopup:
+		return "DismissPopup"
+	case ActionAttackHoldDown:
+		return "AttackHoldDown"
+	case ActionAttackHoldUp:
+		return "AttackHoldUp"
+	case ActionTapTarget:
+		return "TapTarget"
 	default:
 		return "Unknown"
 	}
 }
 
+const (
+	// skillVerifyDelay 技能入队后等待该时长再确认能量是否被消耗
+	skillVerifyDelay = 500 * time.Millisecond
+	// skillCastMaxRetry 技能释放失败后的最大重试次数，超过后跳过该干员
+	skillCastMaxRetry = 2
+)
+
+// pendingSkillCast 等待确认释放结果的普通技能，operator 为 0 表示没有待确认的技能
+type pendingSkillCast struct {
+	operator     int
+	energyBefore int
+	verifyAt     time.Time
+	retries      int
+}
+
+var pendingSkill pendingSkillCast
+
+// attackHoldUntil 当前蓄力普攻的松开时间
+var attackHoldUntil time.Time
+
 type fightAction struct {
 	executeAt time.Time
 	action    ActionType
 	operator  int
+	target    maa.Rect // ActionTapTarget 的点击位置
+	trigger   string   // 入队原因，写入动作日志便于分析
 }
 
 var (
 	actionQueue     []fightAction
-	skillCycleIndex = 1
+	skillCycleIndex = 0     // 下一个释放普通技能的干员下标，0 表示从轮转顺序开头开始
 	enemyInScreen   = false // 检查敌人是是否首次出现在屏幕
+
+	endSkillLastUsed [5]time.Time // 各干员（下标 1–4）上次释放终结技的时间
+	skillLastUsed    [5]time.Time // 各干员（下标 1–4）上次入队普通技能的时间，用于技能冷却
+	lastDodgeAt      time.Time    // 上次入队闪避的时间，用于闪避冷却
+	lastAttackAt     time.Time    // 上次入队点按普攻的时间，用于普攻节奏
+
+	targetLocked bool      // 上一帧是否识别到锁定准星
+	lockAttempts int       // 当前一轮锁定已重试的次数
+	lockVerifyAt time.Time // 到达该时间后校验是否锁定，零值表示无需校验
+
+	lastEnemySeenAt    time.Time // 最近一次在屏幕上发现敌人的时间，用于无敌人看门狗
+	noEnemyExitPending bool      // 看门狗触发退出，由 Exit 识别消费
 )
 
+// runGuardModule 自动战斗的运行令牌名，Entry 获取、退出战斗时释放
+const runGuardModule = "autofight"
+
+// runTaskID 当前持有运行令牌的任务 ID
+var runTaskID int64
+
+// blockedTaskID 最近一次因运行令牌被占用而拒绝进入战斗的任务 ID，用于只提示一次
+var blockedTaskID int64
+
+// resetFightState 退出战斗时重置战斗状态，避免残留动作在菜单或下一场战斗中触发
+func resetFightState(ctx *maa.Context, reason string) {
+	flushActionQueue(ctx)
+	stopActionRecord()
+	emitFightSummary(ctx, reason)
+	clearFightGlobals()
+	runguard.Release(runGuardModule, runTaskID)
+}
+
+// resetSessionState 进入战斗时清理上一次运行残留的状态。
+// 任务被中途停止时不会经过 Exit，包级变量会带入下一次运行（例如 enemyInScreen 为 true 时跳过首次锁定）。
+func resetSessionState(ctx *maa.Context) {
+	if len(actionQueue) > 0 || enemyInScreen || !fightStartedAt.IsZero() || !pauseNotInFightSince.IsZero() {
+		log.Info().
+			Int("queueLen", len(actionQueue)).
+			Bool("enemyInScreen", enemyInScreen).
+			Msg("AutoFight stale state from previous session, reset")
+	}
+	flushActionQueue(ctx)
+	stopActionRecord()
+	stats = fightStats{}
+	clearFightGlobals()
+}
+
+// clearFightGlobals 将战斗相关的包级变量恢复为初始值
+func clearFightGlobals() {
+	actionQueue = nil
+	pauseNotInFightSince = time.Time{}
+	skillCycleIndex = 0
+	enemyInScreen = false // 下次进入 entry 后首次 Execute 再执行 LockTarget
+	lastEnemySeenAt = time.Time{}
+	noEnemyExitPending = false
+	fightStartedAt = time.Time{}
+	endSkillLastUsed = [5]time.Time{}
+	skillLastUsed = [5]time.Time{}
+	lastDodgeAt = time.Time{}
+	lastAttackAt = time.Time{}
+	targetLocked = false
+	lockAttempts = 0
+	lockVerifyAt = time.Time{}
+	pendingSkill = pendingSkillCast{}
+	attackHoldUntil = time.Time{}
+}
+
+// isReleaseAction 松开按键/触点的动作，丢弃后按键会保持按下
+func isReleaseAction(action ActionType) bool {
+	return action == ActionEndSkillKeyUp || action == ActionAttackHoldUp
+}
+
+// flushActionQueue 清空动作队列；未执行的终结技 KeyUp 与蓄力普攻松开会立即执行，避免按键保持按下
+func flushActionQueue(ctx *maa.Context) {
+	if len(actionQueue) == 0 {
+		return
+	}
+	for _, fa := range actionQueue {
+		if isReleaseAction(fa.action) {
+			ctx.RunTask(actionName(fa.action, fa.operator))
+			recordAction(fa, time.Now())
+		}
+	}
+	log.Info().Int("dropped", len(actionQueue)).Msg("AutoFight flush action queue")
+	actionQueue = nil
+}
+
+// actionPriority 队列满时的保留优先级，数值越小越先被丢弃
+func actionPriority(action ActionType) int {
+	switch action {
+	case ActionAttack, ActionAttackHoldDown:
+		return 0
+	case ActionSearch:
+		return 1
+	case ActionDismissPopup:
+		return 5
+	case ActionSkill:
+		return 2
+	case ActionCombo:
+		return 3
+	case ActionLockTarget, ActionTapTarget:
+		return 4
+	case ActionDodge:
+		return 5
+	default:
+		return 6
+	}
+}
+
+// makeRoomInQueue 队列达到 maxQueueLen 时丢弃一个动作，优先丢弃已过期且优先级最低的动作。
+// 松开类动作（终结技 KeyUp、蓄力普攻松开）永远不会被丢弃，避免按键保持按下；若队列中没有比 incoming 优先级更低的动作，返回 false 表示丢弃 incoming。
+func makeRoomInQueue(incoming fightAction) bool {
+	now := time.Now()
+	victim := -1
+	for i, fa := range actionQueue {
+		if isReleaseAction(fa.action) {
+			continue
+		}
+		if victim < 0 {
+			victim = i
+			continue
+		}
+		v := actionQueue[victim]
+		expired, vExpired := !fa.executeAt.After(now), !v.executeAt.After(now)
+		if expired != vExpired {
+			if expired {
+				victim = i
+			}
+			continue
+		}
+		if actionPriority(fa.action) < actionPriority(v.action) {
+			victim = i
+		}
+	}
+	if victim < 0 || actionPriority(actionQueue[victim].action) > actionPriority(incoming.action) {
+		log.Debug().
+			Str("action", incoming.action.String()).
+			Int("operator", incoming.operator).
+			Int("queueLen", len(actionQueue)).
+			Msg("AutoFight queue full, drop incoming action")
+		return false
+	}
+
+	dropped := actionQueue[victim]
+	actionQueue = append(actionQueue[:victim], actionQueue[victim+1:]...)
+	log.Debug().
+		Str("action", dropped.action.String()).
+		Int("operator", dropped.operator).
+		Str("executeAt", dropped.executeAt.Format("15:04:05.000")).
+		Int("queueLen", len(actionQueue)).
+		Msg("AutoFight queue full, drop queued action")
+	return true
+}
+
 func enqueueAction(a fightAction) {
+	// 松开类动作必须入队，即使超出上限，否则按键会保持按下
+	if !isReleaseAction(a.action) && maxQueueLen > 0 && len(actionQueue) >= maxQueueLen && !makeRoomInQueue(a) {
+		return
+	}
 	actionQueue = append(actionQueue, a)
-	sort.Slice(actionQueue, func(i, j int) bool {
+	// 稳定排序：随机偏移后 executeAt 可能相同，此时按入队顺序执行
+	sort.SliceStable(actionQueue, func(i, j int) bool {
 		return actionQueue[i].executeAt.Before(actionQueue[j].executeAt)
 	})
 	log.Debug().
@@ -357,6 +724,27 @@ func enqueueAction(a fightAction) {
 		Msg("AutoFight enqueue action")
 }
 
+// cancelAttacksBefore 移除计划在 deadline 之前执行的普攻，让闪避优先
+func cancelAttacksBefore(deadline time.Time) {
+	kept := actionQueue[:0]
+	canceled := 0
+	for _, a := range actionQueue {
+		if a.action == ActionAttack && a.executeAt.Before(deadline) {
+			canceled++
+			continue
+		}
+		kept = append(kept, a)
+	}
+	actionQueue = kept
+	if canceled > 0 {
+		log.Debug().
+			Int("canceled", canceled).
+			Str("deadline", deadline.Format("15:04:05.000")).
+			Int("queueLen", len(actionQueue)).
+			Msg("AutoFight cancel queued attacks for dodge")
+	}
+}
+
 func dequeueAction() (fightAction, bool) {
 	if len(actionQueue) == 0 {
 		return fightAction{}, false
@@ -373,57 +761,364 @@ func dequeueAction() (fightAction, bool) {
 	return a, true
 }
 
-// 识别干员技能释放
-func recognitionSkill(ctx *maa.Context, arg *maa.CustomRecognitionArg) {
-	if hasComboShow(ctx, arg) {
-		// 连携技能
-		enqueueAction(fightAction{
-			executeAt: time.Now(),
-			action:    ActionCombo,
-		})
-	} else if endSkillUsable := getEndSkillUsable(ctx, arg); len(endSkillUsable) > 0 {
-		// 终结技可用
-		for _, idx := range endSkillUsable {
-			enqueueAction(fightAction{
-				executeAt: time.Now(),
-				action:    ActionEndSkillKeyDown,
-				operator:  idx,
-			})
-			enqueueAction(fightAction{
-				executeAt: time.Now().Add(1500 * time.Millisecond),
-				action:    ActionEndSkillKeyUp,
-				operator:  idx,
-			})
-			break
+// frameObservation 为同一帧上各项互相独立的识别结果
+type frameObservation struct {
+	enemyInScreen  bool
+	comboShow      bool
+	endSkillUsable []int
+	energyLevel    int
+	enemyAttack    bool
+	targetLocked   bool
+}
+
+// observeFrame 在同一帧上依次执行各项互相独立的识别并汇总结果。
+// maa.Context 未保证可并发使用，因此不并行识别；同一帧的重复识别由帧内缓存（见 cache.go）复用
+func observeFrame(ctx *maa.Context, arg *maa.CustomRecognitionArg, checkEnemy bool) frameObservation {
+	var obs frameObservation
+	if checkEnemy {
+		obs.enemyInScreen = hasEnemyInScreen(ctx, arg)
+	}
+	obs.comboShow = hasComboShow(ctx, arg)
+	obs.endSkillUsable = getEndSkillUsable(ctx, arg)
+	obs.energyLevel = getEnergyLevel(ctx, arg)
+	obs.enemyAttack = hasEnemyAttack(ctx, arg)
+	if lockRetry > 0 {
+		obs.targetLocked = isTargetLocked(ctx, arg)
+	}
+	return obs
+}
+
+// 识别干员技能释放，按 skillPriority 依次尝试，命中一项即停止
+func recognitionSkill(ctx *maa.Context, arg *maa.CustomRecognitionArg, obs frameObservation) {
+	checkSkillCast(obs)
+	for _, kind := range skillPriority {
+		var done bool
+		switch kind {
+		case priorityDodge:
+			// 闪避本身由 recognitionAttack 入队，这里只负责让其压过排在后面的技能
+			done = obs.enemyAttack
+		case priorityCombo:
+			done = tryCombo(obs)
+		case priorityEndSkill:
+			done = tryEndSkill(obs)
+		case prioritySkill:
+			done = trySkill(ctx, arg, obs)
 		}
-	} else if getEnergyLevel(ctx, arg) >= 1 {
-		idx := skillCycleIndex
-		enqueueAction(fightAction{
-			executeAt: time.Now(),
-			action:    ActionSkill,
-			operator:  idx,
+		if done {
+			return
+		}
+	}
+}
+
+// tryCombo 连携提示出现时入队连携
+func tryCombo(obs frameObservation) bool {
+	if !obs.comboShow {
+		return false
+	}
+	enqueueAction(fightAction{
+		executeAt: time.Now(),
+		action:    ActionCombo,
+		trigger:   "combo",
+	})
+	return true
+}
+
+// tryEndSkill 终结技可用时入队，只取第一个启用、不在间隔内且未处于按住中的干员
+func tryEndSkill(obs frameObservation) bool {
+	endSkillUsable := filterEndSkillHolding(filterEndSkillInterval(filterEnabledOperators(obs.endSkillUsable, endSkillEnabled)))
+	if len(endSkillUsable) == 0 {
+		return false
+	}
+	idx := endSkillUsable[0]
+	endSkillLastUsed[idx] = time.Now()
+	enqueueAction(fightAction{
+		executeAt: time.Now(),
+		action:    ActionEndSkillKeyDown,
+		trigger:   "end_skill",
+		operator:  idx,
+	})
+	enqueueAction(fightAction{
+		executeAt: time.Now().Add(endSkillHold(idx)),
+		action:    ActionEndSkillKeyUp,
+		trigger:   "end_skill",
+		operator:  idx,
+	})
+	return true
+}
+
+// endSkillHold 返回干员终结技的按住时长
+func endSkillHold(idx int) time.Duration {
+	if hold, ok := endSkillHoldDurations[idx]; ok {
+		return hold
+	}
+	return defaultEndSkillHold
+}
+
+// filterEndSkillHolding 过滤掉 KeyUp 仍在队列中的干员。
+// 按住时长可能跨越多个识别帧，此时再入队 KeyDown 会排在未执行的 KeyUp 之前，导致按键状态错乱。
+func filterEndSkillHolding(operators []int) []int {
+	ready := make([]int, 0, len(operators))
+	for _, idx := range operators {
+		holding := slices.ContainsFunc(actionQueue, func(fa fightAction) bool {
+			return fa.action == ActionEndSkillKeyUp && fa.operator == idx
 		})
-		if idx >= 4 {
-			skillCycleIndex = 1
-		} else {
-			skillCycleIndex = idx + 1
+		if holding {
+			continue
 		}
+		ready = append(ready, idx)
 	}
+	return ready
 }
 
-func recognitionAttack(ctx *maa.Context, arg *maa.CustomRecognitionArg) {
+// trySkill 能量足够时按轮转入队普通技能
+func trySkill(ctx *maa.Context, arg *maa.CustomRecognitionArg, obs frameObservation) bool {
+	// 上一次技能尚未确认释放成功前不再入队新的技能
+	if pendingSkill.operator != 0 {
+		return false
+	}
+	if obs.energyLevel < skillEnergyCost {
+		return false
+	}
+	idx, ok := nextSkillOperator(ctx, arg)
+	if !ok {
+		return false
+	}
+	skillLastUsed[idx] = time.Now()
+	enqueueAction(fightAction{
+		executeAt: time.Now(),
+		action:    ActionSkill,
+		trigger:   "skill",
+		operator:  idx,
+	})
+	if verifySkillCast {
+		pendingSkill = pendingSkillCast{
+			operator:     idx,
+			energyBefore: obs.energyLevel,
+			verifyAt:     time.Now().Add(skillVerifyDelay),
+		}
+		return true
+	}
+	advanceSkillCycle(idx)
+	return true
+}
+
+// defaultSkillOrder 未配置 skill_order 时的普通技能轮转顺序
+var defaultSkillOrder = []int{1, 2, 3, 4}
+
+// activeSkillOrder 返回当前生效的普通技能轮转顺序
+func activeSkillOrder() []int {
+	if len(skillOrder) == 0 {
+		return defaultSkillOrder
+	}
+	return skillOrder
+}
+
+// advanceSkillCycle 将 skillCycleIndex 推进到轮转顺序中 idx 之后的干员，到末尾时回到开头。
+// idx 不在当前顺序中（如战斗中途修改了 skill_order）时从头开始。
+func advanceSkillCycle(idx int) {
+	order := activeSkillOrder()
+	pos := slices.Index(order, idx)
+	skillCycleIndex = order[(pos+1)%len(order)]
+}
+
+// checkSkillCast 开启 verify_skill_cast 时，比较技能入队前后的能量格数确认技能是否释放。
+// 能量未下降视为释放失败，重新入队该技能且不推进 skillCycleIndex。
+func checkSkillCast(obs frameObservation) {
+	if pendingSkill.operator == 0 || time.Now().Before(pendingSkill.verifyAt) {
+		return
+	}
+	if obs.energyLevel < 0 {
+		// 本帧未识别到能量条，等待下一帧再确认
+		return
+	}
+	idx := pendingSkill.operator
+	if obs.energyLevel < pendingSkill.energyBefore {
+		pendingSkill = pendingSkillCast{}
+		advanceSkillCycle(idx)
+		return
+	}
+	if pendingSkill.retries >= skillCastMaxRetry {
+		log.Warn().
+			Int("operator", idx).
+			Int("retries", pendingSkill.retries).
+			Msg("AutoFight skill cast still not confirmed, skip to next operator")
+		pendingSkill = pendingSkillCast{}
+		advanceSkillCycle(idx)
+		return
+	}
+	log.Warn().
+		Int("operator", idx).
+		Int("energyBefore", pendingSkill.energyBefore).
+		Int("energyNow", obs.energyLevel).
+		Msg("AutoFight skill cast failed, energy not consumed, retry")
+	enqueueAction(fightAction{
+		executeAt: time.Now(),
+		action:    ActionSkill,
+		trigger:   "skill_retry",
+		operator:  idx,
+	})
+	pendingSkill.retries++
+	pendingSkill.energyBefore = obs.energyLevel
+	pendingSkill.verifyAt = time.Now().Add(skillVerifyDelay)
+}
+
+// operatorEnabled 判断干员（下标 1–4）在 enabled 中是否启用，未配置时视为全部启用
+func operatorEnabled(enabled []bool, idx int) bool {
+	return len(enabled) != 4 || idx < 1 || idx > 4 || enabled[idx-1]
+}
+
+// filterEnabledOperators 过滤掉在 enabled 中被禁用的干员下标
+func filterEnabledOperators(operators []int, enabled []bool) []int {
+	result := make([]int, 0, len(operators))
+	for _, idx := range operators {
+		if operatorEnabled(enabled, idx) {
+			result = append(result, idx)
+		}
+	}
+	return result
+}
+
+// enabledOperators 返回 enabled 中启用的干员下标，用于日志
+func enabledOperators(enabled []bool) []int {
+	return filterEnabledOperators([]int{1, 2, 3, 4}, enabled)
+}
+
+// filterEndSkillInterval 过滤掉距上次释放终结技不足 endSkillMinInterval 的干员
+func filterEndSkillInterval(operators []int) []int {
+	if endSkillMinInterval <= 0 {
+		return operators
+	}
+	ready := make([]int, 0, len(operators))
+	for _, idx := range operators {
+		if last := endSkillLastUsed[idx]; !last.IsZero() && time.Since(last) < endSkillMinInterval {
+			log.Debug().
+				Int("operator", idx).
+				Dur("sinceLast", time.Since(last)).
+				Dur("minInterval", endSkillMinInterval).
+				Msg("End skill suppressed by min interval")
+			continue
+		}
+		ready = append(ready, idx)
+	}
+	return ready
+}
+
+// nextSkillOperator 按轮转顺序从 skillCycleIndex 开始找到下一个可释放普通技能的干员，均不可释放时返回 false。
+// 禁用和冷却中的干员直接跳过；群攻干员仅在敌人数量达到 aoeMinEnemies 时释放。
+func nextSkillOperator(ctx *maa.Context, arg *maa.CustomRecognitionArg) (int, bool) {
+	order := activeSkillOrder()
+	start := max(slices.Index(order, skillCycleIndex), 0)
+	enemyCount, enemyCounted := -1, false
+	for i := range order {
+		idx := order[(start+i)%len(order)]
+		if !operatorEnabled(skillEnabled, idx) || skillOnCooldown(idx) {
+			continue
+		}
+		if slices.Contains(aoeSkillOperators, idx) {
+			if !enemyCounted {
+				enemyCounted = true
+				var ok bool
+				enemyCount, ok = countEnemies(ctx, arg)
+				log.Debug().Int("enemies", enemyCount).Bool("recognized", ok).Int("min", aoeMinEnemies).Msg("AutoFight enemy count for AOE skill")
+			}
+			// 识别失败时敌人数量未知，按原行为放行群攻技能
+			if enemyCount >= 0 && enemyCount < aoeMinEnemies {
+				continue
+			}
+		}
+		return idx, true
+	}
+	return 0, false
+}
+
+// skillOnCooldown 判断干员距上次入队普通技能是否不足 skillCooldown
+func skillOnCooldown(idx int) bool {
+	last := skillLastUsed[idx]
+	return skillCooldown > 0 && !last.IsZero() && time.Since(last) < skillCooldown
+}
+
+func recognitionAttack(ctx *maa.Context, obs frameObservation) {
 	// 识别闪避、普攻
-	if hasEnemyAttack(ctx, arg) {
+	if obs.enemyAttack {
+		// 同一次攻击前摇会持续多帧，冷却内不再重复闪避
+		if !lastDodgeAt.IsZero() && time.Since(lastDodgeAt) < dodgeCooldown {
+			return
+		}
+		lastDodgeAt = time.Now()
+		if dodgeWindow > 0 {
+			cancelAttacksBefore(lastDodgeAt.Add(dodgeWindow))
+		}
 		enqueueAction(fightAction{
-			executeAt: time.Now().Add(100 * time.Millisecond),
+			executeAt: time.Now().Add(dodgeDelay + jitter()),
 			action:    ActionDodge,
+			trigger:   "dodge",
 		})
 	} else {
+		enqueueAttack(ctx)
+	}
+}
+
+// jitter 返回 [jitterMin, jitterMax] 内均匀分布的随机偏移，用于打散普攻与闪避的执行时间
+func jitter() time.Duration {
+	if jitterMax <= jitterMin {
+		return jitterMin
+	}
+	return jitterMin + rand.N(jitterMax-jitterMin+1)
+}
+
+// enqueueAttack 按当前操控干员的配置入队点按普攻或蓄力普攻
+// attackAnchorEnabled 判断普攻锚点是否指向实际的普攻节点；半自动接口将其置空，此时不应普攻
+func attackAnchorEnabled(ctx *maa.Context) bool {
+	node, err := ctx.GetAnchor("__AutoFightActionAttackAnchor")
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to get attack anchor, treat as no attack")
+		return false
+	}
+	return node != ""
+}
+
+func enqueueAttack(ctx *maa.Context) {
+	hold := attackHoldDurations[controlledOperator]
+	if hold <= 0 {
+		// 按 attack_interval_ms 限制普攻节奏，避免每帧入队导致队列堆积和输入丢失
+		if !lastAttackAt.IsZero() && time.Since(lastAttackAt) < attackInterval {
+			log.Debug().
+				Dur("sinceLast", time.Since(lastAttackAt)).
+				Dur("interval", attackInterval).
+				Msg("AutoFight attack throttled")
+			return
+		}
+		lastAttackAt = time.Now()
 		enqueueAction(fightAction{
-			executeAt: time.Now(),
+			executeAt: time.Now().Add(jitter()),
 			action:    ActionAttack,
+			trigger:   "attack",
 		})
+		return
+	}
+	// 上一次蓄力尚未松开时不再入队
+	if time.Now().Before(attackHoldUntil) {
+		return
 	}
+	// 蓄力普攻直接按下触点，不经过普攻锚点，需自行遵循半自动接口的不普攻约定
+	if !attackAnchorEnabled(ctx) {
+		return
+	}
+	// 按下与松开整体偏移，保持按住时长不变
+	holdAt := time.Now().Add(jitter())
+	attackHoldUntil = holdAt.Add(hold)
+	enqueueAction(fightAction{
+		executeAt: holdAt,
+		action:    ActionAttackHoldDown,
+		trigger:   "charged_attack",
+		operator:  controlledOperator,
+	})
+	enqueueAction(fightAction{
+		executeAt: attackHoldUntil,
+		action:    ActionAttackHoldUp,
+		trigger:   "charged_attack",
+		operator:  controlledOperator,
+	})
 }
 
 type AutoFightExecuteRecognition struct{}
@@ -432,20 +1127,24 @@ func (r *AutoFightExecuteRecognition) Run(ctx *maa.Context, arg *maa.CustomRecog
 	if arg == nil || arg.Img == nil {
 		return nil, false
 	}
-	if !enemyInScreen && hasEnemyInScreen(ctx, arg) {
-		enemyInScreen = true
-		enqueueAction(fightAction{
-			executeAt: time.Now().Add(time.Millisecond),
-			action:    ActionLockTarget,
-		})
+	resetFrameCache(arg.Img)
+	// 开启看门狗时每帧都检查敌人，否则只在锁定前检查
+	obs := observeFrame(ctx, arg, !enemyInScreen || noEnemyTimeout > 0)
+	if obs.enemyInScreen {
+		lastEnemySeenAt = time.Now()
+		if !enemyInScreen {
+			enemyInScreen = true
+			startFightStats()
+			enqueueLockTarget(ctx, arg)
+		}
 	}
+	checkNoEnemyWatchdog()
+	checkTargetLock(ctx, arg, obs)
 
 	if enemyInScreen {
-		recognitionSkill(ctx, arg)
-		recognitionAttack(ctx, arg)
-	} else {
-		recognitionAttack(ctx, arg)
+		recognitionSkill(ctx, arg, obs)
 	}
+	recognitionAttack(ctx, obs)
 
 	return &maa.CustomRecognitionResult{
 		Box:    arg.Roi,
@@ -453,6 +1152,91 @@ func (r *AutoFightExecuteRecognition) Run(ctx *maa.Context, arg *maa.CustomRecog
 	}, true
 }
 
+const (
+	lockVerifyDelay    = 300 * time.Millisecond // LockTarget 执行后等待准星出现的时间
+	lockRelockInterval = 3 * time.Second        // 重试用尽后，间隔该时长再开始新一轮锁定
+)
+
+// enqueueLockTarget 入队锁定目标，并在执行后安排一次锁定校验。
+// 配置了 lock_target_strategy 时先点击选出的敌人，再执行 LockTarget。
+func enqueueLockTarget(ctx *maa.Context, arg *maa.CustomRecognitionArg) {
+	executeAt := time.Now().Add(time.Millisecond)
+	if enqueueTargetTap(ctx, arg, executeAt) {
+		executeAt = executeAt.Add(lockTapLead)
+	}
+	enqueueAction(fightAction{
+		executeAt: executeAt,
+		action:    ActionLockTarget,
+		trigger:   "lock_target",
+	})
+	lockVerifyAt = executeAt.Add(lockVerifyDelay)
+}
+
+// checkTargetLock 校验锁定准星：未锁定时有限次重试，目标丢失（如被击杀）时重新锁定
+func checkTargetLock(ctx *maa.Context, arg *maa.CustomRecognitionArg, obs frameObservation) {
+	if lockRetry <= 0 || !enemyInScreen {
+		return
+	}
+	if obs.targetLocked {
+		if !targetLocked {
+			log.Info().Int("retries", lockAttempts).Msg("Target locked")
+		}
+		targetLocked = true
+		lockAttempts = 0
+		lockVerifyAt = time.Time{}
+		return
+	}
+	if targetLocked {
+		targetLocked = false
+		lockAttempts = 0
+		log.Info().Msg("Target lost, re-locking")
+		enqueueLockTarget(ctx, arg)
+		return
+	}
+	if lockVerifyAt.IsZero() || time.Now().Before(lockVerifyAt) {
+		return
+	}
+	if lockAttempts >= lockRetry {
+		log.Warn().Int("retries", lockAttempts).Dur("relockAfter", lockRelockInterval).Msg("Failed to lock target")
+		lockAttempts = 0
+		lockVerifyAt = time.Now().Add(lockRelockInterval)
+		return
+	}
+	lockAttempts++
+	log.Info().Int("retry", lockAttempts).Int("maxRetry", lockRetry).Msg("Target not locked, retrying LockTarget")
+	enqueueLockTarget(ctx, arg)
+}
+
+// checkNoEnemyWatchdog 屏幕上持续 noEnemyTimeout 未发现敌人时，按 noEnemyAction 搜索或退出
+func checkNoEnemyWatchdog() {
+	if noEnemyTimeout <= 0 {
+		return
+	}
+	if lastEnemySeenAt.IsZero() {
+		lastEnemySeenAt = time.Now()
+		return
+	}
+	elapsed := time.Since(lastEnemySeenAt)
+	if elapsed < noEnemyTimeout {
+		return
+	}
+
+	log.Info().Dur("elapsed", elapsed).Str("action", noEnemyAction).Msg("No enemy watchdog triggered")
+	lastEnemySeenAt = time.Now()
+	enemyInScreen = false          // 重新发现敌人后再次锁定
+	skillLastUsed = [5]time.Time{} // 新的遭遇战不沿用上一波的技能冷却
+	switch noEnemyAction {
+	case noEnemyActionExit:
+		noEnemyExitPending = true
+	default:
+		enqueueAction(fightAction{
+			executeAt: time.Now(),
+			action:    ActionSearch,
+			trigger:   "no_enemy_watchdog",
+		})
+	}
+}
+
 // actionName 根据动作类型和干员下标返回 Pipeline 中的 action 名称
 func actionName(action ActionType, operator int) string {
 	switch action {
@@ -470,6 +1254,16 @@ func actionName(action ActionType, operator int) string {
 		return "__AutoFightActionLockTarget"
 	case ActionDodge:
 		return "__AutoFightActionDodge"
+	case ActionSearch:
+		return "__AutoFightActionSearch"
+	case ActionDismissPopup:
+		return "__AutoFightActionDismissPopup"
+	case ActionAttackHoldDown:
+		return "__AutoFightActionAttackHoldDown"
+	case ActionAttackHoldUp:
+		return "__AutoFightActionAttackHoldUp"
+	case ActionTapTarget:
+		return "__AutoFightActionTapTarget"
 	default:
 		return ""
 	}
@@ -487,6 +1281,13 @@ var (
 )
 
 func (a *AutoFightExecuteAction) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
+	applyAutoFightParam(arg.CustomActionParam, false)
+
+	// 不在战斗空间（暂停中）时不出队，避免动作打到过场或菜单上
+	if !pauseNotInFightSince.IsZero() {
+		return true
+	}
+
 	now := time.Now()
 
 	// 取出已到期的队列动作并依次执行（按 executeAt 顺序）
@@ -500,7 +1301,13 @@ func (a *AutoFightExecuteAction) Run(ctx *maa.Context, arg *maa.CustomActionArg)
 			continue
 		}
 
-		ctx.RunTask(name)
+		if fa.action == ActionTapTarget {
+			ctx.RunTask(name, override.Node(name).Set("target", fa.target).Map())
+		} else {
+			ctx.RunTask(name)
+		}
+		recordAction(fa, time.Now())
+		countAction(fa.action)
 	}
 
 	return true
@@ -0,0 +1,92 @@
+package autofight
+
+import (
+	"testing"
+	"time"
+)
+
+// simulateFight 模拟一场进行中的战斗：入口清理残留状态后，战斗过程写入各包级变量。
+// 不经过 Exit，相当于任务在战斗中被停止。
+func simulateFight(t *testing.T) {
+	t.Helper()
+	resetSessionState(nil)
+	assertFightGlobalsCleared(t)
+
+	now := time.Now()
+	fightStartedAt = now
+	startFightStats()
+	countAction(ActionSkill)
+	enqueueAction(fightAction{executeAt: now.Add(time.Second), action: ActionSkill, operator: 2, trigger: "test"})
+	enqueueAction(fightAction{executeAt: now.Add(2 * time.Second), action: ActionAttack, trigger: "test"})
+	skillCycleIndex = 3
+	enemyInScreen = true
+	lastEnemySeenAt = now
+	noEnemyExitPending = true
+	pauseNotInFightSince = now
+	endSkillLastUsed[1] = now
+	skillLastUsed[2] = now
+	lastDodgeAt = now
+	lastAttackAt = now
+	targetLocked = true
+	lockAttempts = 2
+	lockVerifyAt = now
+	pendingSkill = pendingSkillCast{operator: 2, energyBefore: 1, verifyAt: now, retries: 1}
+	attackHoldUntil = now
+}
+
+func assertFightGlobalsCleared(t *testing.T) {
+	t.Helper()
+	if len(actionQueue) != 0 {
+		t.Errorf("actionQueue has %d stale actions", len(actionQueue))
+	}
+	if skillCycleIndex != 0 {
+		t.Errorf("skillCycleIndex = %d, want 0", skillCycleIndex)
+	}
+	// enemyInScreen 残留为 true 会跳过新一场战斗的首次锁定
+	if enemyInScreen {
+		t.Error("enemyInScreen is still true")
+	}
+	if noEnemyExitPending || targetLocked || lockAttempts != 0 {
+		t.Errorf("stale flags: noEnemyExitPending=%v targetLocked=%v lockAttempts=%d", noEnemyExitPending, targetLocked, lockAttempts)
+	}
+	for name, ts := range map[string]time.Time{
+		"fightStartedAt":       fightStartedAt,
+		"pauseNotInFightSince": pauseNotInFightSince,
+		"lastEnemySeenAt":      lastEnemySeenAt,
+		"lastDodgeAt":          lastDodgeAt,
+		"lastAttackAt":         lastAttackAt,
+		"lockVerifyAt":         lockVerifyAt,
+		"attackHoldUntil":      attackHoldUntil,
+	} {
+		if !ts.IsZero() {
+			t.Errorf("%s = %v, want zero", name, ts)
+		}
+	}
+	if endSkillLastUsed != [5]time.Time{} || skillLastUsed != [5]time.Time{} {
+		t.Error("skill cooldowns carried over")
+	}
+	if pendingSkill != (pendingSkillCast{}) {
+		t.Errorf("pendingSkill = %+v, want zero", pendingSkill)
+	}
+	if !stats.startedAt.IsZero() || stats.total != 0 || len(stats.counts) != 0 {
+		t.Errorf("stats carried over: %+v", stats)
+	}
+}
+
+func TestSequentialFightsStartClean(t *testing.T) {
+	t.Cleanup(func() {
+		clearFightGlobals()
+		stats = fightStats{}
+	})
+
+	simulateFight(t)
+	if !enemyInScreen || len(actionQueue) != 2 {
+		t.Fatalf("first fight did not populate state: enemyInScreen=%v queue=%d", enemyInScreen, len(actionQueue))
+	}
+
+	// 第二场战斗入口：上一场的状态必须全部清空
+	simulateFight(t)
+	if stats.total != 1 {
+		t.Errorf("second fight stats.total = %d, want 1", stats.total)
+	}
+}
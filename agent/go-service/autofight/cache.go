@@ -0,0 +1,90 @@
+package autofight
+
+import (
+	"encoding/json"
+	"image"
+	"sync"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/recognition"
+	"github.com/MaaXYZ/maa-framework-go/v4"
+)
+
+// recognitionAttempts 单次识别出错时的最大尝试次数
+const recognitionAttempts = recognition.DefaultAttempts
+
+// frameCache 缓存同一帧内的识别结果，键为识别节点名与 override（含 ROI）
+var frameCache = struct {
+	mu      sync.Mutex
+	img     image.Image
+	entries map[string]*maa.RecognitionDetail
+}{}
+
+// resetFrameCache 在每次 Run 开始时清空缓存，避免复用上一帧的结果
+func resetFrameCache(img image.Image) {
+	frameCache.mu.Lock()
+	defer frameCache.mu.Unlock()
+	frameCache.img = img
+	frameCache.entries = make(map[string]*maa.RecognitionDetail)
+}
+
+// runRecognition 带帧内缓存的 ctx.RunRecognition，识别出错时不缓存
+func runRecognition(ctx *maa.Context, arg *maa.CustomRecognitionArg, name string, override ...any) (*maa.RecognitionDetail, error) {
+	key := name
+	if len(override) > 0 {
+		if b, err := json.Marshal(override); err == nil {
+			key += string(b)
+		}
+	}
+
+	frameCache.mu.Lock()
+	if frameCache.img == arg.Img {
+		if detail, ok := frameCache.entries[key]; ok {
+			frameCache.mu.Unlock()
+			return detail, nil
+		}
+	}
+	frameCache.mu.Unlock()
+
+	var merged any
+	if o := withThreshold(name, override); len(o) > 0 {
+		merged = o[0]
+	}
+	detail, _, err := recognition.RunRecognitionRetry(ctx, name, arg.Img, merged, recognitionAttempts)
+	if err != nil {
+		return detail, err
+	}
+
+	frameCache.mu.Lock()
+	if frameCache.img == arg.Img && frameCache.entries != nil {
+		frameCache.entries[key] = detail
+	}
+	frameCache.mu.Unlock()
+	return detail, nil
+}
+
+// withThreshold 将 thresholds 参数中配置的阈值合并进该节点的 override
+func withThreshold(name string, override []any) []any {
+	threshold, ok := thresholdOverrides[name]
+	if !ok {
+		return override
+	}
+	merged := map[string]any{}
+	if len(override) > 0 {
+		base, ok := override[0].(map[string]any)
+		if !ok {
+			return override
+		}
+		for k, v := range base {
+			merged[k] = v
+		}
+	}
+	node := map[string]any{}
+	if base, ok := merged[name].(map[string]any); ok {
+		for k, v := range base {
+			node[k] = v
+		}
+	}
+	node["threshold"] = threshold
+	merged[name] = node
+	return []any{merged}
+}
@@ -0,0 +1,506 @@
+package autofight
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	defaultPauseTimeout = 10 * time.Second
+	defaultDodgeDelay   = 100 * time.Millisecond
+	defaultDodgeCD      = time.Duration(0)
+	defaultAttackIntv   = time.Duration(0)
+	defaultSkillCost    = 1
+	defaultAoeEnemies   = 2
+	defaultLockRetry    = 0
+	defaultControlledOp = 1
+	defaultEndSkillHold = 1500 * time.Millisecond
+	defaultSkillCD      = 3 * time.Second
+
+	noEnemyActionSearch = "search"
+	noEnemyActionExit   = "exit"
+
+	defaultLockStrategy = lockStrategyDefault
+
+	priorityDodge    = "dodge"
+	priorityCombo    = "combo"
+	priorityEndSkill = "end_skill"
+	prioritySkill    = "skill"
+)
+
+// defaultPopupNodes 战斗中需要关闭的弹窗识别节点
+var defaultPopupNodes = []string{"__AutoFightRecognitionPopup"}
+
+// thresholdNodes thresholds 参数中可覆盖阈值的键与对应的识别节点
+var thresholdNodes = map[string]string{
+	"combo_notice":  "__AutoFightRecognitionComboNotice",
+	"end_skill":     "__AutoFightRecognitionEndSkill",
+	"fight_skill":   "__AutoFightRecognitionFightSkill",
+	"enemy_attack":  "__AutoFightRecognitionEnemyAttack",
+	"target_locked": "__AutoFightRecognitionTargetLocked",
+}
+
+// defaultSkillPriority 连携 > 终结技 > 普通技能；闪避默认不压制技能
+var defaultSkillPriority = []string{priorityCombo, priorityEndSkill, prioritySkill}
+
+var (
+	// pauseTimeout 不在战斗空间超过该时长后退出战斗，Pause 与 Exit 共用同一个值
+	pauseTimeout = defaultPauseTimeout
+	// dodgeDelay 识别到敌人攻击后延迟该时长再闪避
+	dodgeDelay = defaultDodgeDelay
+	// dodgeWindow 识别到敌人攻击时，取消该时长内已入队的普攻，0 表示不取消
+	dodgeWindow time.Duration
+	// dodgeCooldown 两次入队闪避的最小间隔
+	dodgeCooldown = defaultDodgeCD
+	// jitterMin、jitterMax 普攻与闪避执行时间的随机偏移范围，均为 0 时不偏移
+	jitterMin time.Duration
+	jitterMax time.Duration
+	// attackInterval 两次入队点按普攻的最小间隔，0 表示每帧都可入队
+	attackInterval = defaultAttackIntv
+	// defeatRetryNode 战斗失败后通过 __AutoFightDefeatAnchor 跳转的节点，为空时不跳转
+	defeatRetryNode = ""
+	// skillEnergyCost 释放普通技能所需的能量格数
+	skillEnergyCost = defaultSkillCost
+	// skillEnabled 各干员（1–4 号位）是否释放普通技能，为空时全部释放
+	skillEnabled []bool
+	// skillCooldown 同一干员两次入队普通技能的最小间隔，0 表示不限制
+	skillCooldown = defaultSkillCD
+	// skillOrder 普通技能的轮转顺序（干员下标 1–4），为空时按 1→2→3→4 轮转
+	skillOrder []int
+	// endSkillEnabled 各干员（1–4 号位）是否释放终结技，为空时全部释放
+	endSkillEnabled []bool
+	// endSkillMinInterval 同一干员两次释放终结技的最小间隔，0 表示不限制
+	endSkillMinInterval time.Duration
+	// aoeSkillOperators 普通技能为群攻的干员下标（1–4）
+	aoeSkillOperators []int
+	// aoeMinEnemies 释放群攻技能所需的最少敌人数量
+	aoeMinEnemies = defaultAoeEnemies
+	// noEnemyTimeout 持续未发现敌人超过该时长后触发看门狗，0 表示关闭
+	noEnemyTimeout time.Duration
+	// enemyRetries 尚未发现敌人时，未识别到敌人后重新截图重试的次数，0 表示不重试
+	enemyRetries = 0
+	// noEnemyAction 看门狗触发后的动作：search 移动搜索，exit 退出战斗
+	noEnemyAction = noEnemyActionSearch
+	// skillPriority 每帧技能决策的优先级列表
+	skillPriority = defaultSkillPriority
+	// lockRetry LockTarget 未锁定时的最大重试次数，0 表示不校验锁定
+	lockRetry = defaultLockRetry
+	// lockTargetStrategy LockTarget 前如何选择并点击敌人，default 表示不点击直接锁定
+	lockTargetStrategy = defaultLockStrategy
+	// popupNodes 战斗中检测的弹窗识别节点，命中后入队关闭动作
+	popupNodes = defaultPopupNodes
+	// thresholdOverrides 识别节点名到覆盖阈值，未配置的节点使用 Pipeline 中的阈值
+	thresholdOverrides map[string]float64
+	// controlledOperator 当前操控的干员下标（1–4），用于选择普攻方式
+	controlledOperator = defaultControlledOp
+	// attackHoldDurations 干员下标到蓄力普攻的按住时长，未配置的干员使用点按普攻
+	attackHoldDurations map[int]time.Duration
+	// comboRoiOverrides 干员下标到连携可用指示条 ROI（基准分辨率），未配置的干员使用 defaultComboRois
+	comboRoiOverrides map[int]maa.Rect
+	// endSkillHoldDurations 干员下标到终结技按住时长，未配置的干员使用 defaultEndSkillHold
+	endSkillHoldDurations map[int]time.Duration
+	// verifySkillCast 为 true 时确认普通技能释放后能量下降，否则重新释放
+	verifySkillCast = false
+	// maxQueueLen 动作队列长度上限，0 表示不限制
+	maxQueueLen = 0
+	// maxFightDuration 单场战斗时长上限，超过后强制退出，0 表示不限制
+	maxFightDuration time.Duration
+	// saveTimeoutImage 为 true 时在超过时长上限退出时保存当前画面
+	saveTimeoutImage = false
+	// actionLogPath 非空时将已执行的动作以 JSON Lines 追加写入该文件
+	actionLogPath = ""
+	// recordFight 为 true 时在内存中记录本场已执行的动作，退出战斗时写入调试目录下的 autofight
+	recordFight = false
+)
+
+// autoFightParam 为 AutoFight 各 Custom 节点共用的参数。
+// 入口节点中未填写的字段恢复默认值，循环内节点中未填写的字段保持当前生效值。
+// 字段均为指针，nil 表示未填写（mergeAutoFightParam 依赖这一点）。
+type autoFightParam struct {
+	// Profile 引用 data/AutoFight/profiles.json 中的配置档
+	Profile *string `json:"profile,omitempty"`
+
+	// Stance 选择 stanceParams 中的战斗风格，优先级低于配置档
+	Stance *string `json:"stance,omitempty"`
+
+	PauseTimeoutMs   *int    `json:"pause_timeout_ms,omitempty"`
+	DodgeDelayMs     *int    `json:"dodge_delay_ms,omitempty"`
+	DodgeWindowMs    *int    `json:"dodge_window_ms,omitempty"`
+	DodgeCooldownMs  *int    `json:"dodge_cooldown_ms,omitempty"`
+	AttackIntervalMs *int    `json:"attack_interval_ms,omitempty"`
+	JitterMinMs      *int    `json:"jitter_min_ms,omitempty"`
+	JitterMaxMs      *int    `json:"jitter_max_ms,omitempty"`
+	DefeatRetryNode  *string `json:"defeat_retry_node,omitempty"`
+	SkillEnergyCost  *int    `json:"skill_energy_cost,omitempty"`
+	VerifySkillCast  *bool   `json:"verify_skill_cast,omitempty"`
+
+	SkillEnabled    *[]bool `json:"skill_enabled,omitempty"`
+	SkillOrder      *[]int  `json:"skill_order,omitempty"`
+	SkillCooldownMs *int    `json:"skill_cooldown_ms,omitempty"`
+
+	EndSkillEnabled       *[]bool `json:"end_skill_enabled,omitempty"`
+	EndSkillMinIntervalMs *int    `json:"end_skill_min_interval_ms,omitempty"`
+	AoeSkillOperators     *[]int  `json:"aoe_skill_operators,omitempty"`
+	AoeMinEnemies         *int    `json:"aoe_min_enemies,omitempty"`
+
+	ControlledOperator *int         `json:"controlled_operator,omitempty"`
+	AttackHoldMs       *map[int]int `json:"attack_hold_ms,omitempty"`
+	EndSkillHoldMs     *map[int]int `json:"end_skill_hold_ms,omitempty"`
+
+	ComboRois *map[int]maa.Rect `json:"combo_rois,omitempty"`
+
+	NoEnemyTimeoutMs *int    `json:"no_enemy_timeout_ms,omitempty"`
+	NoEnemyAction    *string `json:"no_enemy_action,omitempty"`
+	EnemyRetries     *int    `json:"enemy_retries,omitempty"`
+
+	SkillPriority *[]string `json:"skill_priority,omitempty"`
+	PopupNodes    *[]string `json:"popup_nodes,omitempty"`
+	LockRetry     *int      `json:"lock_retry,omitempty"`
+
+	LockTargetStrategy *string `json:"lock_target_strategy,omitempty"`
+
+	Thresholds *map[string]float64 `json:"thresholds,omitempty"`
+
+	MaxFightMs       *int  `json:"max_fight_ms,omitempty"`
+	SaveTimeoutImage *bool `json:"save_timeout_image,omitempty"`
+
+	MaxQueueLen   *int    `json:"max_queue_len,omitempty"`
+	ActionLogPath *string `json:"action_log_path,omitempty"`
+	Record        *bool   `json:"record,omitempty"`
+}
+
+func parseAutoFightParam(paramStr string) (*autoFightParam, error) {
+	var param autoFightParam
+	if paramStr == "" || paramStr == "null" {
+		return &param, nil
+	}
+	if err := json.Unmarshal([]byte(paramStr), &param); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal parameters: %w", err)
+	}
+	if param.Profile != nil && *param.Profile != "" {
+		// 节点上显式填写的字段优先于配置档
+		if profile, ok := lookupProfile(*param.Profile); ok {
+			param = mergeAutoFightParam(param, profile)
+		}
+	}
+	if param.Stance != nil {
+		if stance, ok := stanceParams[*param.Stance]; ok {
+			param = mergeAutoFightParam(param, stance)
+		}
+	}
+	if err := param.validate(); err != nil {
+		return nil, err
+	}
+	return &param, nil
+}
+
+func (p *autoFightParam) validate() error {
+	if p.Stance != nil {
+		if _, ok := stanceParams[*p.Stance]; !ok {
+			return fmt.Errorf("invalid stance value: %q", *p.Stance)
+		}
+	}
+	if p.PauseTimeoutMs != nil && *p.PauseTimeoutMs <= 0 {
+		return fmt.Errorf("invalid pause_timeout_ms value: %d", *p.PauseTimeoutMs)
+	}
+	if p.DodgeDelayMs != nil && *p.DodgeDelayMs < 0 {
+		return fmt.Errorf("invalid dodge_delay_ms value: %d", *p.DodgeDelayMs)
+	}
+	if p.DodgeWindowMs != nil && *p.DodgeWindowMs < 0 {
+		return fmt.Errorf("invalid dodge_window_ms value: %d", *p.DodgeWindowMs)
+	}
+	if p.DodgeCooldownMs != nil && *p.DodgeCooldownMs < 0 {
+		return fmt.Errorf("invalid dodge_cooldown_ms value: %d", *p.DodgeCooldownMs)
+	}
+	if p.AttackIntervalMs != nil && *p.AttackIntervalMs < 0 {
+		return fmt.Errorf("invalid attack_interval_ms value: %d", *p.AttackIntervalMs)
+	}
+	if p.JitterMinMs != nil && p.JitterMaxMs != nil && *p.JitterMinMs > *p.JitterMaxMs {
+		return fmt.Errorf("invalid jitter range: jitter_min_ms %d > jitter_max_ms %d", *p.JitterMinMs, *p.JitterMaxMs)
+	}
+	if p.SkillEnergyCost != nil && (*p.SkillEnergyCost < 1 || *p.SkillEnergyCost > len(energyCellNodes)) {
+		return fmt.Errorf("invalid skill_energy_cost value: %d", *p.SkillEnergyCost)
+	}
+	if p.SkillEnabled != nil && len(*p.SkillEnabled) != 4 {
+		return fmt.Errorf("invalid skill_enabled value: expected 4 entries, got %d", len(*p.SkillEnabled))
+	}
+	if p.SkillCooldownMs != nil && *p.SkillCooldownMs < 0 {
+		return fmt.Errorf("invalid skill_cooldown_ms value: %d", *p.SkillCooldownMs)
+	}
+	if p.EndSkillEnabled != nil && len(*p.EndSkillEnabled) != 4 {
+		return fmt.Errorf("invalid end_skill_enabled value: expected 4 entries, got %d", len(*p.EndSkillEnabled))
+	}
+	if err := validateOperators("aoe_skill_operators", p.AoeSkillOperators); err != nil {
+		return err
+	}
+	if p.EndSkillMinIntervalMs != nil && *p.EndSkillMinIntervalMs < 0 {
+		return fmt.Errorf("invalid end_skill_min_interval_ms value: %d", *p.EndSkillMinIntervalMs)
+	}
+	if p.AoeMinEnemies != nil && *p.AoeMinEnemies < 1 {
+		return fmt.Errorf("invalid aoe_min_enemies value: %d", *p.AoeMinEnemies)
+	}
+	if p.NoEnemyTimeoutMs != nil && *p.NoEnemyTimeoutMs < 0 {
+		return fmt.Errorf("invalid no_enemy_timeout_ms value: %d", *p.NoEnemyTimeoutMs)
+	}
+	if p.NoEnemyAction != nil && *p.NoEnemyAction != noEnemyActionSearch && *p.NoEnemyAction != noEnemyActionExit {
+		return fmt.Errorf("invalid no_enemy_action value: %q", *p.NoEnemyAction)
+	}
+	if p.EnemyRetries != nil && *p.EnemyRetries < 0 {
+		return fmt.Errorf("invalid enemy_retries value: %d", *p.EnemyRetries)
+	}
+	if p.SkillPriority != nil {
+		seen := make(map[string]bool, len(*p.SkillPriority))
+		for _, kind := range *p.SkillPriority {
+			switch kind {
+			case priorityDodge, priorityCombo, priorityEndSkill, prioritySkill:
+			default:
+				return fmt.Errorf("invalid skill_priority entry: %q", kind)
+			}
+			if seen[kind] {
+				return fmt.Errorf("duplicate skill_priority entry: %q", kind)
+			}
+			seen[kind] = true
+		}
+	}
+	if p.LockRetry != nil && *p.LockRetry < 0 {
+		return fmt.Errorf("invalid lock_retry value: %d", *p.LockRetry)
+	}
+	if p.LockTargetStrategy != nil {
+		switch *p.LockTargetStrategy {
+		case lockStrategyDefault, lockStrategyNearestCenter, lockStrategyLowest:
+		default:
+			return fmt.Errorf("invalid lock_target_strategy value: %q", *p.LockTargetStrategy)
+		}
+	}
+	if p.ControlledOperator != nil && (*p.ControlledOperator < 1 || *p.ControlledOperator > 4) {
+		return fmt.Errorf("invalid controlled_operator value: %d", *p.ControlledOperator)
+	}
+	if p.AttackHoldMs != nil {
+		for idx, ms := range *p.AttackHoldMs {
+			if idx < 1 || idx > 4 {
+				return fmt.Errorf("invalid attack_hold_ms operator: %d", idx)
+			}
+			if ms < 0 {
+				return fmt.Errorf("invalid attack_hold_ms value: %d", ms)
+			}
+		}
+	}
+	if p.EndSkillHoldMs != nil {
+		for idx, ms := range *p.EndSkillHoldMs {
+			if idx < 1 || idx > 4 {
+				return fmt.Errorf("invalid end_skill_hold_ms operator: %d", idx)
+			}
+			if ms <= 0 {
+				return fmt.Errorf("invalid end_skill_hold_ms value: %d", ms)
+			}
+		}
+	}
+	if p.ComboRois != nil {
+		for idx, roi := range *p.ComboRois {
+			if idx < 1 || idx > 4 {
+				return fmt.Errorf("invalid combo_rois operator: %d", idx)
+			}
+			if roi.X() < 0 || roi.Y() < 0 || roi.Width() <= 0 || roi.Height() <= 0 {
+				return fmt.Errorf("invalid combo_rois.%d value: %v", idx, roi)
+			}
+		}
+	}
+	if p.Thresholds != nil {
+		for key, value := range *p.Thresholds {
+			if _, ok := thresholdNodes[key]; !ok {
+				return fmt.Errorf("invalid thresholds key: %q", key)
+			}
+			if value <= 0 || value > 1 {
+				return fmt.Errorf("invalid thresholds.%s value: %f", key, value)
+			}
+		}
+	}
+	if p.MaxFightMs != nil && *p.MaxFightMs < 0 {
+		return fmt.Errorf("invalid max_fight_ms value: %d", *p.MaxFightMs)
+	}
+	if p.MaxQueueLen != nil && *p.MaxQueueLen < 0 {
+		return fmt.Errorf("invalid max_queue_len value: %d", *p.MaxQueueLen)
+	}
+	return nil
+}
+
+// warnedSkillOrder 最近一次告警的无效 skill_order，参数每帧都会解析，同一配置只告警一次
+var warnedSkillOrder string
+
+// sanitizeSkillOrder 检查 skill_order，越界或重复时告警并忽略，退回默认的 1→2→3→4 轮转
+func sanitizeSkillOrder(order *[]int) *[]int {
+	if order == nil {
+		return nil
+	}
+	err := validateOperators("skill_order", order)
+	if err == nil {
+		seen := make(map[int]bool, len(*order))
+		for _, idx := range *order {
+			if seen[idx] {
+				err = fmt.Errorf("duplicate skill_order entry: %d", idx)
+				break
+			}
+			seen[idx] = true
+		}
+	}
+	if err != nil {
+		if key := fmt.Sprint(*order); key != warnedSkillOrder {
+			warnedSkillOrder = key
+			log.Warn().Err(err).Ints("skill_order", *order).Msg("Invalid skill_order ignored, fall back to default order")
+		}
+		return ptr([]int{})
+	}
+	return order
+}
+
+func validateOperators(field string, operators *[]int) error {
+	if operators == nil {
+		return nil
+	}
+	for _, idx := range *operators {
+		if idx < 1 || idx > 4 {
+			return fmt.Errorf("invalid %s value: operator %d out of range 1-4", field, idx)
+		}
+	}
+	return nil
+}
+
+// applyAutoFightParam 解析参数并更新包级配置，解析失败时保留原值。
+// withDefaults 为 true 时未填写的字段恢复默认值，用于每次进入战斗时重新确定配置。
+func applyAutoFightParam(paramStr string, withDefaults bool) {
+	param, err := parseAutoFightParam(paramStr)
+	if err != nil {
+		log.Warn().Err(err).Str("param", paramStr).Msg("Invalid AutoFight param, keep current config")
+		return
+	}
+	activeStance = resolve(param.Stance, activeStance, stanceBalanced, withDefaults)
+	setPauseTimeout(resolveMs(param.PauseTimeoutMs, pauseTimeout, defaultPauseTimeout, withDefaults))
+	if delay := resolveMs(param.DodgeDelayMs, dodgeDelay, defaultDodgeDelay, withDefaults); delay != dodgeDelay {
+		log.Info().Dur("old", dodgeDelay).Dur("new", delay).Msg("AutoFight dodge delay changed")
+		dodgeDelay = delay
+	}
+	dodgeWindow = resolveMs(param.DodgeWindowMs, dodgeWindow, 0, withDefaults)
+	dodgeCooldown = resolveMs(param.DodgeCooldownMs, dodgeCooldown, defaultDodgeCD, withDefaults)
+	attackInterval = resolveMs(param.AttackIntervalMs, attackInterval, defaultAttackIntv, withDefaults)
+	setJitter(resolveMs(param.JitterMinMs, jitterMin, 0, withDefaults), resolveMs(param.JitterMaxMs, jitterMax, 0, withDefaults))
+	defeatRetryNode = resolve(param.DefeatRetryNode, defeatRetryNode, "", withDefaults)
+	skillEnergyCost = resolve(param.SkillEnergyCost, skillEnergyCost, defaultSkillCost, withDefaults)
+	verifySkillCast = resolve(param.VerifySkillCast, verifySkillCast, false, withDefaults)
+	skillEnabled = resolve(param.SkillEnabled, skillEnabled, nil, withDefaults)
+	skillOrder = resolve(sanitizeSkillOrder(param.SkillOrder), skillOrder, nil, withDefaults)
+	skillCooldown = resolveMs(param.SkillCooldownMs, skillCooldown, defaultSkillCD, withDefaults)
+	endSkillEnabled = resolve(param.EndSkillEnabled, endSkillEnabled, nil, withDefaults)
+	endSkillMinInterval = resolveMs(param.EndSkillMinIntervalMs, endSkillMinInterval, 0, withDefaults)
+	aoeSkillOperators = resolve(param.AoeSkillOperators, aoeSkillOperators, nil, withDefaults)
+	aoeMinEnemies = resolve(param.AoeMinEnemies, aoeMinEnemies, defaultAoeEnemies, withDefaults)
+	noEnemyTimeout = resolveMs(param.NoEnemyTimeoutMs, noEnemyTimeout, 0, withDefaults)
+	noEnemyAction = resolve(param.NoEnemyAction, noEnemyAction, noEnemyActionSearch, withDefaults)
+	enemyRetries = resolve(param.EnemyRetries, enemyRetries, 0, withDefaults)
+	skillPriority = resolve(param.SkillPriority, skillPriority, defaultSkillPriority, withDefaults)
+	popupNodes = resolve(param.PopupNodes, popupNodes, defaultPopupNodes, withDefaults)
+	lockRetry = resolve(param.LockRetry, lockRetry, defaultLockRetry, withDefaults)
+	lockTargetStrategy = resolve(param.LockTargetStrategy, lockTargetStrategy, defaultLockStrategy, withDefaults)
+	controlledOperator = resolve(param.ControlledOperator, controlledOperator, defaultControlledOp, withDefaults)
+	if param.AttackHoldMs != nil {
+		attackHoldDurations = make(map[int]time.Duration, len(*param.AttackHoldMs))
+		for idx, ms := range *param.AttackHoldMs {
+			attackHoldDurations[idx] = time.Duration(ms) * time.Millisecond
+		}
+	} else if withDefaults {
+		attackHoldDurations = nil
+	}
+	if param.EndSkillHoldMs != nil {
+		endSkillHoldDurations = make(map[int]time.Duration, len(*param.EndSkillHoldMs))
+		for idx, ms := range *param.EndSkillHoldMs {
+			endSkillHoldDurations[idx] = time.Duration(ms) * time.Millisecond
+		}
+	} else if withDefaults {
+		endSkillHoldDurations = nil
+	}
+	if param.ComboRois != nil {
+		comboRoiOverrides = make(map[int]maa.Rect, len(*param.ComboRois))
+		for idx, roi := range *param.ComboRois {
+			if roi != comboRoi(idx) {
+				log.Info().Int("operator", idx).Interface("default", defaultComboRois[idx]).Interface("roi", roi).Msg("AutoFight combo ROI override applied")
+			}
+			comboRoiOverrides[idx] = roi
+		}
+	} else if withDefaults {
+		comboRoiOverrides = nil
+	}
+	if param.Thresholds != nil {
+		thresholdOverrides = make(map[string]float64, len(*param.Thresholds))
+		for key, value := range *param.Thresholds {
+			thresholdOverrides[thresholdNodes[key]] = value
+		}
+	} else if withDefaults {
+		thresholdOverrides = nil
+	}
+	maxFightDuration = resolveMs(param.MaxFightMs, maxFightDuration, 0, withDefaults)
+	saveTimeoutImage = resolve(param.SaveTimeoutImage, saveTimeoutImage, false, withDefaults)
+	maxQueueLen = resolve(param.MaxQueueLen, maxQueueLen, 0, withDefaults)
+	actionLogPath = resolve(param.ActionLogPath, actionLogPath, "", withDefaults)
+	recordFight = resolve(param.Record, recordFight, false, withDefaults)
+}
+
+// mergeAutoFightParam 以 base 为准，base 中未填写的字段取 fallback 中的值
+func mergeAutoFightParam(base, fallback autoFightParam) autoFightParam {
+	merged := base
+	mv := reflect.ValueOf(&merged).Elem()
+	fv := reflect.ValueOf(fallback)
+	for i := 0; i < mv.NumField(); i++ {
+		if mv.Field(i).IsNil() {
+			mv.Field(i).Set(fv.Field(i))
+		}
+	}
+	return merged
+}
+
+// resolve 返回参数指定的值；未填写时按 withDefaults 返回默认值或当前值
+func resolve[T any](v *T, current, def T, withDefaults bool) T {
+	switch {
+	case v != nil:
+		return *v
+	case withDefaults:
+		return def
+	default:
+		return current
+	}
+}
+
+// resolveMs 同 resolve，将毫秒参数转换为时长
+func resolveMs(ms *int, current, def time.Duration, withDefaults bool) time.Duration {
+	if ms != nil {
+		return time.Duration(*ms) * time.Millisecond
+	}
+	return resolve(nil, current, def, withDefaults)
+}
+
+// setJitter 更新随机偏移范围；只填写一端导致 min > max 时保留原值
+func setJitter(minJitter, maxJitter time.Duration) {
+	if minJitter > maxJitter {
+		log.Warn().Dur("min", minJitter).Dur("max", maxJitter).Msg("Invalid AutoFight jitter range, keep current config")
+		return
+	}
+	jitterMin, jitterMax = minJitter, maxJitter
+}
+
+// setPauseTimeout 更新暂停超时；计时中途修改时沿用已开始的计时，仅以新值判断是否超时
+func setPauseTimeout(timeout time.Duration) {
+	if timeout == pauseTimeout {
+		return
+	}
+	event := log.Info().
+		Dur("old", pauseTimeout).
+		Dur("new", timeout)
+	if !pauseNotInFightSince.IsZero() {
+		event = event.Dur("elapsed", time.Since(pauseNotInFightSince))
+	}
+	event.Msg("AutoFight pause timeout changed")
+	pauseTimeout = timeout
+}
@@ -0,0 +1,80 @@
+package autofight
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/resource"
+	"github.com/rs/zerolog/log"
+)
+
+const profilesPath = "data/AutoFight/profiles.json"
+
+var (
+	cachedProfiles     map[string]autoFightParam
+	cachedProfilesOnce sync.Once
+	cachedProfilesErr  error
+)
+
+// loadProfiles 读取并校验配置档文件，结果在进程内缓存
+func loadProfiles() (map[string]autoFightParam, error) {
+	cachedProfilesOnce.Do(func() {
+		content, err := resource.ReadResource(profilesPath)
+		if err != nil {
+			cachedProfilesErr = err
+			return
+		}
+		profiles, err := parseProfiles(content)
+		if err != nil {
+			log.Error().Err(err).Str("path", profilesPath).Msg("Invalid AutoFight profiles")
+			cachedProfilesErr = err
+			return
+		}
+		cachedProfiles = profiles
+		log.Info().Int("profileCount", len(profiles)).Msg("AutoFight profiles loaded")
+	})
+	return cachedProfiles, cachedProfilesErr
+}
+
+func parseProfiles(content []byte) (map[string]autoFightParam, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal profiles: %w", err)
+	}
+
+	profiles := make(map[string]autoFightParam, len(raw))
+	for name, data := range raw {
+		var profile autoFightParam
+		// 拒绝未知字段，避免拼写错误的配置被静默忽略
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&profile); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", name, err)
+		}
+		if profile.Profile != nil {
+			return nil, fmt.Errorf("profile %q: nested profile is not allowed", name)
+		}
+		if err := profile.validate(); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", name, err)
+		}
+		profiles[name] = profile
+	}
+	return profiles, nil
+}
+
+// lookupProfile 按名称查找配置档，找不到时告警并回退到内置默认值
+func lookupProfile(name string) (autoFightParam, bool) {
+	profiles, err := loadProfiles()
+	if err != nil {
+		log.Warn().Err(err).Str("profile", name).Msg("AutoFight profiles unavailable, fall back to built-in defaults")
+		return autoFightParam{}, false
+	}
+	profile, ok := profiles[name]
+	if !ok {
+		log.Warn().Str("profile", name).Msg("Unknown AutoFight profile, fall back to built-in defaults")
+		return autoFightParam{}, false
+	}
+	return profile, true
+}
@@ -0,0 +1,134 @@
+package autofight
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/debugimg"
+	"github.com/rs/zerolog/log"
+)
+
+// actionRecord 为一次已执行动作的记录，用于回放与分析
+type actionRecord struct {
+	Time     string `json:"time"`
+	OffsetMs int64  `json:"offset_ms"` // 相对战斗开始的毫秒数
+	LateMs   int64  `json:"late_ms"`   // 实际执行时间晚于 executeAt 的毫秒数
+	Action   string `json:"action"`
+	Operator int    `json:"operator,omitempty"`
+	Trigger  string `json:"trigger,omitempty"`
+}
+
+var (
+	// action_log_path：以 JSON Lines 追加写入，带缓冲，退出战斗时刷盘
+	actionLogFile   *os.File
+	actionLogWriter *bufio.Writer
+
+	// record：战斗中仅记录在内存，退出战斗时一次性写入调试目录（debugimg 根目录）下 autofight 中的 JSON 文件
+	fightRecord []actionRecord
+
+	recordStartedAt time.Time
+)
+
+// startActionRecord 进入战斗时打开动作日志并开始记录；两者均未开启时不做任何事
+func startActionRecord() {
+	recordStartedAt = time.Now()
+	fightRecord = nil
+	if actionLogPath == "" || actionLogFile != nil {
+		return
+	}
+	if dir := filepath.Dir(actionLogPath); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			log.Warn().Err(err).Str("dir", dir).Msg("Failed to create dir for action log")
+			return
+		}
+	}
+	f, err := os.OpenFile(actionLogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		log.Warn().Err(err).Str("path", actionLogPath).Msg("Failed to open action log file")
+		return
+	}
+	actionLogFile = f
+	actionLogWriter = bufio.NewWriter(f)
+	log.Info().Str("path", actionLogPath).Msg("AutoFight action log started")
+}
+
+// recordAction 记录一次已执行的动作
+func recordAction(fa fightAction, executedAt time.Time) {
+	if actionLogWriter == nil && !recordFight {
+		return
+	}
+	rec := actionRecord{
+		Time:     executedAt.Format(time.RFC3339Nano),
+		OffsetMs: executedAt.Sub(recordStartedAt).Milliseconds(),
+		LateMs:   executedAt.Sub(fa.executeAt).Milliseconds(),
+		Action:   fa.action.String(),
+		Operator: fa.operator,
+		Trigger:  fa.trigger,
+	}
+	if recordFight {
+		fightRecord = append(fightRecord, rec)
+	}
+	if actionLogWriter == nil {
+		return
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return
+	}
+	if _, err := actionLogWriter.Write(append(line, '\n')); err != nil {
+		log.Warn().Err(err).Msg("Failed to write action log, stop logging")
+		closeActionLog()
+	}
+}
+
+// stopActionRecord 退出战斗时刷新动作日志，并将本场记录写入调试目录下的 autofight
+func stopActionRecord() {
+	closeActionLog()
+	saveFightRecord()
+}
+
+// closeActionLog 刷新缓冲并关闭动作日志文件
+func closeActionLog() {
+	if actionLogFile == nil {
+		return
+	}
+	if err := actionLogWriter.Flush(); err != nil {
+		log.Warn().Err(err).Msg("Failed to flush action log")
+	}
+	if err := actionLogFile.Close(); err != nil {
+		log.Warn().Err(err).Msg("Failed to close action log file")
+	}
+	log.Info().Str("path", actionLogFile.Name()).Msg("AutoFight action log stopped")
+	actionLogFile = nil
+	actionLogWriter = nil
+}
+
+// saveFightRecord 将内存中的本场动作记录写为 JSON 文件，没有记录时不创建文件
+func saveFightRecord() {
+	if len(fightRecord) == 0 {
+		return
+	}
+	records := fightRecord
+	fightRecord = nil
+
+	dir := debugimg.Dir("autofight")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Warn().Err(err).Str("dir", dir).Msg("Failed to create dir for fight record")
+		return
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to marshal fight record")
+		return
+	}
+	path := filepath.Join(dir, fmt.Sprintf("actions_%s.json", recordStartedAt.Format("20060102_150405")))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("Failed to write fight record")
+		return
+	}
+	log.Info().Str("path", path).Int("actions", len(records)).Msg("AutoFight fight record saved")
+}
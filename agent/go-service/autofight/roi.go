@@ -0,0 +1,32 @@
+package autofight
+
+import (
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/rect"
+	"github.com/MaaXYZ/maa-framework-go/v4"
+)
+
+// defaultComboRois 各干员（下标 1–4）连携可用指示条在基准分辨率下的 ROI
+var defaultComboRois = [5]maa.Rect{
+	1: {28, 657, 56, 4},
+	2: {105, 657, 56, 4},
+	3: {184, 657, 56, 4},
+	4: {262, 657, 56, 4},
+}
+
+// comboRoi 返回干员连携可用指示条的 ROI，combo_rois 中配置的值优先
+func comboRoi(index int) maa.Rect {
+	if roi, ok := comboRoiOverrides[index]; ok {
+		return roi
+	}
+	return defaultComboRois[index]
+}
+
+// frameRect 将基准分辨率下的 Rect 换算到当前帧。
+// 以截图尺寸而非控制器原始分辨率为准，因为 ROI 作用于框架缩放后的截图。
+func frameRect(arg *maa.CustomRecognitionArg, r maa.Rect) maa.Rect {
+	if arg == nil || arg.Img == nil {
+		return r
+	}
+	b := arg.Img.Bounds()
+	return rect.ScaleFromBase(r, b.Dx(), b.Dy())
+}
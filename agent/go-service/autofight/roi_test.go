@@ -0,0 +1,43 @@
+package autofight
+
+import (
+	"image"
+	"testing"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+)
+
+func TestFrameRect(t *testing.T) {
+	endSkillRoi := maa.Rect{1010, 535, 270, 65}
+	cases := []struct {
+		name string
+		w, h int
+		in   maa.Rect
+		want maa.Rect
+	}{
+		{"720p unchanged", 1280, 720, endSkillRoi, endSkillRoi},
+		{"1080p", 1920, 1080, endSkillRoi, maa.Rect{1515, 803, 405, 97}},
+		{"1440p", 2560, 1440, endSkillRoi, maa.Rect{2020, 1070, 540, 130}},
+		{"900p", 1600, 900, endSkillRoi, maa.Rect{1263, 669, 337, 81}},
+		// 连携指示条只有 4px 高，上下边分别取整（985.5→986，991.5→992）
+		{"1080p combo bar", 1920, 1080, defaultComboRois[1], maa.Rect{42, 986, 84, 6}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			arg := &maa.CustomRecognitionArg{Img: image.NewRGBA(image.Rect(0, 0, tc.w, tc.h))}
+			if got := frameRect(arg, tc.in); got != tc.want {
+				t.Errorf("frameRect(%v) at %dx%d = %v, want %v", tc.in, tc.w, tc.h, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFrameRectWithoutImage(t *testing.T) {
+	r := maa.Rect{1010, 535, 270, 65}
+	if got := frameRect(nil, r); got != r {
+		t.Errorf("frameRect(nil) = %v, want %v", got, r)
+	}
+	if got := frameRect(&maa.CustomRecognitionArg{}, r); got != r {
+		t.Errorf("frameRect without image = %v, want %v", got, r)
+	}
+}
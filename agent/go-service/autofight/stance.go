@@ -0,0 +1,46 @@
+package autofight
+
+import "github.com/rs/zerolog/log"
+
+const (
+	stanceAggressive = "aggressive"
+	stanceBalanced   = "balanced"
+	stanceDefensive  = "defensive"
+)
+
+// stanceParams 各战斗风格对应的参数组合，balanced 为空即保持内置默认值
+var stanceParams = map[string]autoFightParam{
+	stanceAggressive: {
+		DodgeDelayMs:    ptr(150),
+		DodgeCooldownMs: ptr(1000),
+		SkillPriority:   ptr([]string{priorityEndSkill, priorityCombo, prioritySkill}),
+	},
+	stanceBalanced: {},
+	stanceDefensive: {
+		DodgeDelayMs:    ptr(50),
+		DodgeCooldownMs: ptr(0),
+		DodgeWindowMs:   ptr(300),
+		SkillPriority:   ptr([]string{priorityDodge, priorityCombo, priorityEndSkill, prioritySkill}),
+	},
+}
+
+// activeStance 当前生效的战斗风格，进入战斗时输出到日志
+var activeStance = stanceBalanced
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// logActiveStance 进入战斗时输出当前战斗风格、关键参数及启用技能的干员，每场战斗一次
+func logActiveStance() {
+	log.Info().
+		Str("stance", activeStance).
+		Dur("dodgeDelay", dodgeDelay).
+		Dur("dodgeCooldown", dodgeCooldown).
+		Dur("dodgeWindow", dodgeWindow).
+		Dur("attackInterval", attackInterval).
+		Strs("skillPriority", skillPriority).
+		Ints("skillEnabled", enabledOperators(skillEnabled)).
+		Ints("endSkillEnabled", enabledOperators(endSkillEnabled)).
+		Msg("AutoFight stance active")
+}
@@ -0,0 +1,79 @@
+package autofight
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/events"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/i18n"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/maafocus"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/metrics"
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// fightStats 单场战斗的统计，从首次发现敌人开始计时
+type fightStats struct {
+	startedAt time.Time
+	counts    map[ActionType]int
+	total     int
+}
+
+var stats fightStats
+
+// startFightStats 首次发现敌人时开始计时，重复调用不会重置
+func startFightStats() {
+	if !stats.startedAt.IsZero() {
+		return
+	}
+	stats.startedAt = time.Now()
+}
+
+// countAction 记录一次已执行的动作
+func countAction(action ActionType) {
+	if stats.counts == nil {
+		stats.counts = make(map[ActionType]int)
+	}
+	stats.counts[action]++
+	stats.total++
+	metrics.Inc("autofight.actions")
+}
+
+// emitFightSummary 退出战斗时输出统计并重置，未发现过敌人时不输出
+func emitFightSummary(ctx *maa.Context, reason string) {
+	defer func() { stats = fightStats{} }()
+	if stats.startedAt.IsZero() {
+		return
+	}
+
+	duration := time.Since(stats.startedAt)
+	apm := 0.0
+	if duration > 0 {
+		apm = float64(stats.total) / duration.Minutes()
+	}
+
+	parts := make([]string, 0, len(stats.counts))
+	for action := ActionAttack; action < actionTypeCount; action++ {
+		if n := stats.counts[action]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s×%d", action, n))
+		}
+	}
+
+	log.Info().
+		Str("reason", reason).
+		Dur("duration", duration).
+		Int("actions", stats.total).
+		Float64("apm", apm).
+		Strs("breakdown", parts).
+		Msg("AutoFight fight summary")
+	events.Metric(ctx, "autofight", "fight_summary", map[string]any{
+		"reason":      reason,
+		"duration_ms": duration.Milliseconds(),
+		"actions":     stats.total,
+		"apm":         apm,
+		"breakdown":   parts,
+	})
+	maafocus.Print(ctx, i18n.T("autofight.fight_summary",
+		duration.Round(100*time.Millisecond).String(), stats.total, apm, strings.Join(parts, i18n.Separator())))
+}
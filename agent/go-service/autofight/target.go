@@ -0,0 +1,96 @@
+package autofight
+
+import (
+	"image"
+	"time"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// lockStrategyDefault 直接执行 LockTarget，由游戏自行选择目标
+	lockStrategyDefault = "default"
+	// lockStrategyNearestCenter 先点击离画面中心最近的敌人血条
+	lockStrategyNearestCenter = "nearest_center"
+	// lockStrategyLowest 先点击画面中最靠下（通常最近）的敌人血条
+	lockStrategyLowest = "lowest"
+
+	// lockTapLead 点击目标与执行 LockTarget 之间的间隔
+	lockTapLead = 50 * time.Millisecond
+)
+
+// enemyMarkers 返回画面中识别到的敌人血条位置，识别出错时第二个返回值为 false
+func enemyMarkers(ctx *maa.Context, arg *maa.CustomRecognitionArg) ([]maa.Rect, bool) {
+	detail, err := runRecognition(ctx, arg, "__AutoFightRecognitionHasEnemy")
+	if err != nil || detail == nil {
+		log.Error().Err(err).Msg("Failed to run recognition for AutoFightRecognitionHasEnemy")
+		return nil, false
+	}
+	if !detail.Hit || detail.Results == nil {
+		return nil, true
+	}
+	markers := make([]maa.Rect, 0, len(detail.Results.Filtered))
+	for _, m := range detail.Results.Filtered {
+		cm, ok := m.AsColorMatch()
+		if !ok {
+			continue
+		}
+		markers = append(markers, cm.Box)
+	}
+	return markers, true
+}
+
+// chooseLockTarget 按 strategy 从敌人血条中选出点击目标，没有候选时返回 false
+func chooseLockTarget(markers []maa.Rect, bounds image.Rectangle, strategy string) (maa.Rect, bool) {
+	if len(markers) == 0 {
+		return maa.Rect{}, false
+	}
+	cx, cy := bounds.Min.X+bounds.Dx()/2, bounds.Min.Y+bounds.Dy()/2
+	best := 0
+	for i := 1; i < len(markers); i++ {
+		switch strategy {
+		case lockStrategyLowest:
+			if markers[i].Y()+markers[i].Height() > markers[best].Y()+markers[best].Height() {
+				best = i
+			}
+		default:
+			if distSqToPoint(markers[i], cx, cy) < distSqToPoint(markers[best], cx, cy) {
+				best = i
+			}
+		}
+	}
+	return markers[best], true
+}
+
+// distSqToPoint 矩形中心到 (x, y) 的距离平方
+func distSqToPoint(r maa.Rect, x, y int) int {
+	dx := r.X() + r.Width()/2 - x
+	dy := r.Y() + r.Height()/2 - y
+	return dx*dx + dy*dy
+}
+
+// enqueueTargetTap 按 lockTargetStrategy 选出目标并在 executeAt 入队点击，返回是否入队
+func enqueueTargetTap(ctx *maa.Context, arg *maa.CustomRecognitionArg, executeAt time.Time) bool {
+	if lockTargetStrategy == lockStrategyDefault || ctx == nil || arg == nil || arg.Img == nil {
+		return false
+	}
+	markers, _ := enemyMarkers(ctx, arg)
+	target, ok := chooseLockTarget(markers, arg.Img.Bounds(), lockTargetStrategy)
+	if !ok {
+		log.Debug().Str("strategy", lockTargetStrategy).Msg("No enemy marker for lock target, lock directly")
+		return false
+	}
+	log.Info().
+		Str("strategy", lockTargetStrategy).
+		Int("candidates", len(markers)).
+		Ints("target", target[:]).
+		Msg("AutoFight lock target chosen")
+	enqueueAction(fightAction{
+		executeAt: executeAt,
+		action:    ActionTapTarget,
+		target:    target,
+		trigger:   "enemy_marker",
+	})
+	return true
+}
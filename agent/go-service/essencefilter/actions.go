@@ -2,13 +2,19 @@ package essencefilter
 
 import (
 	"encoding/json"
+	"fmt"
 	"image"
 	"regexp"
 	"sort"
 	"strconv"
+	"time"
 
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/essencefilter/matchapi"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/i18n"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/minicv"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/recognition"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/runguard"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/screenshot"
 	maa "github.com/MaaXYZ/maa-framework-go/v4"
 	"github.com/rs/zerolog/log"
 )
@@ -23,6 +29,9 @@ const essenceMaxSinglePageInventory = 45
 // EssenceFilterInitAction - initialize filter
 type EssenceFilterInitAction struct{}
 
+// runGuardModule 基质筛选的运行令牌名，Init 获取、Finish 释放
+const runGuardModule = "essencefilter"
+
 // afterBattleInitResetPerLoot clears state that must be fresh for each战后战利品界面；引擎与锁定汇总保留在 RunState 上由首次完整 Init 建立。
 func afterBattleInitResetPerLoot(st *RunState) {
 	st.RowBoxes = nil
@@ -44,10 +53,16 @@ func (a *EssenceFilterInitAction) Run(ctx *maa.Context, arg *maa.CustomActionArg
 		}
 	}
 
+	if err := runguard.Acquire(runGuardModule, arg.TaskID); err != nil {
+		log.Warn().Err(err).Str("component", "EssenceFilter").Msg("init rejected, already running in another task")
+		reportFocusByKey(ctx, nil, "focus.error.already_running")
+		return false
+	}
+
 	engine, opts, err := EnsureMatchEngine(ctx, nil, arg.CurrentTaskName)
 	if err != nil {
 		log.Error().Err(err).Str("component", "EssenceFilter").Str("step", "LoadMatchEngine").Msg("load match data failed")
-		reportFocusByKey(ctx, nil, "focus.error.load_engine_failed", err.Error())
+		reportLoadEngineError(ctx, err)
 		return false
 	}
 	inputLocale := matchapi.NormalizeInputLocale(opts.InputLanguage)
@@ -87,12 +102,39 @@ func (a *EssenceFilterInitAction) Run(ctx *maa.Context, arg *maa.CustomActionArg
 		essenceMode = EssenceModePureOnly
 	}
 
-	st := &RunState{MaxItemsPerRow: 9, EssenceTypes: essenceTypes}
+	// 按 ColorTolerance 放宽颜色范围，此后 RowCollect 只使用放宽后的范围
+	for i := range essenceTypes {
+		essenceTypes[i].Range = essenceTypes[i].Range.Expand(opts.ColorTolerance)
+	}
+	boundaryRange := PureEssenceMeta.Range.Expand(opts.ColorTolerance)
+	logColorRanges(essenceTypes, boundaryRange, opts.ColorTolerance)
+
+	itemsPerRow := opts.ItemsPerRow
+	if itemsPerRow <= 0 {
+		itemsPerRow = defaultItemsPerRow
+	} else if itemsPerRow > maxItemsPerRowLimit {
+		log.Error().Str("component", "EssenceFilter").Str("step", "ValidatePresets").
+			Int("items_per_row", itemsPerRow).Int("max", maxItemsPerRowLimit).Msg("items_per_row out of range")
+		reportColoredByKey(ctx, nil, "#ff0000", "focus.init.invalid_items_per_row", itemsPerRow, maxItemsPerRowLimit)
+		return false
+	}
+
+	st := &RunState{EssenceTypes: essenceTypes}
 	st.Reset()
+	st.MaxItemsPerRow = itemsPerRow
 	st.PipelineOpts = *opts
 	st.InputLanguage = inputLocale
 	st.MatchEngine = engine
 	st.EssenceMode = essenceMode
+	st.BoundaryRange = boundaryRange
+	if opts.Resume {
+		cp, err := loadCheckpoint()
+		if err != nil {
+			log.Warn().Err(err).Str("component", "EssenceFilter").Msg("load checkpoint failed, start fresh")
+			clearCheckpoint()
+		}
+		st.ResumeCheckpoint = cp
+	}
 
 	matchOpts := matchOptsFromPipeline(opts)
 	st.TargetSkillCombinations = engine.BuildTargets(matchOpts)
@@ -114,6 +156,8 @@ func (a *EssenceFilterInitAction) Run(ctx *maa.Context, arg *maa.CustomActionArg
 	log.Info().Str("component", "EssenceFilter").Msg("init done")
 
 	reportInitSkillList(ctx, st, vm.SlotSkills)
+	reportUnreachableSkills(ctx, st)
+	reportMatcherConfigWarnings(ctx, st)
 	reportDataVersionNotice(ctx, st)
 	return true
 }
@@ -144,6 +188,7 @@ func (a *OCREssenceInventoryNumberAction) Run(ctx *maa.Context, arg *maa.CustomA
 	if st := getRunState(); st != nil {
 		LogMXUHTML(ctx, i18n.RenderHTML("essencefilter.inventory_count", map[string]any{"Count": n}))
 		st.TotalCount = n
+		applyResumeCheckpoint(ctx, st)
 	} else {
 		LogMXUHTML(ctx, i18n.RenderHTML("essencefilter.inventory_count", map[string]any{"Count": n}))
 	}
@@ -177,6 +222,8 @@ func (a *EssenceFilterCheckItemAction) Run(ctx *maa.Context, arg *maa.CustomActi
 	var params struct {
 		Slot   int  `json:"slot"`
 		IsLast bool `json:"is_last"`
+		// OCR 分数下限，低于下限的结果依次回退到 Filtered/All 中的下一个候选；0 表示不校验
+		MinOcrScore float64 `json:"min_ocr_score"`
 	}
 	if arg.CustomActionParam != "" {
 		_ = json.Unmarshal([]byte(arg.CustomActionParam), &params)
@@ -195,12 +242,34 @@ func (a *EssenceFilterCheckItemAction) Run(ctx *maa.Context, arg *maa.CustomActi
 		st.CurrentSkills = [3]string{}
 		st.CurrentSkillLevels = [3]int{}
 	}
-	rawText, ok := firstOCRText(arg.RecognitionDetail)
+	// 节点参数与全局 min_ocr_confidence 取较严格者
+	minScore := max(params.MinOcrScore, st.PipelineOpts.MinOcrConfidence)
+	rawText, score, rejected, ok := ocrResultAtLeast(arg.RecognitionDetail, minScore)
+	lowConfidence := !ok && len(rejected) > 0
+	for attempt := 1; lowConfidence && !ok && attempt <= ocrLowConfidenceRetries; attempt++ {
+		log.Warn().Str("component", "EssenceFilter").Int("slot", params.Slot).Int("attempt", attempt).
+			Interface("rejected", rejected).Float64("min", minScore).Msg("OCR confidence too low, retry on fresh frame")
+		rawText, score, rejected, ok = ocrResultAtLeast(rerunOCR(ctx, arg), minScore)
+	}
 	if !ok {
+		if lowConfidence {
+			log.Warn().Str("component", "EssenceFilter").Int("slot", params.Slot).
+				Interface("rejected", rejected).Float64("min", minScore).Msg("OCR confidence too low after retries, skip item")
+			routeOCRFallback(ctx, arg, st)
+			return true
+		}
 		log.Error().Str("component", "EssenceFilter").Msg("OCR detail missing from pipeline")
 		return false
 	}
+	restoreOCRNext(ctx, arg, st)
+	if len(rejected) > 0 {
+		log.Debug().Str("component", "EssenceFilter").Int("slot", params.Slot).Interface("rejected", rejected).
+			Str("raw", rawText).Float64("score", score).Msg("OCR fell back to lower-ranked candidate")
+	}
 	text := matchapi.NormalizeInputForMatch(rawText, st.InputLanguage)
+	if st.MatchEngine != nil {
+		text = st.MatchEngine.NormalizeInput(rawText)
+	}
 	if text == "" {
 		log.Error().Str("component", "EssenceFilter").Int("slot", params.Slot).Str("raw", rawText).Msg("OCR empty")
 		return false
@@ -233,7 +302,7 @@ func (a *EssenceFilterCheckItemLevelAction) Run(ctx *maa.Context, arg *maa.Custo
 		log.Error().Str("component", "EssenceFilter").Int("slot", params.Slot).Msg("invalid level slot param")
 		return false
 	}
-	rawText, ok := firstOCRText(arg.RecognitionDetail)
+	rawText, score, ok := firstOCRResult(arg.RecognitionDetail)
 	if !ok {
 		log.Error().Str("component", "EssenceFilter").Int("slot", params.Slot).Msg("level OCR detail missing or empty")
 		return false
@@ -242,6 +311,21 @@ func (a *EssenceFilterCheckItemLevelAction) Run(ctx *maa.Context, arg *maa.Custo
 	if st == nil {
 		return false
 	}
+	minScore := st.PipelineOpts.MinOcrConfidence
+	for attempt := 1; score < minScore && attempt <= ocrLowConfidenceRetries; attempt++ {
+		log.Warn().Str("component", "EssenceFilter").Int("slot", params.Slot).Int("attempt", attempt).Str("raw", rawText).
+			Float64("score", score).Float64("min", minScore).Msg("level OCR confidence too low, retry on fresh frame")
+		if text, s, ok := firstOCRResult(rerunOCR(ctx, arg)); ok {
+			rawText, score = text, s
+		}
+	}
+	if score < minScore {
+		log.Warn().Str("component", "EssenceFilter").Int("slot", params.Slot).Str("raw", rawText).
+			Float64("score", score).Float64("min", minScore).Msg("level OCR confidence too low after retries, skip item")
+		routeOCRFallback(ctx, arg, st)
+		return true
+	}
+	restoreOCRNext(ctx, arg, st)
 	if m := levelParseRe.FindStringSubmatch(rawText); len(m) >= 2 {
 		if lv, err := strconv.Atoi(m[1]); err == nil && lv >= 1 && lv <= 6 {
 			st.CurrentSkillLevels[params.Slot-1] = lv
@@ -271,11 +355,113 @@ func (a *EssenceFilterSkillDecisionAction) Run(ctx *maa.Context, arg *maa.Custom
 		reportFocusByKey(ctx, st, "focus.error.no_match_engine")
 		return false
 	}
-	return runUnifiedSkillDecision(ctx, arg, st, st.MatchEngine, ocr, decisionNextNodes{
+
+	fingerprint := itemFingerprint(st)
+	if _, seen := st.LockedFingerprints[fingerprint]; seen {
+		st.DedupeCount++
+		log.Info().Str("component", "EssenceFilter").Str("fingerprint", fingerprint).Msg("item already locked this run, skip")
+		ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: "EssenceFilterRowNextItem"}})
+		return true
+	}
+	if isWithheld(st, fingerprint) {
+		// 暂扣件并未锁定，不计入 DedupeCount，避免已锁定汇总偏大
+		st.WithheldRevisitCount++
+		log.Info().Str("component", "EssenceFilter").Str("fingerprint", fingerprint).Msg("item already withheld this run, skip")
+		ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: "EssenceFilterRowNextItem"}})
+		return true
+	}
+
+	matchedBefore := st.MatchedCount
+	ok := runUnifiedSkillDecision(ctx, arg, st, st.MatchEngine, ocr, decisionNextNodes{
 		Lock:    "EssenceFilterLockItemLog",
 		Discard: "EssenceFilterDiscardItemLog",
 		Skip:    "EssenceFilterRowNextItem",
 	})
+	if ok && st.MatchedCount > matchedBefore {
+		st.LockedFingerprints[fingerprint] = struct{}{}
+	}
+	return ok
+}
+
+// fingerprintGrid 指纹中格子位置的量化步长（像素），吸收两次扫描间的识别框抖动
+const fingerprintGrid = 40
+
+// itemFingerprint 由当前物品的技能、等级与库存序号组成，用于识别同一次运行中重复访问的物品。
+// 屏幕位置在不同行、不同页之间会重复，只能在无法推算库存序号时退回使用，并带上当前行号区分。
+func itemFingerprint(st *RunState) string {
+	pos := "-"
+	if i := st.RowIndex - 1; i >= 0 && i < len(st.RowBoxes) {
+		rb := st.RowBoxes[i]
+		b := rb.Box
+		switch {
+		case rb.Slot >= 0:
+			pos = fmt.Sprintf("#%d", rb.Slot)
+		case st.InFinalScan:
+			pos = fmt.Sprintf("final:%d,%d", (b[0]+b[2]/2)/fingerprintGrid, (b[1]+b[3]/2)/fingerprintGrid)
+		default:
+			pos = fmt.Sprintf("row%d:%d,%d", st.CurrentRow, (b[0]+b[2]/2)/fingerprintGrid, (b[1]+b[3]/2)/fingerprintGrid)
+		}
+	}
+	return fmt.Sprintf("%s|%s|%s|%d,%d,%d|%s",
+		st.CurrentSkills[0], st.CurrentSkills[1], st.CurrentSkills[2],
+		st.CurrentSkillLevels[0], st.CurrentSkillLevels[1], st.CurrentSkillLevels[2], pos)
+}
+
+// inventorySlots 推算本次识别到的每个格子在库存中的序号（从 0 开始），与滚动位置无关。
+// 行扫：每次只识别一行，序号 = (CurrentRow-1)*MaxItemsPerRow + 列；
+// 尾扫：此前已补滑到底，画面中最后一格即库存最后一件，按格子顺序从 TotalCount 倒推。
+// 格子数与行宽或总数不符、无法推算时返回 nil。
+func inventorySlots(st *RunState, results []*maa.RecognitionResult, finalScan bool) map[[4]int]int {
+	boxes := make([][4]int, 0, len(results))
+	for _, res := range results {
+		if tm, ok := res.AsTemplateMatch(); ok {
+			b := tm.Box
+			boxes = append(boxes, [4]int{b.X(), b.Y(), b.Width(), b.Height()})
+		}
+	}
+	if len(boxes) == 0 {
+		return nil
+	}
+	sortGridBoxes(boxes)
+
+	var first int
+	switch {
+	case finalScan && st.TotalCount >= len(boxes):
+		first = st.TotalCount - len(boxes)
+	case !finalScan && st.MaxItemsPerRow > 0 && st.CurrentRow >= 1 && len(boxes) <= st.MaxItemsPerRow:
+		first = (st.CurrentRow - 1) * st.MaxItemsPerRow
+	default:
+		return nil
+	}
+	slots := make(map[[4]int]int, len(boxes))
+	for i, b := range boxes {
+		slots[b] = first + i
+	}
+	return slots
+}
+
+// sortGridBoxes 按行（中心 y 相差不足半个格子高视为同一行）再按列排序，吸收同一行内识别框的上下抖动
+func sortGridBoxes(boxes [][4]int) {
+	sort.Slice(boxes, func(i, j int) bool {
+		return boxes[i][1]+boxes[i][3]/2 < boxes[j][1]+boxes[j][3]/2
+	})
+	rowOf := make(map[[4]int]int, len(boxes))
+	row, rowY := 0, boxes[0][1]+boxes[0][3]/2
+	for _, b := range boxes {
+		cy := b[1] + b[3]/2
+		if cy-rowY > b[3]/2 {
+			row++
+			rowY = cy
+		}
+		rowOf[b] = row
+	}
+	sort.SliceStable(boxes, func(i, j int) bool {
+		ri, rj := rowOf[boxes[i]], rowOf[boxes[j]]
+		if ri != rj {
+			return ri < rj
+		}
+		return boxes[i][0] < boxes[j][0]
+	})
 }
 
 // --- RowCollect / RowNextItem / Finish / SwipeCalibrate（同一 case：行遍历与网格）---
@@ -298,10 +484,29 @@ func rowCollectThumbHit(ctx *maa.Context, img image.Image, thumbROI []int, skipL
 	}
 }
 
+// defaultColorROITopOffset 颜色识别 ROI 相对格子顶部的下移量（1080p 下基质底部色条的位置）
+const defaultColorROITopOffset = 90
+
 // EssenceFilterRowCollectAction - collect boxes in a row (TemplateMatch + ColorMatch), then RowNextItem
 type EssenceFilterRowCollectAction struct{}
 
 func (a *EssenceFilterRowCollectAction) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
+	var params struct {
+		// 颜色识别 ROI 为格子顶部下移 n 像素后的剩余部分；缺省为 90，不同 UI 缩放下需调整
+		ColorROITopOffset *int `json:"color_roi_top_offset"`
+	}
+	if arg.CustomActionParam != "" {
+		_ = json.Unmarshal([]byte(arg.CustomActionParam), &params)
+	}
+	colorROITopOffset := defaultColorROITopOffset
+	if params.ColorROITopOffset != nil {
+		if *params.ColorROITopOffset < 0 {
+			log.Warn().Str("component", "EssenceFilter").Str("action", "RowCollect").
+				Int("color_roi_top_offset", *params.ColorROITopOffset).Msg("negative color_roi_top_offset, use default")
+		} else {
+			colorROITopOffset = *params.ColorROITopOffset
+		}
+	}
 	if arg.RecognitionDetail == nil || arg.RecognitionDetail.Results == nil || !arg.RecognitionDetail.Hit {
 		log.Error().Str("component", "EssenceFilter").Str("action", "RowCollect").Msg("recognition detail empty")
 		return false
@@ -310,17 +515,18 @@ func (a *EssenceFilterRowCollectAction) Run(ctx *maa.Context, arg *maa.CustomAct
 	if st == nil {
 		return false
 	}
+	if finishOnTimeBudget(ctx, arg, st) {
+		return true
+	}
 	results := arg.RecognitionDetail.Results.Filtered
 	if len(results) == 0 {
 		results = arg.RecognitionDetail.Results.All
 	}
-	controller := ctx.GetTasker().GetController()
-	if controller == nil {
-		log.Error().Str("component", "EssenceFilter").Str("action", "RowCollect").Msg("controller nil")
-		return false
+	if skipResumedRow(ctx, arg, st, len(results)) {
+		return true
 	}
-	controller.PostScreencap().Wait()
-	img, err := controller.CacheImage()
+	// 行收集必须基于最新画面
+	img, err := screenshot.FreshFrame(ctx)
 	if err != nil {
 		log.Error().Err(err).Str("component", "EssenceFilter").Str("action", "RowCollect").Msg("get screenshot failed")
 		return false
@@ -332,6 +538,25 @@ func (a *EssenceFilterRowCollectAction) Run(ctx *maa.Context, arg *maa.CustomAct
 	skipDiscard := st.PipelineOpts.SkipThumbDiscard
 	anyThumbSkip := skipLock || skipDiscard
 	boundaryHit := false
+	invalidROICount := 0
+
+	// 各基质类型的颜色范围，无暇模式下末尾追加高纯边界范围，一次遍历全部分类
+	probeBoundary := st.EssenceMode == EssenceModeFlawlessOnly
+	ranges := make([]ColorRange, 0, len(st.EssenceTypes)+1)
+	for _, et := range st.EssenceTypes {
+		ranges = append(ranges, et.Range)
+	}
+	if probeBoundary {
+		ranges = append(ranges, st.BoundaryRange)
+	}
+	var classifyElapsed time.Duration
+	classifiedBoxes := 0
+	colorParams, singlePass := loadEssenceColorParams(ctx)
+	var rgba *image.RGBA
+	if singlePass {
+		rgba = minicv.ImageConvertRGBA(img)
+	}
+	slots := inventorySlots(st, results, arg.CurrentTaskName == "EssenceDetectFinal")
 
 	for _, res := range results {
 		tm, ok := res.AsTemplateMatch()
@@ -341,39 +566,41 @@ func (a *EssenceFilterRowCollectAction) Run(ctx *maa.Context, arg *maa.CustomAct
 		b := tm.Box
 		boxArr := [4]int{b.X(), b.Y(), b.Width(), b.Height()}
 		colorMatchROIW := boxArr[2]
-		colorMatchROIH := boxArr[3] - 90
+		colorMatchROIH := boxArr[3] - colorROITopOffset
 		if colorMatchROIW <= 0 || colorMatchROIH <= 0 {
+			invalidROICount++
 			continue
 		}
-		roi := maa.Rect{boxArr[0], boxArr[1] + 90, colorMatchROIW, colorMatchROIH}
+		roi := maa.Rect{boxArr[0], boxArr[1] + colorROITopOffset, colorMatchROIW, colorMatchROIH}
+
+		var hits []bool
+		if singlePass {
+			classifyStart := time.Now()
+			hits = classifyEssenceColors(rgba, roi, ranges, colorParams)
+			classifyElapsed += time.Since(classifyStart)
+			classifiedBoxes++
+			sampleLegacyColorMatch(ctx, st, img, roi, ranges, hits)
+		} else {
+			hits = make([]bool, len(ranges))
+			for i, r := range ranges {
+				hits[i], _ = essenceColorMatch(ctx, img, roi, r)
+			}
+		}
 
 		colorMatched := false
-		for _, et := range st.EssenceTypes {
-			cDetail, err := ctx.RunRecognition("EssenceColorMatch", img, map[string]any{
-				"EssenceColorMatch": map[string]any{"roi": roi, "lower": et.Range.Lower, "upper": et.Range.Upper},
-			})
-			if err != nil {
-				continue
-			}
-			if cDetail != nil && cDetail.Hit {
+		essenceType := ""
+		for i, et := range st.EssenceTypes {
+			if hits[i] {
 				colorMatched = true
+				essenceType = et.Key
 				break
 			}
 		}
 
-		// Flawless-only boundary: if box didn't match flawless, probe pure in the same pass.
+		// Flawless-only boundary: if box didn't match flawless, check the pure range from the same pass.
 		// First pure hit means we've reached the tier boundary (inventory is sorted flawless-first).
-		if !colorMatched && !boundaryHit && st.EssenceMode == EssenceModeFlawlessOnly {
-			cDetail, err := ctx.RunRecognition("EssenceColorMatch", img, map[string]any{
-				"EssenceColorMatch": map[string]any{
-					"roi":   roi,
-					"lower": PureEssenceMeta.Range.Lower,
-					"upper": PureEssenceMeta.Range.Upper,
-				},
-			})
-			if err == nil && cDetail != nil && cDetail.Hit {
-				boundaryHit = true
-			}
+		if !colorMatched && probeBoundary && hits[len(hits)-1] {
+			boundaryHit = true
 		}
 
 		if colorMatched {
@@ -398,17 +625,22 @@ func (a *EssenceFilterRowCollectAction) Run(ctx *maa.Context, arg *maa.CustomAct
 			}
 
 			if !isMarked {
-				st.RowBoxes = append(st.RowBoxes, boxArr)
+				slot, ok := slots[boxArr]
+				if !ok {
+					slot = -1
+				}
+				st.RowBoxes = append(st.RowBoxes, rowBox{Box: boxArr, EssenceType: essenceType, Slot: slot})
 			}
 		}
 	}
 
-	sort.Slice(st.RowBoxes, func(i, j int) bool {
-		if st.RowBoxes[i][1] == st.RowBoxes[j][1] {
-			return st.RowBoxes[i][0] < st.RowBoxes[j][0]
-		}
-		return st.RowBoxes[i][1] < st.RowBoxes[j][1]
-	})
+	logColorClassifyTiming(st, classifiedBoxes, classifyElapsed)
+	if invalidROICount > 0 {
+		log.Warn().Str("component", "EssenceFilter").Str("action", "RowCollect").
+			Int("skipped", invalidROICount).Int("color_roi_top_offset", colorROITopOffset).Msg("color ROI empty, boxes skipped")
+	}
+
+	sortRowBoxes(st.RowBoxes)
 
 	log.Info().Str("component", "EssenceFilter").Str("action", "RowCollect").Int("len_results", len(results)).Int("valid_boxes", len(st.RowBoxes)).Msg("color match done")
 
@@ -431,11 +663,17 @@ func (a *EssenceFilterRowCollectAction) Run(ctx *maa.Context, arg *maa.CustomAct
 
 	isFallbackScan := arg.CurrentTaskName == "EssenceDetectFinal"
 	st.InFinalScan = isFallbackScan
-	if isFallbackScan && !st.FinalLargeScanUsed {
-		st.FinalLargeScanUsed = true
-		ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: "EssenceDetectFinal"}})
-		reportColoredByKey(ctx, st, "#1a01fd", "focus.row.tail_scan_done")
-		return true
+	if isFallbackScan {
+		st.FinalScanCount++
+		if st.FinalScanCount == 1 {
+			ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: "EssenceDetectFinal"}})
+			reportColoredByKey(ctx, st, "#1a01fd", "focus.row.tail_scan_done")
+			return true
+		}
+		if !keepNewFinalScanBoxes(ctx, st) {
+			ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: "EssenceFilterFinish"}})
+			return true
+		}
 	}
 	if (st.PhysicalItemCount > st.MaxItemsPerRow) && !isFallbackScan {
 		ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: "EssenceFilterFinish"}})
@@ -450,6 +688,79 @@ func (a *EssenceFilterRowCollectAction) Run(ctx *maa.Context, arg *maa.CustomAct
 	return true
 }
 
+// essenceColorMatch 对 roi 做 EssenceColorMatch；色相跨 0/180 的范围拆成两段分别识别，任一命中即命中
+func essenceColorMatch(ctx *maa.Context, img image.Image, roi maa.Rect, r ColorRange) (bool, error) {
+	var lastErr error
+	for _, part := range r.HueSegments() {
+		_, hit, err := recognition.RunRecognitionRetry(ctx, "EssenceColorMatch", img, map[string]any{
+			"EssenceColorMatch": map[string]any{"roi": roi, "lower": part.Lower, "upper": part.Upper},
+		}, recognition.DefaultAttempts)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if hit {
+			return true, nil
+		}
+	}
+	return false, lastErr
+}
+
+// finishOnTimeBudget 超出 MaxRunMs 时转到 Finish。只在 RowCollect / RowNextItem 检查：
+// 此时上一格的锁定/废弃已完成、停留在库存网格，不会卡在物品详情中。
+func finishOnTimeBudget(ctx *maa.Context, arg *maa.CustomActionArg, st *RunState) bool {
+	budget := st.PipelineOpts.MaxRunMs
+	if budget <= 0 {
+		return false
+	}
+	elapsed := time.Since(st.StartedAt)
+	if elapsed < time.Duration(budget)*time.Millisecond {
+		return false
+	}
+	st.TimeBudgetReached = true
+	log.Info().Str("component", "EssenceFilter").Str("node", arg.CurrentTaskName).
+		Dur("elapsed", elapsed).Int("max_run_ms", budget).Msg("time budget reached, finishing")
+	ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: "EssenceFilterFinish"}})
+	return true
+}
+
+func finalScanPasses(st *RunState) int {
+	if st.FinalScanCount <= 1 {
+		return 0
+	}
+	return st.FinalScanCount - 1
+}
+
+func finalScanMaxPasses(st *RunState) int {
+	if st.PipelineOpts.FinalScanMaxPasses < 1 {
+		return 1
+	}
+	return st.PipelineOpts.FinalScanMaxPasses
+}
+
+// keepNewFinalScanBoxes 只保留本轮尾扫新出现的格子（之前轮次已处理过的位置不再访问）；
+// 本轮没有新格子时返回 false，表示尾扫可以结束
+func keepNewFinalScanBoxes(ctx *maa.Context, st *RunState) bool {
+	total := len(st.RowBoxes)
+	fresh := st.RowBoxes[:0]
+	for _, rb := range st.RowBoxes {
+		b := rb.Box
+		pos := [2]int{(b[0] + b[2]/2) / fingerprintGrid, (b[1] + b[3]/2) / fingerprintGrid}
+		if _, seen := st.FinalScanSeen[pos]; seen {
+			continue
+		}
+		st.FinalScanSeen[pos] = struct{}{}
+		fresh = append(fresh, rb)
+	}
+	st.RowBoxes = fresh
+
+	pass := finalScanPasses(st)
+	log.Info().Str("component", "EssenceFilter").Str("action", "RowCollect").
+		Int("pass", pass).Int("boxes", total).Int("new_boxes", len(fresh)).Msg("final scan pass")
+	reportSimpleByKey(ctx, st, "focus.row.final_scan_pass", pass, total, len(fresh))
+	return len(fresh) > 0
+}
+
 // EssenceFilterRowNextItemAction - proceed to next box or swipe/finish
 type EssenceFilterRowNextItemAction struct{}
 
@@ -458,6 +769,9 @@ func (a *EssenceFilterRowNextItemAction) Run(ctx *maa.Context, arg *maa.CustomAc
 	if st == nil {
 		return false
 	}
+	if finishOnTimeBudget(ctx, arg, st) {
+		return true
+	}
 	if st.PendingFinalScan {
 		st.PendingFinalScan = false
 		st.InFinalScan = true
@@ -473,7 +787,13 @@ func (a *EssenceFilterRowNextItemAction) Run(ctx *maa.Context, arg *maa.CustomAc
 			ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: "EssenceFilterTierBoundaryFlawlessNotice"}})
 			return true
 		}
-		if (st.PhysicalItemCount == st.MaxItemsPerRow) && !st.FinalLargeScanUsed {
+		if st.InFinalScan && finalScanPasses(st) < finalScanMaxPasses(st) {
+			log.Info().Str("component", "EssenceFilter").Str("action", "RowNextItem").
+				Int("pass", finalScanPasses(st)).Msg("final scan pass done, rescan")
+			ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: "EssenceDetectFinal"}})
+			return true
+		}
+		if (st.PhysicalItemCount == st.MaxItemsPerRow) && st.FinalScanCount == 0 {
 			rowsDone := st.CurrentRow
 			remaining := st.TotalCount - st.MaxItemsPerRow*rowsDone
 			if st.TotalCount > 0 && remaining <= essenceMaxSinglePageInventory {
@@ -496,20 +816,17 @@ func (a *EssenceFilterRowNextItemAction) Run(ctx *maa.Context, arg *maa.CustomAc
 			ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: nextNode}})
 			reportSimpleByKey(ctx, st, "focus.row.swipe_to", st.CurrentRow+1)
 			st.CurrentRow++
+			saveCheckpoint(st)
 			return true
 		}
 		ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: "EssenceFilterFinish"}})
 		return true
 	}
 
-	box := st.RowBoxes[st.RowIndex]
+	box := st.RowBoxes[st.RowIndex].Box
 	log.Info().Str("component", "EssenceFilter").Str("action", "RowNextItem").Ints("box", box[:]).Msg("click next box")
-	clickingBox := [4]int{box[0] + 10, box[1] + 10, box[2] - 20, box[3] - 20}
-	ctx.RunTask("NodeClick", map[string]any{
-		"NodeClick": map[string]any{
-			"action": map[string]any{"param": map[string]any{"target": clickingBox}},
-		},
-	})
+	clickEssenceBox(ctx, box)
+	st.CurrentEssenceType = st.RowBoxes[st.RowIndex].EssenceType
 	st.VisitedCount++
 	st.RowIndex++
 	ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: "EssenceFilterCheckItemSlot1"}})
@@ -525,10 +842,35 @@ func (a *EssenceFilterFinishAction) Run(ctx *maa.Context, arg *maa.CustomActionA
 	if st != nil {
 		log.Info().Str("component", "EssenceFilter").Int("matched_total", st.MatchedCount).Msg("locked items")
 		reportColoredByKey(ctx, st, "#11cf00", "focus.finish.summary", st.VisitedCount, st.MatchedCount)
+		if st.PipelineOpts.DryRun {
+			reportColoredByKey(ctx, st, "#ffba03", "focus.finish.dry_run", st.MatchedCount)
+		}
+		if st.DedupeCount > 0 {
+			log.Info().Str("component", "EssenceFilter").Int("dedupe", st.DedupeCount).Msg("skipped repeated items")
+			reportColoredByKey(ctx, st, "#11cf00", "focus.finish.dedupe", st.DedupeCount)
+		}
+		if st.WithheldRevisitCount > 0 {
+			log.Info().Str("component", "EssenceFilter").Int("withheld_revisit", st.WithheldRevisitCount).Msg("skipped repeated withheld items")
+			reportColoredByKey(ctx, st, "#ffba03", "focus.finish.withheld_revisit", st.WithheldRevisitCount)
+		}
+		if st.TimeBudgetReached {
+			reportColoredByKey(ctx, st, "#ffba03", "focus.finish.time_budget", st.PipelineOpts.MaxRunMs/1000)
+		}
+		reportWithheldDuplicates(ctx, st)
+		reportWarmupHeld(ctx, st)
+		reportTypeLockCounts(ctx, st)
+		if st.SlotRuleSkipCount > 0 {
+			reportColoredByKey(ctx, st, "#ffba03", "focus.finish.slot_rule_skipped", st.SlotRuleSkipCount)
+		}
 		reportFinishExtRuleStats(ctx, st)
 		reportFinishArtifacts(ctx, st)
+		// 因时间预算提前结束时保留检查点，下次可 resume 续扫
+		if !st.TimeBudgetReached {
+			clearCheckpoint()
+		}
 	}
 	setRunState(nil)
+	runguard.Release(runGuardModule, arg.TaskID)
 	return true
 }
 
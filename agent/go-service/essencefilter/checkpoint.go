@@ -0,0 +1,122 @@
+package essencefilter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	maa "github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// checkpointPath 遍历检查点文件：每滑过一行写一次，正常 Finish 时删除；任务中途被停止时保留，供下次 resume 续扫
+var checkpointPath = filepath.Join(defaultSummaryOutputDir, "checkpoint.json")
+
+// traversalCheckpoint 是续扫所需的最小遍历状态。CurrentRow 为下一个待处理的行号（从 1 开始），
+// 其前的行都已处理完；TotalCount 用于校验库存是否变化
+type traversalCheckpoint struct {
+	SavedAt           string `json:"saved_at"`
+	TotalCount        int    `json:"total_count"`
+	ItemsPerRow       int    `json:"items_per_row"`
+	CurrentRow        int    `json:"current_row"`
+	FirstRowSwipeDone bool   `json:"first_row_swipe_done"`
+	VisitedCount      int    `json:"visited_count"`
+	MatchedCount      int    `json:"matched_count"`
+}
+
+// saveCheckpoint 在 RowNextItem 滑到下一行后调用；写入失败只记日志，不影响本次运行
+func saveCheckpoint(st *RunState) {
+	cp := traversalCheckpoint{
+		SavedAt:           time.Now().Format(time.RFC3339),
+		TotalCount:        st.TotalCount,
+		ItemsPerRow:       st.MaxItemsPerRow,
+		CurrentRow:        st.CurrentRow,
+		FirstRowSwipeDone: st.FirstRowSwipeDone,
+		VisitedCount:      st.VisitedCount,
+		MatchedCount:      st.MatchedCount,
+	}
+	data, err := json.MarshalIndent(cp, "", "    ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(checkpointPath), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(checkpointPath, append(data, '\n'), 0o644)
+	}
+	if err != nil {
+		log.Warn().Err(err).Str("component", "EssenceFilter").Str("path", checkpointPath).Msg("save checkpoint failed")
+		return
+	}
+	log.Debug().Str("component", "EssenceFilter").Int("row", cp.CurrentRow).Int("visited", cp.VisitedCount).Msg("checkpoint saved")
+}
+
+// loadCheckpoint 读取检查点；文件不存在时返回 nil, nil
+func loadCheckpoint() (*traversalCheckpoint, error) {
+	data, err := os.ReadFile(checkpointPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp traversalCheckpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("parse checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+func clearCheckpoint() {
+	if err := os.Remove(checkpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn().Err(err).Str("component", "EssenceFilter").Str("path", checkpointPath).Msg("clear checkpoint failed")
+	}
+}
+
+// applyResumeCheckpoint 在库存总数 OCR 后调用：总数与每行格子数都与检查点一致时恢复统计并记下要跳过的行数，
+// 否则视为库存已变化，丢弃检查点从头扫描
+func applyResumeCheckpoint(ctx *maa.Context, st *RunState) {
+	cp := st.ResumeCheckpoint
+	if cp == nil {
+		return
+	}
+	st.ResumeCheckpoint = nil
+	if cp.TotalCount != st.TotalCount || cp.ItemsPerRow != st.MaxItemsPerRow {
+		log.Info().Str("component", "EssenceFilter").
+			Int("saved_total", cp.TotalCount).Int("total", st.TotalCount).
+			Int("saved_items_per_row", cp.ItemsPerRow).Int("items_per_row", st.MaxItemsPerRow).
+			Msg("checkpoint does not match inventory, start fresh")
+		reportSimpleByKey(ctx, st, "focus.resume.discarded", cp.TotalCount, st.TotalCount)
+		clearCheckpoint()
+		return
+	}
+	st.ResumeSkipRows = max(0, cp.CurrentRow-1)
+	st.VisitedCount = cp.VisitedCount
+	st.MatchedCount = cp.MatchedCount
+	log.Info().Str("component", "EssenceFilter").Int("skip_rows", st.ResumeSkipRows).
+		Int("visited", cp.VisitedCount).Int("matched", cp.MatchedCount).Str("saved_at", cp.SavedAt).Msg("resume from checkpoint")
+	reportSimpleByKey(ctx, st, "focus.resume.restored", st.ResumeSkipRows, cp.VisitedCount)
+}
+
+// skipResumedRow 在 RowCollect 中调用：仍有待跳过的行且本行是满行时，不收集格子直接交给 RowNextItem 滑到下一行。
+// 非满行说明画面与检查点对不上，停止跳过并按正常流程处理本行
+func skipResumedRow(ctx *maa.Context, arg *maa.CustomActionArg, st *RunState, physical int) bool {
+	if st.ResumeSkipRows <= 0 || arg.CurrentTaskName == "EssenceDetectFinal" {
+		return false
+	}
+	if physical != st.MaxItemsPerRow {
+		log.Warn().Str("component", "EssenceFilter").Int("row", st.CurrentRow).Int("physical", physical).
+			Int("skip_left", st.ResumeSkipRows).Msg("resume: row not full, stop skipping")
+		st.ResumeSkipRows = 0
+		return false
+	}
+	st.ResumeSkipRows--
+	st.RowBoxes = st.RowBoxes[:0]
+	st.PhysicalItemCount = physical
+	st.RowIndex = 0
+	log.Info().Str("component", "EssenceFilter").Str("action", "RowCollect").Int("row", st.CurrentRow).Msg("resume: skip visited row")
+	reportSimpleByKey(ctx, st, "focus.resume.skip_row", st.CurrentRow)
+	ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: "EssenceFilterRowNextItem"}})
+	return true
+}
@@ -0,0 +1,187 @@
+package essencefilter
+
+import (
+	"image"
+	"math"
+	"time"
+
+	maa "github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// essenceColorNode 单次遍历分类需复现其判定的颜色识别节点
+const essenceColorNode = "EssenceColorMatch"
+
+// essenceColorParams EssenceColorMatch 节点中决定是否命中的参数，从 pipeline 读取，避免与节点定义脱节
+type essenceColorParams struct {
+	count     int  // 命中所需的最少像素数
+	connected bool // 为 true 时按最大 8 连通区域计数，否则按范围内像素总数计数
+}
+
+// loadEssenceColorParams 读取 EssenceColorMatch 节点的 count / connected / method。
+// 单次遍历只实现了 HSV（method 40）的换算，节点不是 HSV ColorMatch 时返回 false，由调用方退回逐范围识别
+func loadEssenceColorParams(ctx *maa.Context) (essenceColorParams, bool) {
+	node, err := ctx.GetNode(essenceColorNode)
+	if err != nil || node == nil || node.Recognition == nil {
+		log.Warn().Err(err).Str("component", "EssenceFilter").Str("node", essenceColorNode).Msg("failed to read color match node")
+		return essenceColorParams{}, false
+	}
+	param, ok := node.Recognition.Param.(*maa.ColorMatchParam)
+	if !ok || param.Method != maa.ColorMatchMethodHSV {
+		log.Warn().Str("component", "EssenceFilter").Str("node", essenceColorNode).
+			Msg("color match node is not an HSV ColorMatch, fall back to per-range recognition")
+		return essenceColorParams{}, false
+	}
+	return essenceColorParams{count: max(param.Count, 1), connected: param.Connected}, true
+}
+
+// classifyEssenceColors 在截图的 roi 内一次遍历完成所有颜色范围的分类，替代逐个范围调用 EssenceColorMatch。
+// 像素按 OpenCV 8 位 HSV（H 0-179，S/V 0-255）换算，跨 0/180 的色相范围按 HueWraps 处理；
+// connected 时取 8 连通的最大区域，否则取范围内的像素总数，达到 count 即命中
+func classifyEssenceColors(img *image.RGBA, roi maa.Rect, ranges []ColorRange, params essenceColorParams) []bool {
+	hits := make([]bool, len(ranges))
+	rect := image.Rect(roi.X(), roi.Y(), roi.X()+roi.Width(), roi.Y()+roi.Height()).Intersect(img.Bounds())
+	w, h := rect.Dx(), rect.Dy()
+	if w <= 0 || h <= 0 || len(ranges) == 0 {
+		return hits
+	}
+
+	masks := make([][]bool, len(ranges))
+	for i := range masks {
+		masks[i] = make([]bool, w*h)
+	}
+	counts := make([]int, len(ranges))
+	for y := 0; y < h; y++ {
+		row := img.Pix[img.PixOffset(rect.Min.X, rect.Min.Y+y):]
+		for x := 0; x < w; x++ {
+			px := row[x*4 : x*4+3]
+			hsv := opencvHSV(int(px[0]), int(px[1]), int(px[2]))
+			for i, r := range ranges {
+				if r.contains(hsv) {
+					masks[i][y*w+x] = true
+					counts[i]++
+				}
+			}
+		}
+	}
+	for i, mask := range masks {
+		if !params.connected {
+			hits[i] = counts[i] >= params.count
+			continue
+		}
+		hits[i] = counts[i] >= params.count && largestComponent(mask, w, h, params.count) >= params.count
+	}
+	return hits
+}
+
+func (r ColorRange) contains(hsv [3]int) bool {
+	for i := 1; i < 3; i++ {
+		if hsv[i] < r.Lower[i] || hsv[i] > r.Upper[i] {
+			return false
+		}
+	}
+	if r.HueWraps() {
+		return hsv[0] >= r.Lower[0] || hsv[0] <= r.Upper[0]
+	}
+	return hsv[0] >= r.Lower[0] && hsv[0] <= r.Upper[0]
+}
+
+// hsvShift 与 OpenCV RGB2HSV_b 相同的定点数精度
+const hsvShift = 12
+
+// hsvSdivTable / hsvHdivTable 对应 OpenCV 的 sdiv_table / hdiv_table180：
+// 以定点数表示的 255/v 与 180/(6*diff)，下标 0 为 0
+var hsvSdivTable, hsvHdivTable = func() (sdiv, hdiv [256]int) {
+	for i := 1; i < 256; i++ {
+		sdiv[i] = int(math.Round(float64(255<<hsvShift) / float64(i)))
+		hdiv[i] = int(math.Round(float64(180<<hsvShift) / (6 * float64(i))))
+	}
+	return
+}()
+
+// opencvHSV 按 cv::COLOR_RGB2HSV（8 位）的定点数算法换算，逐位复现 ColorMatch method 40 使用的 HSV 值
+func opencvHSV(r, g, b int) [3]int {
+	v := max(r, g, b)
+	diff := v - min(r, g, b)
+	s := (diff*hsvSdivTable[v] + 1<<(hsvShift-1)) >> hsvShift
+	var h int
+	switch v {
+	case r:
+		h = g - b
+	case g:
+		h = b - r + 2*diff
+	default:
+		h = r - g + 4*diff
+	}
+	// 负数右移向下取整，与 OpenCV 一致
+	h = (h*hsvHdivTable[diff] + 1<<(hsvShift-1)) >> hsvShift
+	if h < 0 {
+		h += hueMax + 1
+	}
+	return [3]int{h, s, v}
+}
+
+// largestComponent 返回 mask 中 8 连通的最大区域面积；达到 enough 后提前返回，mask 会被清空
+func largestComponent(mask []bool, w, h, enough int) int {
+	best := 0
+	stack := make([]int, 0, 64)
+	for start, on := range mask {
+		if !on {
+			continue
+		}
+		mask[start] = false
+		stack = append(stack[:0], start)
+		area := 0
+		for len(stack) > 0 {
+			p := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			area++
+			px, py := p%w, p/w
+			for dy := -1; dy <= 1; dy++ {
+				for dx := -1; dx <= 1; dx++ {
+					nx, ny := px+dx, py+dy
+					if nx < 0 || ny < 0 || nx >= w || ny >= h {
+						continue
+					}
+					if q := ny*w + nx; mask[q] {
+						mask[q] = false
+						stack = append(stack, q)
+					}
+				}
+			}
+		}
+		best = max(best, area)
+		if best >= enough {
+			return best
+		}
+	}
+	return best
+}
+
+// sampleLegacyColorMatch 每次运行只对第一个格子额外跑一遍逐范围的 EssenceColorMatch，
+// 记录其耗时用于估算单次遍历节省的时间，并在结果不一致时告警
+func sampleLegacyColorMatch(ctx *maa.Context, st *RunState, img image.Image, roi maa.Rect, ranges []ColorRange, hits []bool) {
+	if st.LegacyColorMatchCost > 0 {
+		return
+	}
+	start := time.Now()
+	for i, r := range ranges {
+		legacy, err := essenceColorMatch(ctx, img, roi, r)
+		if err == nil && legacy != hits[i] {
+			log.Warn().Str("component", "EssenceFilter").Str("action", "RowCollect").
+				Int("range", i).Bool("legacy", legacy).Bool("single_pass", hits[i]).Msg("color classification differs from EssenceColorMatch")
+		}
+	}
+	st.LegacyColorMatchCost = max(time.Since(start), time.Nanosecond)
+}
+
+// logColorClassifyTiming 每行收集结束时输出单次遍历的耗时与相对逐范围识别的估算节省
+func logColorClassifyTiming(st *RunState, boxes int, elapsed time.Duration) {
+	if boxes == 0 {
+		return
+	}
+	legacy := st.LegacyColorMatchCost * time.Duration(boxes)
+	log.Debug().Str("component", "EssenceFilter").Str("action", "RowCollect").
+		Int("boxes", boxes).Dur("single_pass", elapsed).Dur("legacy_estimate", legacy).Dur("saved", legacy-elapsed).
+		Msg("color classification timing")
+}
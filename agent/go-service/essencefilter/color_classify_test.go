@@ -0,0 +1,110 @@
+package essencefilter
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	maa "github.com/MaaXYZ/maa-framework-go/v4"
+)
+
+// 期望值按 cv::cvtColor(COLOR_RGB2HSV) 的 8 位定点数算法手算：H 为角度的一半（0-179），S/V 为 0-255
+func TestOpencvHSV(t *testing.T) {
+	cases := []struct {
+		name    string
+		r, g, b int
+		want    [3]int
+	}{
+		{"red", 255, 0, 0, [3]int{0, 255, 255}},
+		{"yellow", 255, 255, 0, [3]int{30, 255, 255}},
+		{"green", 0, 255, 0, [3]int{60, 255, 255}},
+		{"cyan", 0, 255, 255, [3]int{90, 255, 255}},
+		{"blue", 0, 0, 255, [3]int{120, 255, 255}},
+		{"magenta", 255, 0, 255, [3]int{150, 255, 255}},
+		{"black", 0, 0, 0, [3]int{0, 0, 0}},
+		{"gray", 128, 128, 128, [3]int{0, 0, 128}},
+		// S 精确值为 127.5，但定点数表 255/200 取整后得 127，浮点四舍五入会得到 128
+		{"half saturation", 200, 100, 100, [3]int{0, 127, 200}},
+		// 色相 1.18 / -1.18，位于 0/180 边界两侧，负值加 180 回绕
+		{"just above 0", 255, 10, 0, [3]int{1, 255, 255}},
+		{"just below 180", 255, 0, 10, [3]int{179, 255, 255}},
+		// 色相 -0.12 四舍五入为 0，不会得到 180
+		{"wraps to 0", 255, 0, 1, [3]int{0, 255, 255}},
+		// Flawless 基质的金色，H 18.46
+		{"flawless gold", 255, 180, 60, [3]int{18, 195, 255}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := opencvHSV(tc.r, tc.g, tc.b); got != tc.want {
+				t.Errorf("opencvHSV(%d, %d, %d) = %v, want %v", tc.r, tc.g, tc.b, got, tc.want)
+			}
+		})
+	}
+}
+
+// maskFromRows 把 "#." 字符画转成掩码，# 为命中
+func maskFromRows(rows ...string) ([]bool, int, int) {
+	w, h := len(rows[0]), len(rows)
+	mask := make([]bool, w*h)
+	for y, row := range rows {
+		for x, c := range row {
+			mask[y*w+x] = c == '#'
+		}
+	}
+	return mask, w, h
+}
+
+func TestLargestComponent(t *testing.T) {
+	cases := []struct {
+		name   string
+		rows   []string
+		enough int
+		want   int
+	}{
+		{"empty", []string{"....", "...."}, 10, 0},
+		{"two blobs", []string{"##..#", "##..#", "....."}, 10, 4},
+		// 对角相邻按 8 连通算作同一区域
+		{"diagonal", []string{"#...", ".#..", "..#.", "...#"}, 10, 4},
+		{"ring", []string{"###", "#.#", "###"}, 10, 8},
+		{"touching edges", []string{"#...#", ".....", "#...#"}, 10, 1},
+		// 达到 enough 后提前返回，不再找更大的区域
+		{"early return", []string{"##...", ".....", "#####"}, 2, 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mask, w, h := maskFromRows(tc.rows...)
+			if got := largestComponent(mask, w, h, tc.enough); got != tc.want {
+				t.Errorf("largestComponent() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClassifyConnectedVsTotal(t *testing.T) {
+	// 两块互不相连的 3 像素红色区域，共 6 个像素
+	img := image.NewRGBA(image.Rect(0, 0, 8, 3))
+	red := color.RGBA{255, 0, 0, 255}
+	for _, p := range [][2]int{{0, 0}, {1, 0}, {2, 0}, {5, 2}, {6, 2}, {7, 2}} {
+		img.SetRGBA(p[0], p[1], red)
+	}
+	ranges := []ColorRange{{Lower: [3]int{0, 200, 200}, Upper: [3]int{5, 255, 255}}}
+	roi := maa.Rect{0, 0, 8, 3}
+
+	cases := []struct {
+		count     int
+		connected bool
+		want      bool
+	}{
+		{6, false, true},
+		{7, false, false},
+		{3, true, true},
+		// 像素总数够，但最大连通区域只有 3
+		{4, true, false},
+	}
+	for _, tc := range cases {
+		hits := classifyEssenceColors(img, roi, ranges, essenceColorParams{count: tc.count, connected: tc.connected})
+		if hits[0] != tc.want {
+			t.Errorf("count=%d connected=%v: hit = %v, want %v", tc.count, tc.connected, hits[0], tc.want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package essencefilter
+
+import (
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/essencefilter/matchapi"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/override"
+	maa "github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// withheldItem 仅保留重复组合模式下暂未锁定的单件。
+// 只有仍处于同一行扫描（同一批 RowBoxes）时才能点回去补锁；滑过之后只能在结束时提示手动处理。
+type withheldItem struct {
+	Fingerprint string
+	Row         int
+	InFinalScan bool
+	Box         [4]int
+	HasBox      bool
+	EssenceType string
+	Rarity      int
+	ComboKey    string
+	Combo       *matchapi.SkillCombinationSummary
+}
+
+func duplicateMinCount(st *RunState) int {
+	if st.PipelineOpts.DuplicateMinCount < 2 {
+		return 2
+	}
+	return st.PipelineOpts.DuplicateMinCount
+}
+
+func currentWithheldItem(st *RunState) withheldItem {
+	item := withheldItem{
+		Fingerprint: itemFingerprint(st),
+		Row:         st.CurrentRow,
+		InFinalScan: st.InFinalScan,
+		EssenceType: st.CurrentEssenceType,
+		Rarity:      st.CurrentMaxRarity,
+		ComboKey:    st.CurrentComboKey,
+		Combo:       st.CurrentCombo,
+	}
+	if i := st.RowIndex - 1; i >= 0 && i < len(st.RowBoxes) {
+		item.Box = st.RowBoxes[i].Box
+		item.HasBox = true
+	}
+	return item
+}
+
+func (w withheldItem) inCurrentRow(st *RunState) bool {
+	return w.HasBox && w.Row == st.CurrentRow && w.InFinalScan == st.InFinalScan
+}
+
+func isWithheld(st *RunState, fingerprint string) bool {
+	for _, it := range st.WarmupHeld {
+		if it.Fingerprint == fingerprint {
+			return true
+		}
+	}
+	for _, items := range st.WithheldItems {
+		for _, it := range items {
+			if it.Fingerprint == fingerprint {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withholdDuplicate 在精准匹配并更新 CombinationSeenCount 后调用。
+// 返回 true 表示该组合尚未达到重复阈值，本件应跳过而不锁定；
+// 达到阈值时先尝试补锁同一行内此前暂扣的单件，再由调用方锁定当前物品。
+func withholdDuplicate(ctx *maa.Context, st *RunState, key string, count int) bool {
+	if st == nil || !st.PipelineOpts.KeepDuplicatesOnly || key == "" {
+		return false
+	}
+	minCount := duplicateMinCount(st)
+	if count < minCount {
+		st.WithheldItems[key] = append(st.WithheldItems[key], currentWithheldItem(st))
+		log.Info().Str("component", "EssenceFilter").Str("key", key).Int("count", count).Int("min", minCount).Msg("withhold singleton")
+		reportSimpleByKey(ctx, st, "focus.duplicates.withheld", count, minCount)
+		return true
+	}
+	retroLockWithheld(ctx, st, key)
+	return false
+}
+
+// retroLockWithheld 补锁该组合在同一行内暂扣的物品
+func retroLockWithheld(ctx *maa.Context, st *RunState, key string) {
+	remaining, locked := retroLockItems(ctx, st, st.WithheldItems[key])
+	st.WithheldItems[key] = remaining
+	if locked == 0 {
+		return
+	}
+	st.RetroLockedCount += locked
+	log.Info().Str("component", "EssenceFilter").Str("key", key).Int("locked", locked).Msg("retro-locked withheld items")
+	reportSimpleByKey(ctx, st, "focus.duplicates.retro_locked", locked)
+}
+
+// retroLockItems 点回同一行内暂扣的物品逐个锁定，完成后再点回当前物品；返回未能补锁的物品与补锁件数。
+func retroLockItems(ctx *maa.Context, st *RunState, items []withheldItem) ([]withheldItem, int) {
+	if len(items) == 0 {
+		return items, 0
+	}
+
+	remaining := items[:0]
+	locked := 0
+	for _, it := range items {
+		// 试运行时只计数，不点回物品锁定
+		if !it.inCurrentRow(st) || !typeLockAllowed(ctx, st, it.EssenceType) || !(st.PipelineOpts.DryRun || retroLockItem(ctx, it.Box)) {
+			remaining = append(remaining, it)
+			continue
+		}
+		locked++
+		st.MatchedCount++
+		st.RarityLockedCount[it.Rarity]++
+		if it.EssenceType != "" {
+			st.TypeLockedCount[it.EssenceType]++
+		}
+		addMatchedSummary(st, it.ComboKey, it.Combo)
+		st.LockedFingerprints[it.Fingerprint] = struct{}{}
+	}
+
+	if locked > 0 && !st.PipelineOpts.DryRun {
+		if i := st.RowIndex - 1; i >= 0 && i < len(st.RowBoxes) {
+			clickEssenceBox(ctx, st.RowBoxes[i].Box)
+		}
+	}
+	return remaining, locked
+}
+
+func retroLockItem(ctx *maa.Context, box [4]int) bool {
+	clickEssenceBox(ctx, box)
+	detail, err := ctx.RunTask("EssenceFilterLockItem", override.
+		Node("EssenceFilterLockItem").Set("next", []string{"EssenceFilterCheckLocked"}).
+		Node("EssenceFilterCheckLocked").Set("next", []string{}).Set("on_error", []string{}).
+		Map())
+	if err != nil || detail == nil || !detail.Status.Success() {
+		log.Warn().Err(err).Str("component", "EssenceFilter").Ints("box", box[:]).Msg("retro-lock failed")
+		return false
+	}
+	return true
+}
+
+func clickEssenceBox(ctx *maa.Context, box [4]int) {
+	clickingBox := [4]int{box[0] + 10, box[1] + 10, box[2] - 20, box[3] - 20}
+	ctx.RunTask("NodeClick", override.Node("NodeClick").Set("action.param.target", clickingBox).Map())
+}
+
+// reportWithheldDuplicates 汇总结束时仍未锁定的暂扣件：未达阈值的单件，以及达到阈值但已滑过无法补锁的件。
+func reportWithheldDuplicates(ctx *maa.Context, st *RunState) {
+	if st == nil || !st.PipelineOpts.KeepDuplicatesOnly {
+		return
+	}
+	minCount := duplicateMinCount(st)
+	singletons, missed := 0, 0
+	for key, items := range st.WithheldItems {
+		if st.CombinationSeenCount[key] >= minCount {
+			missed += len(items)
+		} else {
+			singletons += len(items)
+		}
+	}
+	log.Info().Str("component", "EssenceFilter").
+		Int("singletons", singletons).Int("missed", missed).Int("retro_locked", st.RetroLockedCount).
+		Msg("keep duplicates only summary")
+	reportColoredByKey(ctx, st, "#11cf00", "focus.finish.duplicates", singletons, st.RetroLockedCount)
+	if missed > 0 {
+		reportColoredByKey(ctx, st, "#ffba03", "focus.finish.duplicates_missed", missed)
+	}
+}
@@ -1,29 +1,44 @@
 package essencefilter
 
 import (
+	"errors"
 	"fmt"
-	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/essencefilter/matchapi"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/events"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/i18n"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/maafocus"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/metrics"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/resource"
 	maa "github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
 )
 
+const essenceFilterDataDir = "data/EssenceFilter"
+
 func dataDirFromResourceBase() string {
-	base := getResourceBase()
-	if base == "" {
-		base = "data"
+	if dir := resource.Find(essenceFilterDataDir); dir != "" {
+		return dir
 	}
-	return filepath.Join(base, "EssenceFilter")
+	return essenceFilterDataDir
 }
 
 func reportFocusByKey(ctx *maa.Context, _ *RunState, key string, args ...any) {
 	maafocus.Print(ctx, i18n.T("essencefilter."+key, args...))
 }
 
+// reportLoadEngineError 向 MXU 报告匹配引擎加载失败；数据 schema 不兼容时给出明确提示
+func reportLoadEngineError(ctx *maa.Context, err error) {
+	var schemaErr *matchapi.SchemaVersionError
+	if errors.As(err, &schemaErr) {
+		reportFocusByKey(ctx, nil, "focus.error.schema_mismatch", schemaErr.File, schemaErr.Found, schemaErr.Supported)
+		return
+	}
+	reportFocusByKey(ctx, nil, "focus.error.load_engine_failed", err.Error())
+}
+
 func reportSimpleByKey(ctx *maa.Context, _ *RunState, key string, args ...any) {
 	LogMXUSimpleHTML(ctx, i18n.T("essencefilter."+key, args...))
 }
@@ -40,16 +55,18 @@ func buildMatchOptions(st *RunState) matchapi.EssenceFilterOptions {
 }
 
 func reportOCRSkills(ctx *maa.Context, skills []string, levels [3]int, matched bool) {
-	color := "#00bfff"
-	if matched {
-		color = "#064d7c"
-	}
+	color := activeTheme().ocrColor(matched)
 	text := i18n.T("essencefilter.focus.ocr_skills",
 		skills[0], levels[0], skills[1], levels[1], skills[2], levels[2])
 	LogMXUSimpleHTMLWithColor(ctx, text, color)
 }
 
 func reportMatchedWeapons(ctx *maa.Context, weapons []matchapi.WeaponData) {
+	ids := make([]string, 0, len(weapons))
+	for _, w := range weapons {
+		ids = append(ids, w.InternalID)
+	}
+	events.Match(ctx, "essencefilter", "matched_weapons", map[string]any{"weapons": ids})
 	LogMXUHTML(ctx, i18n.RenderHTML("essencefilter.matched_weapons", map[string]any{
 		"Weapons": weaponsToViews(weapons),
 	}))
@@ -117,6 +134,103 @@ func buildInitViewModel(st *RunState) InitViewModel {
 	return vm
 }
 
+// unreachableSkills 返回目标组合中永远无法命中的技能名（按槽位）：
+// 技能未能解析到技能池，或 slot2/slot3 技能不出现在任何刷取地点中。
+func unreachableSkills(st *RunState) [3][]string {
+	var out [3][]string
+	if st == nil || st.MatchEngine == nil {
+		return out
+	}
+
+	var present [3]map[int]bool
+	for i := range present {
+		present[i] = make(map[int]bool)
+	}
+	for _, s := range st.MatchEngine.SkillPools().Slot1 {
+		present[0][s.ID] = true
+	}
+	locations := st.MatchEngine.Locations()
+	for _, loc := range locations {
+		for _, id := range loc.Slot2IDs {
+			present[1][id] = true
+		}
+		for _, id := range loc.Slot3IDs {
+			present[2][id] = true
+		}
+	}
+	if len(locations) == 0 {
+		// 没有地点数据时无法判断掉落，仅以技能池为准
+		for _, s := range st.MatchEngine.SkillPools().Slot2 {
+			present[1][s.ID] = true
+		}
+		for _, s := range st.MatchEngine.SkillPools().Slot3 {
+			present[2][s.ID] = true
+		}
+	}
+
+	seen := [3]map[string]bool{{}, {}, {}}
+	for _, combo := range st.TargetSkillCombinations {
+		for i := 0; i < 3 && i < len(combo.SkillIDs); i++ {
+			if present[i][combo.SkillIDs[i]] {
+				continue
+			}
+			name := ""
+			if i < len(combo.SkillsChinese) {
+				name = combo.SkillsChinese[i]
+			}
+			if name == "" {
+				name = fmt.Sprintf("#%d", combo.SkillIDs[i])
+			}
+			if !seen[i][name] {
+				seen[i][name] = true
+				out[i] = append(out[i], name)
+			}
+		}
+	}
+	for i := range out {
+		sort.Strings(out[i])
+	}
+	return out
+}
+
+// reportUnreachableSkills 目标组合引用了无法命中的技能时给出黄色警告，只提示不中止
+func reportUnreachableSkills(ctx *maa.Context, st *RunState) {
+	slots := unreachableSkills(st)
+	var parts []string
+	for i, names := range slots {
+		if len(names) == 0 {
+			continue
+		}
+		parts = append(parts, i18n.T("essencefilter.focus.init.unreachable_slot", i+1, strings.Join(names, i18n.Separator())))
+	}
+	if len(parts) == 0 {
+		return
+	}
+	log.Warn().Str("component", "EssenceFilter").Strs("slots", parts).Msg("target combinations reference unreachable skills")
+	reportColoredByKey(ctx, st, "#ffba03", "focus.init.unreachable_skills", strings.Join(parts, "; "))
+}
+
+// reportMatcherConfigWarnings 以低优先级（灰色）提示 matcher_config.json 中不会生效的条目，便于尽早发现笔误
+func reportMatcherConfigWarnings(ctx *maa.Context, st *RunState) {
+	if st == nil || st.MatchEngine == nil {
+		return
+	}
+	warnings := st.MatchEngine.ConfigWarnings()
+	if len(warnings) == 0 {
+		return
+	}
+	parts := make([]string, 0, len(warnings))
+	for _, w := range warnings {
+		switch w.Kind {
+		case matchapi.ConfigWarningSimilarWord:
+			parts = append(parts, i18n.T("essencefilter.focus.init.config_similar_word", w.Key, w.Value))
+		default:
+			parts = append(parts, i18n.T("essencefilter.focus.init.config_stopword", w.Key))
+		}
+	}
+	reportColoredByKey(ctx, st, "#9e9e9e", "focus.init.config_warnings", strings.Join(parts, i18n.Separator()))
+}
+
 func reportInitSelection(ctx *maa.Context, st *RunState, weaponRarity []int, essenceTypes []EssenceMeta) {
 	if len(weaponRarity) == 0 {
 		reportSimpleByKey(ctx, st, "focus.init.no_weapon_rarity")
@@ -221,9 +335,22 @@ func reportFinishArtifacts(ctx *maa.Context, st *RunState) {
 		return
 	}
 	logMatchSummary(ctx)
+	logRaritySummary(ctx)
 	if st.PipelineOpts.ExportCalculatorScript {
 		logCalculatorResult(ctx)
 	}
+	if path, n, err := exportMatchedPreset(st); err != nil {
+		log.Error().Err(err).Str("component", "EssenceFilter").Msg("export matched preset failed")
+		reportColoredByKey(ctx, st, "#ffba03", "focus.finish.preset_export_failed", err.Error())
+	} else if path != "" {
+		reportColoredByKey(ctx, st, "#11cf00", "focus.finish.preset_exported", n, path)
+	}
+	if path, err := exportMatchSummary(st); err != nil {
+		log.Error().Err(err).Str("component", "EssenceFilter").Msg("export match summary failed")
+		reportColoredByKey(ctx, st, "#ffba03", "focus.finish.summary_export_failed", err.Error())
+	} else if path != "" {
+		reportSimpleByKey(ctx, st, "focus.finish.summary_exported", path)
+	}
 }
 
 type decisionNextNodes struct {
@@ -253,27 +380,25 @@ func runUnifiedSkillDecision(
 	}
 
 	reportOCRSkills(ctx, skills, ocr.Levels, matchResult.Kind != matchapi.MatchNone)
+	st.CurrentMaxRarity = maxWeaponRarity(matchResult.Weapons)
+	st.CurrentComboKey, st.CurrentCombo = "", nil
 
 	switch matchResult.Kind {
 	case matchapi.MatchExact:
-		st.MatchedCount++
 		reportMatchedWeapons(ctx, matchResult.Weapons)
 
 		key := skillCombinationKey(matchResult.SkillIDs)
+		count := 1
 		if key != "" {
-			if s, ok := st.MatchedCombinationSummary[key]; ok {
-				s.Count++
-			} else {
-				st.MatchedCombinationSummary[key] = &matchapi.SkillCombinationSummary{
-					SkillIDs:      append([]int(nil), matchResult.SkillIDs...),
-					SkillsChinese: append([]string(nil), matchResult.SkillsChinese...),
-					OCRSkills:     append([]string(nil), skills...),
-					Weapons:       append([]matchapi.WeaponData(nil), matchResult.Weapons...),
-					Count:         1,
-				}
-			}
+			st.CombinationSeenCount[key]++
+			count = st.CombinationSeenCount[key]
 		}
-		ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: next.Lock}})
+		setCurrentCombo(st, key, matchResult, skills, matchResult.Weapons)
+		if withholdDuplicate(ctx, st, key, count) {
+			ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: next.Skip}})
+			break
+		}
+		lockCurrentItem(ctx, arg, st, slotRuleSkills(st, engine, ocr, matchResult), next)
 
 	case matchapi.MatchFuturePromising, matchapi.MatchSlot3Level3Practical:
 		var reason string
@@ -281,6 +406,13 @@ func runUnifiedSkillDecision(
 			st.ExtFuturePromisingCount++
 			reason = i18n.T("essencefilter.reason.future_promising",
 				matchResult.ExtLevelSum, matchResult.ExtMinTotal)
+			log.Info().
+				Str("component", "EssenceFilter").
+				Int("distinct", matchResult.ExtDistinct).
+				Int("min_distinct", st.PipelineOpts.FuturePromisingMinDistinct).
+				Int("level_sum", matchResult.ExtLevelSum).
+				Int("min_total", matchResult.ExtMinTotal).
+				Msg("future promising matched")
 		} else {
 			st.ExtSlot3PracticalCount++
 			reason = i18n.T("essencefilter.reason.slot3_practical",
@@ -288,36 +420,23 @@ func runUnifiedSkillDecision(
 		}
 
 		if matchResult.ShouldLock {
-			st.MatchedCount++
 			// 与精准匹配相同，均用 skillCombinationKey（未来可期时 SkillIDs 为各槽池解析出的 ID，未识别槽为 0）。
 			key := skillCombinationKey(matchResult.SkillIDs)
-			if key != "" {
-				if s, ok := st.MatchedCombinationSummary[key]; ok {
-					s.Count++
-				} else {
-					weapons := append([]matchapi.WeaponData(nil), matchResult.Weapons...)
-					if len(weapons) == 0 {
-						// 无关联武器名时，用一条占位武器承载扩展规则说明（与 reportExtRule 同文案），沿用既有战利品摘要渲染
-						weapons = []matchapi.WeaponData{{ChineseName: reason, Rarity: 3}}
-					}
-					st.MatchedCombinationSummary[key] = &matchapi.SkillCombinationSummary{
-						SkillIDs:      append([]int(nil), matchResult.SkillIDs...),
-						SkillsChinese: append([]string(nil), matchResult.SkillsChinese...),
-						OCRSkills:     append([]string(nil), skills...),
-						Weapons:       weapons,
-						Count:         1,
-					}
-				}
+			weapons := matchResult.Weapons
+			if len(weapons) == 0 {
+				// 无关联武器名时，用一条占位武器承载扩展规则说明（与 reportExtRule 同文案），沿用既有战利品摘要渲染
+				weapons = []matchapi.WeaponData{{ChineseName: reason, Rarity: 3}}
 			}
+			setCurrentCombo(st, key, matchResult, skills, weapons)
 			reportExtRule(ctx, reason, true)
-			ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: next.Lock}})
+			lockCurrentItem(ctx, arg, st, slotRuleSkills(st, engine, ocr, matchResult), next)
 		} else {
 			reportExtRule(ctx, reason, false)
 			ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: next.Skip}})
 		}
 
 	case matchapi.MatchNone:
-		if matchResult.ShouldDiscard {
+		if matchResult.ShouldDiscard && !st.PipelineOpts.DryRun {
 			reportNoMatch(ctx, true)
 			ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: next.Discard}})
 		} else {
@@ -331,6 +450,116 @@ func runUnifiedSkillDecision(
 	return true
 }
 
+// setCurrentCombo 记录当前物品的组合摘要模板，待 lockCurrentItem 或补锁成功后再由 addMatchedSummary 计入
+func setCurrentCombo(st *RunState, key string, matchResult *matchapi.MatchResult, skills []string, weapons []matchapi.WeaponData) {
+	if key == "" {
+		return
+	}
+	st.CurrentComboKey = key
+	st.CurrentCombo = &matchapi.SkillCombinationSummary{
+		SkillIDs:      append([]int(nil), matchResult.SkillIDs...),
+		SkillsChinese: append([]string(nil), matchResult.SkillsChinese...),
+		OCRSkills:     append([]string(nil), skills...),
+		Weapons:       append([]matchapi.WeaponData(nil), weapons...),
+	}
+}
+
+// addMatchedSummary 将一件已锁定物品计入 MatchedCombinationSummary；combo 为 nil（非组合命中）时忽略
+func addMatchedSummary(st *RunState, key string, combo *matchapi.SkillCombinationSummary) {
+	if key == "" || combo == nil {
+		return
+	}
+	if s, ok := st.MatchedCombinationSummary[key]; ok {
+		s.Count++
+		return
+	}
+	s := *combo
+	s.Count = 1
+	st.MatchedCombinationSummary[key] = &s
+}
+
+// lockCurrentItem 计入锁定数并跳转到锁定节点；锁定预热未完成或当前基质类型已达 PerTypeLockLimit 时改为跳过。
+// 试运行（dry_run）时照常计数，但跳转到下一格而不锁定；被槽位黑/白名单拦截时同样跳过。
+func lockCurrentItem(ctx *maa.Context, arg *maa.CustomActionArg, st *RunState, slotSkills [3]string, next decisionNextNodes) bool {
+	if applySlotRules(ctx, st, slotSkills) {
+		ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: next.Skip}})
+		return false
+	}
+	if holdForWarmup(ctx, st) {
+		ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: next.Skip}})
+		return false
+	}
+	if !typeLockAllowed(ctx, st, st.CurrentEssenceType) {
+		ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: next.Skip}})
+		return false
+	}
+	st.MatchedCount++
+	addMatchedSummary(st, st.CurrentComboKey, st.CurrentCombo)
+	metrics.Inc("essencefilter.locked")
+	st.RarityLockedCount[st.CurrentMaxRarity]++
+	if st.CurrentEssenceType != "" {
+		st.TypeLockedCount[st.CurrentEssenceType]++
+	}
+	if st.PipelineOpts.DryRun {
+		log.Info().Str("component", "EssenceFilter").Msg("dry run, would lock")
+		ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: next.Skip}})
+		return true
+	}
+	ctx.OverrideNext(arg.CurrentTaskName, []maa.NextItem{{Name: next.Lock}})
+	return true
+}
+
+func maxWeaponRarity(weapons []matchapi.WeaponData) int {
+	best := 0
+	for _, w := range weapons {
+		if w.Rarity > best {
+			best = w.Rarity
+		}
+	}
+	return best
+}
+
+// typeLockAllowed 检查该基质类型是否仍可锁定；首次触及上限时提示一次
+func typeLockAllowed(ctx *maa.Context, st *RunState, essenceType string) bool {
+	limit := st.PipelineOpts.PerTypeLockLimit[essenceType]
+	if essenceType == "" || limit <= 0 || st.TypeLockedCount[essenceType] < limit {
+		return true
+	}
+	if st.TypeLimitSkipCount[essenceType] == 0 {
+		log.Info().Str("component", "EssenceFilter").Str("essence_type", essenceType).Int("limit", limit).Msg("per-type lock limit reached")
+		reportColoredByKey(ctx, st, "#ffba03", "focus.type_limit.reached", essenceTypeName(essenceType), limit)
+	}
+	st.TypeLimitSkipCount[essenceType]++
+	return false
+}
+
+func essenceTypeName(key string) string {
+	for _, meta := range []EssenceMeta{FlawlessEssenceMeta, PureEssenceMeta} {
+		if meta.Key == key {
+			return meta.Name
+		}
+	}
+	return key
+}
+
+// reportTypeLockCounts 结束时按基质类型输出锁定数；仅在多类型或配置了上限时输出
+func reportTypeLockCounts(ctx *maa.Context, st *RunState) {
+	if st == nil || (len(st.EssenceTypes) < 2 && len(st.PipelineOpts.PerTypeLockLimit) == 0) {
+		return
+	}
+	for _, et := range st.EssenceTypes {
+		limit := "-"
+		if n := st.PipelineOpts.PerTypeLockLimit[et.Key]; n > 0 {
+			limit = fmt.Sprintf("%d", n)
+		}
+		log.Info().Str("component", "EssenceFilter").Str("essence_type", et.Key).
+			Int("locked", st.TypeLockedCount[et.Key]).Int("limit_skipped", st.TypeLimitSkipCount[et.Key]).
+			Msg("per-type lock count")
+		reportColoredByKey(ctx, st, "#11cf00", "focus.finish.type_counts",
+			et.Name, st.TypeLockedCount[et.Key], limit, st.TypeLimitSkipCount[et.Key])
+	}
+}
+
 // EnsureMatchEngine centralizes engine initialization and reuse logic.
 // If run state already has an engine, it is reused directly.
 // Otherwise, options + locale are read from node attach and an engine is loaded.
@@ -14,6 +14,7 @@ type Engine struct {
 	cfg               MatcherConfig
 	data              EngineData
 	slotIdx           [3]slotIndex
+	configWarnings    []ConfigWarning
 	matchTraceEnabled bool
 
 	slotIndicesOnce sync.Once
@@ -51,6 +52,17 @@ func (e *Engine) Weapons() []WeaponData {
 	return e.data.Weapons
 }
 
+// NormalizeInput normalizes OCR or pool text for this engine's locale and matcher config
+// (see MatcherConfig.KeepDigitsAndLatin); prefer it over NormalizeInputForMatch when an engine is loaded.
+func (e *Engine) NormalizeInput(text string) string {
+	return normalizeForMatchOpts(text, e.locale, e.cfg.KeepDigitsAndLatin)
+}
+
+// ConfigWarnings returns matcher_config.json entries found to be dead at load time; see validateMatcherConfig.
+func (e *Engine) ConfigWarnings() []ConfigWarning {
+	return e.configWarnings
+}
+
 // Locations returns the location extra-pool rows currently loaded in this engine.
 // The returned slice aliases the engine's backing array; callers must treat it as read-only.
 func (e *Engine) Locations() []Location {
@@ -88,7 +100,7 @@ func NewEngineFromDirWithLocale(dataDir string, locale string) (*Engine, error)
 	if err != nil {
 		return nil, err
 	}
-	weapons, err := loadWeaponsOutputAndConvert(dataDir, cfg, pools, loc)
+	weapons, displays, err := loadWeaponsOutputAndConvert(dataDir, cfg, pools, loc)
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +117,7 @@ func NewEngineFromDirWithLocale(dataDir string, locale string) (*Engine, error)
 			Weapons:    weapons,
 			Locations:  locations,
 		},
+		configWarnings:    validateMatcherConfig(cfg, pools, displays, loc),
 		matchTraceEnabled: isMatchTraceEnabled(),
 		targetsCache:      make(map[string][]SkillCombination),
 	}, nil
@@ -148,11 +161,12 @@ func (e *Engine) MatchOCR(ocr OCRInput, opts EssenceFilterOptions) (*MatchResult
 
 	// 2) Extension rules: evaluate both first, then OR lock decision.
 	futureMatched := false
-	futureMinTotal := 0
+	futureMinTotal, futureDistinct, futureSum := 0, 0, 0
 	if opts.KeepFuturePromising && opts.FuturePromisingMinTotal > 0 {
-		if e.matchFuturePromising(ocrSkills, ocrLevels, opts.FuturePromisingMinTotal) {
+		if ok, distinct, sum := e.matchFuturePromising(ocrSkills, ocrLevels, opts.FuturePromisingMinTotal, opts.FuturePromisingMinDistinct); ok {
 			futureMatched = true
 			futureMinTotal = opts.FuturePromisingMinTotal
+			futureDistinct, futureSum = distinct, sum
 		}
 	}
 
@@ -196,7 +210,8 @@ func (e *Engine) MatchOCR(ocr OCRInput, opts EssenceFilterOptions) (*MatchResult
 				SkillIDs:      []int{fpIDs[0], fpIDs[1], fpIDs[2]},
 				SkillsChinese: []string{ocrSkills[0], ocrSkills[1], ocrSkills[2]},
 				Weapons:       []WeaponData{},
-				ExtLevelSum:   ocrLevels[0] + ocrLevels[1] + ocrLevels[2],
+				ExtLevelSum:   futureSum,
+				ExtDistinct:   futureDistinct,
 				ExtMinTotal:   futureMinTotal,
 				ShouldLock:    shouldLock,
 				ShouldDiscard: false,
@@ -223,7 +238,8 @@ func (e *Engine) MatchOCR(ocr OCRInput, opts EssenceFilterOptions) (*MatchResult
 				SkillIDs:      []int{fpIDs[0], fpIDs[1], fpIDs[2]},
 				SkillsChinese: []string{ocrSkills[0], ocrSkills[1], ocrSkills[2]},
 				Weapons:       []WeaponData{},
-				ExtLevelSum:   ocrLevels[0] + ocrLevels[1] + ocrLevels[2],
+				ExtLevelSum:   futureSum,
+				ExtDistinct:   futureDistinct,
 				ExtMinTotal:   futureMinTotal,
 				ShouldLock:    shouldLock,
 				ShouldDiscard: false,
@@ -253,6 +269,29 @@ func (e *Engine) MatchOCR(ocr OCRInput, opts EssenceFilterOptions) (*MatchResult
 	}, nil
 }
 
+// SlotSkillsChinese resolves OCR skills to canonical Chinese skill names in pool slot order
+// (slot1, slot2, slot3), using the same pool-based reordering as MatchOCR.
+// Canonical IDs from a MatchResult take precedence over OCR resolution; unresolved slots are "".
+func (e *Engine) SlotSkillsChinese(skills [3]string, result *MatchResult) [3]string {
+	e.ensureSlotIndices()
+	ordered, _ := e.reorderByPoolAssignmentIfPossible(skills, [3]int{})
+	var names [3]string
+	for i := range names {
+		slot := i + 1
+		id := 0
+		if result != nil && len(result.SkillIDs) == 3 {
+			id = result.SkillIDs[i]
+		}
+		if id == 0 {
+			id, _ = e.matchSkillIDEnhanced(slot, ordered[i])
+		}
+		if id != 0 {
+			names[i] = e.skillNameByID(id, e.poolBySlot(slot))
+		}
+	}
+	return names
+}
+
 // reorderByPoolAssignmentIfPossible reorders OCR skills/levels into slot1/2/3 order
 // by inferring which slot-pool each OCR skill belongs to.
 //
@@ -300,7 +339,7 @@ func (e *Engine) reorderByPoolAssignmentIfPossible(inSkills [3]string, inLevels
 // assignSlotForOCRText returns which slot pool the given OCR skill text belongs to.
 // It prefers strict exact (full/core) matches; if those are not unique, it falls back to fuzzy matching.
 func (e *Engine) assignSlotForOCRText(text string) (int, bool) {
-	cleanedRaw := normalizeForMatch(text, e.locale)
+	cleanedRaw := e.NormalizeInput(text)
 	if cleanedRaw == "" {
 		return 0, false
 	}
@@ -3,11 +3,14 @@ package matchapi
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/resource"
+	"github.com/rs/zerolog/log"
 )
 
 const defaultLoadLocale = LocaleCN
@@ -90,24 +93,32 @@ func loadMatcherConfig(dataDir string, locale string) (MatcherConfig, error) {
 	}
 
 	var withRaw struct {
+		SchemaVersion      string            `json:"schema_version"`
 		DataVersion        string            `json:"data_version"`
 		SimilarWordMap     map[string]string `json:"similarWordMap"`
 		SuffixStopwords    json.RawMessage   `json:"suffixStopwords"`
 		SuffixStopwordsMap map[string][]string
+		SkillRegex         map[string]map[string][]string `json:"skillRegex"`
+		KeepDigitsAndLatin bool                           `json:"keepDigitsAndLatin"`
 	}
 
 	if err := json.Unmarshal(b, &withRaw); err != nil {
 		// matcher_config.json uses suffixStopwords as an object/map.
 		return MatcherConfig{}, err
 	}
+	if err := checkSchemaVersion("matcher_config.json", withRaw.SchemaVersion); err != nil {
+		return MatcherConfig{}, err
+	}
 
 	cfg := MatcherConfig{
-		DataVersion:    withRaw.DataVersion,
-		SimilarWordMap: withRaw.SimilarWordMap,
+		DataVersion:        withRaw.DataVersion,
+		SimilarWordMap:     withRaw.SimilarWordMap,
+		KeepDigitsAndLatin: withRaw.KeepDigitsAndLatin,
 	}
 	if cfg.SimilarWordMap == nil {
 		cfg.SimilarWordMap = make(map[string]string)
 	}
+	cfg.SkillRegex = compileSkillRegex(withRaw.SkillRegex)
 
 	loc := NormalizeInputLocale(locale)
 
@@ -129,6 +140,31 @@ func loadMatcherConfig(dataDir string, locale string) (MatcherConfig, error) {
 	return cfg, nil
 }
 
+// compileSkillRegex compiles matcher_config.json "skillRegex"; invalid patterns and unknown slots are logged and ignored.
+func compileSkillRegex(raw map[string]map[string][]string) map[string]map[string][]*regexp.Regexp {
+	out := make(map[string]map[string][]*regexp.Regexp, len(raw))
+	for slot, byName := range raw {
+		if slot != "slot1" && slot != "slot2" && slot != "slot3" {
+			log.Warn().Str("component", "EssenceFilterMatch").Str("slot", slot).Msg("skillRegex: unknown slot, ignored")
+			continue
+		}
+		for name, patterns := range byName {
+			for _, p := range patterns {
+				re, err := regexp.Compile(p)
+				if err != nil {
+					log.Warn().Err(err).Str("component", "EssenceFilterMatch").Str("slot", slot).Str("skill", name).Str("pattern", p).Msg("skillRegex: invalid pattern, ignored")
+					continue
+				}
+				if out[slot] == nil {
+					out[slot] = make(map[string][]*regexp.Regexp)
+				}
+				out[slot][name] = append(out[slot][name], re)
+			}
+		}
+	}
+	return out
+}
+
 func normalizeStopwordsForLocale(in []string, locale string) []string {
 	if len(in) == 0 {
 		return in
@@ -336,13 +372,46 @@ func pickLocalizedSkillSlice(m map[string][]string, locale string) []string {
 	return nil
 }
 
-func loadWeaponsOutputAndConvert(dataDir string, cfg MatcherConfig, pools SkillPools, locale string) ([]WeaponData, error) {
-	var raw WeaponsOutputRaw
-	if err := resource.ReadJsonResource(filepath.Join(dataDir, "weapons_output.json"), &raw); err != nil {
+// readWeaponsOutput reads weapons_output.json, whose top level maps weapon IDs to entries
+// plus an optional "schema_version" key checked by checkSchemaVersion.
+func readWeaponsOutput(dataDir string) (WeaponsOutputRaw, error) {
+	var top map[string]json.RawMessage
+	if err := resource.ReadJsonResource(filepath.Join(dataDir, "weapons_output.json"), &top); err != nil {
+		return nil, err
+	}
+
+	var version string
+	if v, ok := top["schema_version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return nil, fmt.Errorf("weapons_output.json: invalid schema_version: %w", err)
+		}
+		delete(top, "schema_version")
+	}
+	if err := checkSchemaVersion("weapons_output.json", version); err != nil {
 		return nil, err
 	}
 
+	raw := make(WeaponsOutputRaw, len(top))
+	for id, v := range top {
+		var entry WeaponOutputEntry
+		if err := json.Unmarshal(v, &entry); err != nil {
+			return nil, fmt.Errorf("weapons_output.json: entry %s: %w", id, err)
+		}
+		raw[id] = entry
+	}
+	return raw, nil
+}
+
+// loadWeaponsOutputAndConvert also returns every localized skill display string it saw,
+// which validateMatcherConfig uses to detect stopwords that never occur.
+func loadWeaponsOutputAndConvert(dataDir string, cfg MatcherConfig, pools SkillPools, locale string) ([]WeaponData, []string, error) {
+	raw, err := readWeaponsOutput(dataDir)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	weapons := make([]WeaponData, 0, len(raw))
+	var displays []string
 	loc := NormalizeInputLocale(locale)
 	for _, entry := range raw {
 		name := pickLocalizedString(entry.Names, loc)
@@ -351,6 +420,7 @@ func loadWeaponsOutputAndConvert(dataDir string, cfg MatcherConfig, pools SkillP
 		if len(skillStrs) != 3 {
 			continue
 		}
+		displays = append(displays, skillStrs...)
 
 		var ids [3]int
 		var canonicals [3]string
@@ -385,7 +455,7 @@ func loadWeaponsOutputAndConvert(dataDir string, cfg MatcherConfig, pools SkillP
 		})
 	}
 
-	return weapons, nil
+	return weapons, displays, nil
 }
 
 func loadLocations(dataDir string) ([]Location, error) {
@@ -454,11 +524,11 @@ func cleanDisplayToCanonical(display string, slot int, locale string, cfg Matche
 	}
 
 	matchOne := func(a, b string) bool {
-		return normalizeForMatch(a, loc) == normalizeForMatch(b, loc)
+		return normalizeForMatchOpts(a, loc, cfg.KeepDigitsAndLatin) == normalizeForMatchOpts(b, loc, cfg.KeepDigitsAndLatin)
 	}
 	prefixOK := func(c, poolName string) bool {
-		nc := normalizeForMatch(c, loc)
-		np := normalizeForMatch(poolName, loc)
+		nc := normalizeForMatchOpts(c, loc, cfg.KeepDigitsAndLatin)
+		np := normalizeForMatchOpts(poolName, loc, cfg.KeepDigitsAndLatin)
 		return np != "" && strings.HasPrefix(nc, np)
 	}
 
@@ -485,7 +555,7 @@ func cleanDisplayToCanonical(display string, slot int, locale string, cfg Matche
 			if !prefixOK(c, e.Chinese) {
 				continue
 			}
-			plen := runeCount(normalizeForMatch(e.Chinese, loc))
+			plen := runeCount(normalizeForMatchOpts(e.Chinese, loc, cfg.KeepDigitsAndLatin))
 			if plen > best.length {
 				best.chinese = e.Chinese
 				best.id = e.ID
@@ -2,6 +2,7 @@ package matchapi
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 
 	"github.com/rs/zerolog/log"
@@ -34,6 +35,13 @@ type slotIndex struct {
 	lastCharNorm  map[string][]int
 
 	entries []skillEntry
+	regexes []skillRegexEntry
+}
+
+// skillRegexEntry is one compiled skillRegex pattern bound to a pool skill id.
+type skillRegexEntry struct {
+	ID int
+	Re *regexp.Regexp
 }
 
 func (e *Engine) ensureSlotIndices() {
@@ -58,7 +66,7 @@ func (e *Engine) buildSlotIndices() {
 		}
 
 		for _, s := range pool {
-			rawFull := normalizeForMatch(s.Chinese, e.locale)
+			rawFull := e.NormalizeInput(s.Chinese)
 			rawCore := trimStopSuffix(e.cfg, rawFull, e.locale)
 
 			normFull := normalizeSimilarIfLocale(e.cfg, rawFull, e.locale)
@@ -91,6 +99,10 @@ func (e *Engine) buildSlotIndices() {
 				idx.lastCharNorm[ent.LastCharNorm] = append(idx.lastCharNorm[ent.LastCharNorm], s.ID)
 			}
 
+			for _, re := range e.cfg.SkillRegex[fmt.Sprintf("slot%d", slot)][s.Chinese] {
+				idx.regexes = append(idx.regexes, skillRegexEntry{ID: s.ID, Re: re})
+			}
+
 			idx.entries = append(idx.entries, ent)
 			idx.rawFullIndex[rawFull] = append(idx.rawFullIndex[rawFull], s.ID)
 			idx.rawCoreIndex[rawCore] = append(idx.rawCoreIndex[rawCore], s.ID)
@@ -172,20 +184,32 @@ func (e *Engine) matchEssenceSkills(ocrSkills [3]string, targets []SkillCombinat
 	}, true
 }
 
-func (e *Engine) matchFuturePromising(ocrSkills [3]string, levels [3]int, minTotal int) bool {
+// matchFuturePromising checks the future-promising rule: at least minDistinct distinct non-empty skills
+// (each with level >= 1) whose levels sum to >= minTotal. minDistinct <= 0 requires all three slots,
+// which is the original behavior. It also returns the distinct count and level sum for logging.
+func (e *Engine) matchFuturePromising(ocrSkills [3]string, levels [3]int, minTotal, minDistinct int) (ok bool, distinct, sum int) {
 	if minTotal <= 0 {
-		return false
+		return false, 0, 0
 	}
+	requireAll := minDistinct <= 0 || minDistinct >= 3
+	seen := make(map[string]bool, 3)
 	for i, s := range ocrSkills {
-		if s == "" {
-			return false
+		if s == "" || levels[i] < 1 {
+			if requireAll {
+				return false, 0, 0
+			}
+			continue
 		}
-		if levels[i] < 1 {
-			return false
+		sum += levels[i]
+		if !seen[s] {
+			seen[s] = true
+			distinct++
 		}
 	}
-	sum := levels[0] + levels[1] + levels[2]
-	return sum >= minTotal
+	if requireAll {
+		return sum >= minTotal, distinct, sum
+	}
+	return distinct >= minDistinct && sum >= minTotal, distinct, sum
 }
 
 func (e *Engine) matchSlot3Level3Practical(ocrSkills [3]string, levels [3]int, minLevel int) (match *SkillCombinationMatch, slot3Level int, ok bool) {
@@ -238,7 +262,7 @@ func (e *Engine) matchSlot3Level3Practical(ocrSkills [3]string, levels [3]int, m
 func (e *Engine) matchSkillIDEnhanced(slot int, ocrText string) (int, bool) {
 	idx := e.slotIdx[slot-1]
 
-	cleanedRaw := normalizeForMatch(ocrText, e.locale)
+	cleanedRaw := e.NormalizeInput(ocrText)
 	if cleanedRaw == "" {
 		e.traceMatch(slot, ocrText, "", "", "", "empty")
 		return 0, false
@@ -287,6 +311,12 @@ func attemptMatch(e *Engine, phase string, cleaned string, core string, idx slot
 	if ids, ok := coreIndex[core]; ok && len(ids) > 0 {
 		return ids[0], phase + ":core_exact", true
 	}
+	// 2.5) User-defined skillRegex, tried after exact matching and before fuzzy matching.
+	for _, r := range idx.regexes {
+		if r.Re.MatchString(cleaned) {
+			return r.ID, phase + ":regex", true
+		}
+	}
 	// 3) Full substring bidirectional.
 	for _, ent := range idx.entries {
 		tFull := ent.RawFull
@@ -0,0 +1,71 @@
+package matchapi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Data schema version this binary understands, as "major.minor" in the "schema_version" field of
+// matcher_config.json and weapons_output.json. A different major version changes the data shape and
+// is refused; a newer minor version only adds optional fields and is accepted with a warning.
+const (
+	SchemaMajor = 1
+	SchemaMinor = 0
+)
+
+// SchemaVersionError reports a data file whose schema major version this binary cannot read.
+type SchemaVersionError struct {
+	File      string
+	Found     string
+	Supported string
+}
+
+func (e *SchemaVersionError) Error() string {
+	return fmt.Sprintf("%s schema_version %s is not supported (supported %s)", e.File, e.Found, e.Supported)
+}
+
+// SupportedSchemaVersion returns the schema version this binary understands, e.g. "1.0".
+func SupportedSchemaVersion() string {
+	return fmt.Sprintf("%d.%d", SchemaMajor, SchemaMinor)
+}
+
+// checkSchemaVersion validates the "schema_version" of one data file.
+// An empty version means a file written before the field existed and is read as 1.0.
+func checkSchemaVersion(file, version string) error {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return nil
+	}
+	major, minor, err := parseSchemaVersion(version)
+	if err != nil {
+		return fmt.Errorf("%s: invalid schema_version %q: %w", file, version, err)
+	}
+	if major != SchemaMajor {
+		return &SchemaVersionError{File: file, Found: version, Supported: SupportedSchemaVersion()}
+	}
+	if minor > SchemaMinor {
+		log.Warn().
+			Str("component", "EssenceFilter").
+			Str("file", file).
+			Str("schema_version", version).
+			Str("supported", SupportedSchemaVersion()).
+			Msg("data schema is newer than supported, unknown fields are ignored")
+	}
+	return nil
+}
+
+func parseSchemaVersion(version string) (major, minor int, err error) {
+	majorStr, minorStr, found := strings.Cut(version, ".")
+	if major, err = strconv.Atoi(majorStr); err != nil {
+		return 0, 0, err
+	}
+	if found {
+		if minor, err = strconv.Atoi(minorStr); err != nil {
+			return 0, 0, err
+		}
+	}
+	return major, minor, nil
+}
@@ -1,5 +1,7 @@
 package matchapi
 
+import "regexp"
+
 // WeaponData represents a weapon entry after canonicalizing its three skills.
 type WeaponData struct {
 	InternalID    string   `json:"internal_id"`
@@ -61,6 +63,12 @@ type MatcherConfig struct {
 	SimilarWordMap     map[string]string   `json:"similarWordMap"`
 	SuffixStopwords    []string            `json:"-"`
 	SuffixStopwordsMap map[string][]string `json:"suffixStopwords"`
+	// SkillRegex is slot ("slot1"/"slot2"/"slot3") -> pool display name -> compiled patterns,
+	// compiled from matcher_config.json "skillRegex" at load; invalid patterns are dropped.
+	SkillRegex map[string]map[string][]*regexp.Regexp `json:"-"`
+	// KeepDigitsAndLatin keeps ASCII digits/letters when normalizing CN/TC/JP/KR text ("keepDigitsAndLatin";
+	// default false = Han/kana/hangul only, the historical behavior).
+	KeepDigitsAndLatin bool `json:"keepDigitsAndLatin"`
 }
 
 // EssenceFilterOptions is the subset of EssenceFilter attach options needed for matching.
@@ -74,6 +82,8 @@ type EssenceFilterOptions struct {
 	KeepFuturePromising     bool `json:"keep_future_promising"`
 	FuturePromisingMinTotal int  `json:"future_promising_min_total"`
 	LockFuturePromising     bool `json:"lock_future_promising"`
+	// Minimum number of distinct non-empty skills (1-3); <= 0 requires all three slots.
+	FuturePromisingMinDistinct int `json:"future_promising_min_distinct"`
 
 	// Slot3 Practical extension.
 	KeepSlot3Level3Practical bool `json:"keep_slot3_level3_practical"`
@@ -111,6 +121,7 @@ type MatchResult struct {
 
 	// Extension rule detail — only populated for the corresponding Kind.
 	ExtLevelSum int // MatchFuturePromising: sum of three slot levels
+	ExtDistinct int // MatchFuturePromising: distinct non-empty skills counted
 	ExtMinTotal int // MatchFuturePromising: required minimum
 	ExtSlot3Lv  int // MatchSlot3Level3Practical: matched slot-3 level
 	ExtMinLevel int // MatchSlot3Level3Practical: required minimum
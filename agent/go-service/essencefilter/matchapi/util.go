@@ -6,8 +6,10 @@ import (
 	"unicode/utf8"
 )
 
-func cleanChinese(text string) string {
-	return normalizeForMatch(text, LocaleCN)
+// cleanChinese keeps only Han characters; with keepAlnum it also keeps ASCII digits and letters (lower-cased),
+// for skill names whose numeral or latin suffix is the distinguishing part.
+func cleanChinese(text string, keepAlnum bool) string {
+	return normalizeForMatchOpts(text, LocaleCN, keepAlnum)
 }
 
 // NormalizeInputForMatch normalizes OCR or pool text for matching for the given locale.
@@ -17,20 +19,46 @@ func NormalizeInputForMatch(text string, locale string) string {
 }
 
 func normalizeForMatch(text string, locale string) string {
+	return normalizeForMatchOpts(text, locale, false)
+}
+
+// normalizeForMatchOpts is normalizeForMatch with MatcherConfig.KeepDigitsAndLatin applied:
+// when keepAlnum is set, the CJK normalizers also keep ASCII digits and letters (EN already keeps them).
+func normalizeForMatchOpts(text string, locale string, keepAlnum bool) string {
 	text = strings.TrimSpace(normalizePunctuation(text))
 	loc := NormalizeInputLocale(locale)
 	switch loc {
 	case LocaleEN:
 		return normalizeForMatchEN(text)
 	case LocaleJP:
-		return normalizeForMatchJP(text)
+		return keepAlnumRunes(normalizeForMatchJP, text, keepAlnum)
 	case LocaleKR:
-		return normalizeForMatchKR(text)
+		return keepAlnumRunes(normalizeForMatchKR, text, keepAlnum)
 	case LocaleTC, LocaleCN:
-		return normalizeForMatchHan(text)
+		return keepAlnumRunes(normalizeForMatchHan, text, keepAlnum)
 	default:
-		return normalizeForMatchHan(text)
+		return keepAlnumRunes(normalizeForMatchHan, text, keepAlnum)
+	}
+}
+
+// keepAlnumRunes runs a rune-filtering normalizer; with keepAlnum, ASCII digits and letters
+// survive in their original positions (letters lower-cased).
+func keepAlnumRunes(normalize func(string) string, text string, keepAlnum bool) string {
+	if !keepAlnum {
+		return normalize(text)
 	}
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r >= '0' && r <= '9', r >= 'a' && r <= 'z':
+			b.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			b.WriteRune(r + ('a' - 'A'))
+		default:
+			b.WriteString(normalize(string(r)))
+		}
+	}
+	return b.String()
 }
 
 func normalizeForMatchHan(text string) string {
@@ -0,0 +1,58 @@
+package matchapi
+
+import "testing"
+
+func TestCleanChineseMixedScript(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		keepAlnum bool
+		want      string
+	}{
+		{"han only strict", "暴击伤害提升", false, "暴击伤害提升"},
+		{"digit suffix strict drops digit", "能量回复2", false, "能量回复"},
+		{"digit suffix kept", "能量回复2", true, "能量回复2"},
+		{"latin prefix kept and lower-cased", "EX终结技", true, "ex终结技"},
+		{"latin prefix strict drops latin", "EX终结技", false, "终结技"},
+		{"digits and latin interleaved", "源石技艺Lv3强化", true, "源石技艺lv3强化"},
+		{"punctuation and spaces dropped", "攻击·提升（Ⅱ） 2", true, "攻击提升2"},
+		{"full-width digits are not ascii", "攻击提升２", true, "攻击提升"},
+		{"empty", "", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanChinese(tt.text, tt.keepAlnum); got != tt.want {
+				t.Errorf("cleanChinese(%q, %v) = %q, want %q", tt.text, tt.keepAlnum, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeForMatchOptsMixedScript(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		locale    string
+		keepAlnum bool
+		want      string
+	}{
+		{"CN strict matches normalizeForMatch", "能量回复2", LocaleCN, false, "能量回复"},
+		{"TC keeps alnum", "能量回復B2", LocaleTC, true, "能量回復b2"},
+		{"JP keeps kana and alnum", "アーツ強化2", LocaleJP, true, "アーツ強化2"},
+		{"JP strict drops digit", "アーツ強化2", LocaleJP, false, "アーツ強化"},
+		{"KR keeps hangul and alnum", "공격력 증가 2", LocaleKR, true, "공격력증가2"},
+		{"KR strict drops digit", "공격력 증가 2", LocaleKR, false, "공격력증가"},
+		{"EN ignores keepAlnum", "Attack Boost 2", LocaleEN, false, "attack boost 2"},
+		{"unknown locale falls back to Han", "攻击A1", "XX", true, "攻击a1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeForMatchOpts(tt.text, tt.locale, tt.keepAlnum); got != tt.want {
+				t.Errorf("normalizeForMatchOpts(%q, %q, %v) = %q, want %q", tt.text, tt.locale, tt.keepAlnum, got, tt.want)
+			}
+		})
+	}
+	if got, want := normalizeForMatchOpts("能量回复2", LocaleCN, false), normalizeForMatch("能量回复2", LocaleCN); got != want {
+		t.Errorf("strict normalizeForMatchOpts = %q, normalizeForMatch = %q; want equal", got, want)
+	}
+}
@@ -0,0 +1,80 @@
+package matchapi
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Config warning kinds reported by Engine.ConfigWarnings.
+const (
+	ConfigWarningSimilarWord = "similar_word"
+	ConfigWarningStopword    = "stopword"
+)
+
+// ConfigWarning describes one matcher_config.json entry that can never take effect.
+// For similar_word, Key is the OCR text and Value the (missing) replacement; for stopword, Key is the stopword.
+type ConfigWarning struct {
+	Kind  string
+	Key   string
+	Value string
+}
+
+// validateMatcherConfig flags similarWordMap targets that occur in no skill pool name or skill text
+// (CN only, where the map is applied) and locale stopwords that never occur in any skill text.
+func validateMatcherConfig(cfg MatcherConfig, pools SkillPools, displays []string, locale string) []ConfigWarning {
+	loc := NormalizeInputLocale(locale)
+	texts := make([]string, 0, len(displays)+len(pools.Slot1)+len(pools.Slot2)+len(pools.Slot3))
+	for _, d := range displays {
+		texts = append(texts, strings.ToLower(d))
+	}
+	for _, pool := range [][]SkillPool{pools.Slot1, pools.Slot2, pools.Slot3} {
+		for _, e := range pool {
+			texts = append(texts, strings.ToLower(e.Chinese))
+		}
+	}
+	occurs := func(s string) bool {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" {
+			return true
+		}
+		for _, t := range texts {
+			if strings.Contains(t, s) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var out []ConfigWarning
+	if loc == LocaleCN {
+		for from, to := range cfg.SimilarWordMap {
+			if !occurs(to) {
+				out = append(out, ConfigWarning{Kind: ConfigWarningSimilarWord, Key: from, Value: to})
+			}
+		}
+	}
+	stopwords := cfg.SuffixStopwords
+	if w, ok := cfg.SuffixStopwordsMap[loc]; ok && len(w) > 0 {
+		stopwords = w
+	}
+	for _, w := range stopwords {
+		if !occurs(w) {
+			out = append(out, ConfigWarning{Kind: ConfigWarningStopword, Key: strings.TrimSpace(w)})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		return out[i].Key < out[j].Key
+	})
+	for _, w := range out {
+		log.Warn().Str("component", "EssenceFilterMatch").Str("locale", loc).
+			Str("kind", w.Kind).Str("key", w.Key).Str("value", w.Value).
+			Msg("matcher_config entry never takes effect")
+	}
+	return out
+}
@@ -3,22 +3,120 @@ package essencefilter
 import (
 	"strings"
 
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/screenshot"
 	maa "github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
 )
 
 // firstOCRText returns the first non-empty OCR string from Best, then Filtered, then All.
 func firstOCRText(d *maa.RecognitionDetail) (string, bool) {
+	text, _, ok := firstOCRResult(d)
+	return text, ok
+}
+
+// firstOCRResult is firstOCRText plus the OCR score of the chosen result.
+func firstOCRResult(d *maa.RecognitionDetail) (string, float64, bool) {
 	if d == nil || d.Results == nil {
-		return "", false
+		return "", 0, false
 	}
 	for _, results := range [][]*maa.RecognitionResult{{d.Results.Best}, d.Results.Filtered, d.Results.All} {
 		if len(results) > 0 {
 			if ocrResult, ok := results[0].AsOCR(); ok {
 				if t := strings.TrimSpace(ocrResult.Text); t != "" {
-					return t, true
+					return t, ocrResult.Score, true
 				}
 			}
 		}
 	}
-	return "", false
+	return "", 0, false
+}
+
+// rejectedOCR is an OCR candidate skipped by ocrResultAtLeast for scoring below the threshold.
+type rejectedOCR struct {
+	Text  string  `json:"text"`
+	Score float64 `json:"score"`
+}
+
+// ocrResultAtLeast walks Best, then every Filtered, then every All result and returns the first
+// non-empty text whose score >= minScore, along with the candidates rejected on the way.
+// minScore <= 0 behaves like firstOCRResult.
+func ocrResultAtLeast(d *maa.RecognitionDetail, minScore float64) (string, float64, []rejectedOCR, bool) {
+	if d == nil || d.Results == nil {
+		return "", 0, nil, false
+	}
+	var rejected []rejectedOCR
+	for _, results := range [][]*maa.RecognitionResult{{d.Results.Best}, d.Results.Filtered, d.Results.All} {
+		for _, r := range results {
+			if r == nil {
+				continue
+			}
+			ocrResult, ok := r.AsOCR()
+			if !ok {
+				continue
+			}
+			t := strings.TrimSpace(ocrResult.Text)
+			if t == "" {
+				continue
+			}
+			if ocrResult.Score >= minScore {
+				return t, ocrResult.Score, rejected, true
+			}
+			rejected = append(rejected, rejectedOCR{Text: t, Score: ocrResult.Score})
+		}
+	}
+	return "", 0, rejected, false
+}
+
+// ocrLowConfidenceRetries 读数置信度过低时重新截图识别的次数；仍不达标时转到 OCR 回退节点跳过当前物品
+const ocrLowConfidenceRetries = 2
+
+// ocrFallbackNode 低置信度重试用尽后的默认去向：跳过当前物品，继续下一格。
+// 节点原本的 next 中带有 *OCRFallback 时（如战后流程）优先使用该节点。
+const ocrFallbackNode = "EssenceFilterCheckItemOCRFallback"
+
+// rerunOCR 重新截图并对当前节点再识别一次，失败时返回 nil
+func rerunOCR(ctx *maa.Context, arg *maa.CustomActionArg) *maa.RecognitionDetail {
+	img, err := screenshot.FreshFrame(ctx)
+	if err != nil {
+		log.Warn().Err(err).Str("component", "EssenceFilter").Str("node", arg.CurrentTaskName).Msg("OCR retry: get screenshot failed")
+		return nil
+	}
+	detail, err := ctx.RunRecognition(arg.CurrentTaskName, img)
+	if err != nil {
+		log.Warn().Err(err).Str("component", "EssenceFilter").Str("node", arg.CurrentTaskName).Msg("OCR retry: recognition failed")
+		return nil
+	}
+	return detail
+}
+
+// routeOCRFallback 将当前节点的 next 改为 OCR 回退节点并记下原本的 next。
+// OverrideNext 在本次任务内持续生效，读数恢复正常时需由 restoreOCRNext 改回。
+func routeOCRFallback(ctx *maa.Context, arg *maa.CustomActionArg, st *RunState) {
+	name := arg.CurrentTaskName
+	if _, saved := st.OCRDefaultNext[name]; !saved {
+		node, err := ctx.GetNode(name)
+		if err != nil || node == nil {
+			log.Warn().Err(err).Str("component", "EssenceFilter").Str("node", name).Msg("get node failed, next will not be restored")
+		} else {
+			st.OCRDefaultNext[name] = node.Next
+		}
+	}
+	fallback := ocrFallbackNode
+	for _, item := range st.OCRDefaultNext[name] {
+		if strings.HasSuffix(item.Name, "OCRFallback") {
+			fallback = item.Name
+		}
+	}
+	ctx.OverrideNext(name, []maa.NextItem{{Name: fallback}})
+}
+
+// restoreOCRNext 恢复此前被 routeOCRFallback 改写的 next
+func restoreOCRNext(ctx *maa.Context, arg *maa.CustomActionArg, st *RunState) {
+	name := arg.CurrentTaskName
+	next, saved := st.OCRDefaultNext[name]
+	if !saved {
+		return
+	}
+	ctx.OverrideNext(name, next)
+	delete(st.OCRDefaultNext, name)
 }
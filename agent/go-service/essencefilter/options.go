@@ -18,16 +18,17 @@ func matchOptsFromPipeline(opts *EssenceFilterOptions) matchapi.EssenceFilterOpt
 		return matchapi.EssenceFilterOptions{}
 	}
 	return matchapi.EssenceFilterOptions{
-		Rarity6Weapon:            opts.Rarity6Weapon,
-		Rarity5Weapon:            opts.Rarity5Weapon,
-		Rarity4Weapon:            opts.Rarity4Weapon,
-		KeepFuturePromising:      opts.KeepFuturePromising,
-		FuturePromisingMinTotal:  opts.FuturePromisingMinTotal,
-		LockFuturePromising:      opts.LockFuturePromising,
-		KeepSlot3Level3Practical: opts.KeepSlot3Level3Practical,
-		Slot3MinLevel:            opts.Slot3MinLevel,
-		LockSlot3Practical:       opts.LockSlot3Practical,
-		DiscardUnmatched:         opts.DiscardUnmatched,
+		Rarity6Weapon:              opts.Rarity6Weapon,
+		Rarity5Weapon:              opts.Rarity5Weapon,
+		Rarity4Weapon:              opts.Rarity4Weapon,
+		KeepFuturePromising:        opts.KeepFuturePromising,
+		FuturePromisingMinTotal:    opts.FuturePromisingMinTotal,
+		FuturePromisingMinDistinct: opts.FuturePromisingMinDistinct,
+		LockFuturePromising:        opts.LockFuturePromising,
+		KeepSlot3Level3Practical:   opts.KeepSlot3Level3Practical,
+		Slot3MinLevel:              opts.Slot3MinLevel,
+		LockSlot3Practical:         opts.LockSlot3Practical,
+		DiscardUnmatched:           opts.DiscardUnmatched,
 	}
 }
 
@@ -74,3 +75,17 @@ func essenceListToString(EssenceTypes []EssenceMeta) string {
 	}
 	return strings.Join(names, i18n.Separator())
 }
+
+// logColorRanges 记录本次运行实际使用的基质颜色范围（已应用 ColorTolerance）
+func logColorRanges(essenceTypes []EssenceMeta, boundary ColorRange, tolerance int) {
+	for _, et := range essenceTypes {
+		log.Info().Str("component", "EssenceFilter").Str("step", "ColorRange").
+			Str("essence", et.Key).Int("tolerance", tolerance).
+			Ints("lower", et.Range.Lower[:]).Ints("upper", et.Range.Upper[:]).
+			Msg("effective essence color range")
+	}
+	log.Info().Str("component", "EssenceFilter").Str("step", "ColorRange").
+		Str("essence", "pure_boundary").Int("tolerance", tolerance).
+		Ints("lower", boundary.Lower[:]).Ints("upper", boundary.Upper[:]).
+		Msg("effective essence color range")
+}
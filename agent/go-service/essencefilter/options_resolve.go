@@ -15,18 +15,35 @@ type EssenceFilterOptionsPatch struct {
 	FlawlessEssence *bool `json:"flawless_essence"`
 	PureEssence     *bool `json:"pure_essence"`
 
-	KeepFuturePromising     *bool `json:"keep_future_promising"`
-	FuturePromisingMinTotal *int  `json:"future_promising_min_total"`
-	LockFuturePromising     *bool `json:"lock_future_promising"`
+	KeepFuturePromising        *bool `json:"keep_future_promising"`
+	FuturePromisingMinTotal    *int  `json:"future_promising_min_total"`
+	FuturePromisingMinDistinct *int  `json:"future_promising_min_distinct"`
+	LockFuturePromising        *bool `json:"lock_future_promising"`
 
 	KeepSlot3Level3Practical *bool `json:"keep_slot3_level3_practical"`
 	Slot3MinLevel            *int  `json:"slot3_min_level"`
 	LockSlot3Practical       *bool `json:"lock_slot3_practical"`
 
-	DiscardUnmatched       *bool `json:"discard_unmatched"`
-	ExportCalculatorScript *bool `json:"export_calculator_script"`
-	SkipThumbLock          *bool `json:"skip_thumb_lock"`
-	SkipThumbDiscard       *bool `json:"skip_thumb_discard"`
+	DiscardUnmatched         *bool            `json:"discard_unmatched"`
+	DryRun                   *bool            `json:"dry_run"`
+	Resume                   *bool            `json:"resume"`
+	ExportCalculatorScript   *bool            `json:"export_calculator_script"`
+	SkipThumbLock            *bool            `json:"skip_thumb_lock"`
+	SkipThumbDiscard         *bool            `json:"skip_thumb_discard"`
+	KeepDuplicatesOnly       *bool            `json:"keep_duplicates_only"`
+	DuplicateMinCount        *int             `json:"duplicate_min_count"`
+	PerTypeLockLimit         map[string]int   `json:"per_type_lock_limit"`
+	SlotWhitelist            map[int][]string `json:"slot_whitelist"`
+	SlotBlacklist            map[int][]string `json:"slot_blacklist"`
+	RequireMatchesBeforeLock *int             `json:"require_matches_before_lock"`
+	FinalScanMaxPasses       *int             `json:"final_scan_max_passes"`
+	ItemsPerRow              *int             `json:"items_per_row"`
+	MaxRunMs                 *int             `json:"max_run_ms"`
+	MinOcrConfidence         *float64         `json:"min_ocr_confidence"`
+	ColorTolerance           *int             `json:"color_tolerance"`
+	ExportPresetPath         *string          `json:"export_preset_path"`
+	SummaryOutputDir         *string          `json:"summary_output_dir"`
+	Theme                    *ThemeOptions    `json:"theme"`
 	// Legacy: when both SkipThumbLock and SkipThumbDiscard are absent in the same patch, maps to both.
 	SkipLockedRow *bool   `json:"skip_locked_row"`
 	InputLanguage *string `json:"input_language"`
@@ -49,7 +66,11 @@ func defaultEssenceFilterOptions() EssenceFilterOptions {
 		ExportCalculatorScript:   false,
 		SkipThumbLock:            true,
 		SkipThumbDiscard:         true,
+		KeepDuplicatesOnly:       false,
+		DuplicateMinCount:        2,
+		FinalScanMaxPasses:       1,
 		InputLanguage:            "CN",
+		Theme:                    defaultTheme,
 	}
 }
 
@@ -143,6 +164,9 @@ func applyOptionsPatch(dst *EssenceFilterOptions, patch EssenceFilterOptionsPatc
 	if patch.FuturePromisingMinTotal != nil {
 		dst.FuturePromisingMinTotal = *patch.FuturePromisingMinTotal
 	}
+	if patch.FuturePromisingMinDistinct != nil {
+		dst.FuturePromisingMinDistinct = *patch.FuturePromisingMinDistinct
+	}
 	if patch.LockFuturePromising != nil {
 		dst.LockFuturePromising = *patch.LockFuturePromising
 	}
@@ -160,6 +184,12 @@ func applyOptionsPatch(dst *EssenceFilterOptions, patch EssenceFilterOptionsPatc
 	if patch.DiscardUnmatched != nil {
 		dst.DiscardUnmatched = *patch.DiscardUnmatched
 	}
+	if patch.DryRun != nil {
+		dst.DryRun = *patch.DryRun
+	}
+	if patch.Resume != nil {
+		dst.Resume = *patch.Resume
+	}
 	if patch.ExportCalculatorScript != nil {
 		dst.ExportCalculatorScript = *patch.ExportCalculatorScript
 	}
@@ -169,6 +199,49 @@ func applyOptionsPatch(dst *EssenceFilterOptions, patch EssenceFilterOptionsPatc
 	if patch.SkipThumbDiscard != nil {
 		dst.SkipThumbDiscard = *patch.SkipThumbDiscard
 	}
+	if patch.KeepDuplicatesOnly != nil {
+		dst.KeepDuplicatesOnly = *patch.KeepDuplicatesOnly
+	}
+	if patch.DuplicateMinCount != nil {
+		dst.DuplicateMinCount = *patch.DuplicateMinCount
+	}
+	if patch.RequireMatchesBeforeLock != nil {
+		dst.RequireMatchesBeforeLock = *patch.RequireMatchesBeforeLock
+	}
+	if patch.PerTypeLockLimit != nil {
+		dst.PerTypeLockLimit = patch.PerTypeLockLimit
+	}
+	if patch.SlotWhitelist != nil {
+		dst.SlotWhitelist = patch.SlotWhitelist
+	}
+	if patch.SlotBlacklist != nil {
+		dst.SlotBlacklist = patch.SlotBlacklist
+	}
+	if patch.Theme != nil {
+		mergeTheme(&dst.Theme, *patch.Theme)
+	}
+	if patch.ExportPresetPath != nil {
+		dst.ExportPresetPath = strings.TrimSpace(*patch.ExportPresetPath)
+	}
+	if patch.SummaryOutputDir != nil {
+		dir := strings.TrimSpace(*patch.SummaryOutputDir)
+		dst.SummaryOutputDir = &dir
+	}
+	if patch.MinOcrConfidence != nil {
+		dst.MinOcrConfidence = *patch.MinOcrConfidence
+	}
+	if patch.MaxRunMs != nil {
+		dst.MaxRunMs = *patch.MaxRunMs
+	}
+	if patch.ColorTolerance != nil {
+		dst.ColorTolerance = *patch.ColorTolerance
+	}
+	if patch.FinalScanMaxPasses != nil {
+		dst.FinalScanMaxPasses = *patch.FinalScanMaxPasses
+	}
+	if patch.ItemsPerRow != nil {
+		dst.ItemsPerRow = *patch.ItemsPerRow
+	}
 	if patch.SkipLockedRow != nil && patch.SkipThumbLock == nil && patch.SkipThumbDiscard == nil {
 		dst.SkipThumbLock = *patch.SkipLockedRow
 		dst.SkipThumbDiscard = *patch.SkipLockedRow
@@ -0,0 +1,85 @@
+package essencefilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+)
+
+// 仓库中尚无读取自定义组合的功能，预设格式在此定义：
+// 每条组合以三槽技能池 ID 为准（skill_ids），技能名与武器仅供人工查看。
+type exportedPreset struct {
+	DataVersion   string          `json:"data_version"`
+	InputLanguage string          `json:"input_language"`
+	Combos        []exportedCombo `json:"combos"`
+}
+
+type exportedCombo struct {
+	SkillIDs []int    `json:"skill_ids"`
+	Skills   []string `json:"skills"`
+	Weapons  []string `json:"weapons"`
+	Count    int      `json:"count"`
+}
+
+// buildExportedPreset 由 MatchedCombinationSummary 生成预设：按组合 key 去重，组合按 key、武器按 ID 排序，保证输出稳定
+func buildExportedPreset(st *RunState) exportedPreset {
+	preset := exportedPreset{InputLanguage: st.InputLanguage, Combos: []exportedCombo{}}
+	if st.MatchEngine != nil {
+		preset.DataVersion = st.MatchEngine.DataVersion()
+	}
+
+	keys := make([]string, 0, len(st.MatchedCombinationSummary))
+	for k := range st.MatchedCombinationSummary {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := st.MatchedCombinationSummary[k]
+		seen := make(map[string]struct{}, len(s.Weapons))
+		weapons := make([]string, 0, len(s.Weapons))
+		for _, w := range s.Weapons {
+			if w.InternalID == "" {
+				continue
+			}
+			if _, ok := seen[w.InternalID]; ok {
+				continue
+			}
+			seen[w.InternalID] = struct{}{}
+			weapons = append(weapons, w.InternalID)
+		}
+		sort.Strings(weapons)
+		preset.Combos = append(preset.Combos, exportedCombo{
+			SkillIDs: append([]int(nil), s.SkillIDs...),
+			Skills:   append([]string(nil), s.SkillsChinese...),
+			Weapons:  weapons,
+			Count:    s.Count,
+		})
+	}
+	return preset
+}
+
+// exportMatchedPreset 将本次命中的组合写入 ExportPresetPath；路径为空时不导出
+func exportMatchedPreset(st *RunState) (string, int, error) {
+	path := st.PipelineOpts.ExportPresetPath
+	if path == "" {
+		return "", 0, nil
+	}
+	preset := buildExportedPreset(st)
+	data, err := json.MarshalIndent(preset, "", "    ")
+	if err != nil {
+		return "", 0, fmt.Errorf("marshal preset: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", 0, fmt.Errorf("create preset dir: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", 0, fmt.Errorf("write preset: %w", err)
+	}
+	log.Info().Str("component", "EssenceFilter").Str("path", path).Int("combos", len(preset.Combos)).Msg("matched preset exported")
+	return path, len(preset.Combos), nil
+}
@@ -0,0 +1,80 @@
+package essencefilter
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/essencefilter/matchapi"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/i18n"
+	maa "github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// queryWeaponMaxResults 单次查询最多输出的武器条数，避免片段过短时刷屏
+const queryWeaponMaxResults = 30
+
+// EssenceFilterQueryWeaponAction - 只读查询：按名称片段搜索武器，输出稀有度与三槽技能，便于配置预设
+type EssenceFilterQueryWeaponAction struct{}
+
+func (a *EssenceFilterQueryWeaponAction) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
+	var params struct {
+		Name          string `json:"name"`
+		InputLanguage string `json:"input_language"`
+	}
+	if arg.CustomActionParam != "" {
+		_ = json.Unmarshal([]byte(arg.CustomActionParam), &params)
+	}
+	fragment := strings.TrimSpace(params.Name)
+	if fragment == "" {
+		log.Error().Str("component", "EssenceFilter").Str("action", "QueryWeapon").Msg("empty name")
+		reportFocusByKey(ctx, nil, "query.empty_name")
+		return false
+	}
+
+	engine, err := queryEngine(params.InputLanguage)
+	if err != nil {
+		log.Error().Err(err).Str("component", "EssenceFilter").Str("action", "QueryWeapon").Msg("load match engine failed")
+		reportLoadEngineError(ctx, err)
+		return false
+	}
+
+	matches := searchWeapons(engine.Weapons(), fragment)
+	log.Info().Str("component", "EssenceFilter").Str("action", "QueryWeapon").Str("name", fragment).Int("matches", len(matches)).Msg("query done")
+	if len(matches) == 0 {
+		reportSimpleByKey(ctx, nil, "query.no_result", fragment)
+		return true
+	}
+
+	reportSimpleByKey(ctx, nil, "query.header", fragment, len(matches))
+	if len(matches) > queryWeaponMaxResults {
+		matches = matches[:queryWeaponMaxResults]
+		reportSimpleByKey(ctx, nil, "query.truncated", queryWeaponMaxResults)
+	}
+	for _, w := range matches {
+		LogMXUSimpleHTMLWithColor(ctx, i18n.T("essencefilter.query.weapon",
+			escapeHTML(w.ChineseName), w.Rarity, escapeHTML(strings.Join(w.SkillsChinese, " / "))), getColorForRarity(w.Rarity))
+	}
+	return true
+}
+
+// queryEngine 优先复用正在运行的筛选引擎，否则按语言临时加载一份
+func queryEngine(language string) (*matchapi.Engine, error) {
+	if st := getRunState(); st != nil && st.MatchEngine != nil {
+		return st.MatchEngine, nil
+	}
+	return matchapi.NewEngineFromDirWithLocale(dataDirFromResourceBase(), matchapi.NormalizeInputLocale(language))
+}
+
+// searchWeapons 按 ChineseName / InternalID 子串（不区分大小写）查找，结果按稀有度降序
+func searchWeapons(weapons []matchapi.WeaponData, fragment string) []matchapi.WeaponData {
+	needle := strings.ToLower(fragment)
+	var out []matchapi.WeaponData
+	for _, w := range weapons {
+		if strings.Contains(strings.ToLower(w.ChineseName), needle) || strings.Contains(strings.ToLower(w.InternalID), needle) {
+			out = append(out, w)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Rarity > out[j].Rarity })
+	return out
+}
@@ -43,7 +43,7 @@ func (r *EssenceFilterAfterBattleNthRecognition) Run(ctx *maa.Context, arg *maa.
 	}
 
 	if st.RowIndex < len(st.RowBoxes) {
-		box := st.RowBoxes[st.RowIndex]
+		box := st.RowBoxes[st.RowIndex].Box
 		st.RowIndex++
 		return &maa.CustomRecognitionResult{
 			Box:    maa.Rect{box[0], box[1], box[2], box[3]},
@@ -63,14 +63,14 @@ func (r *EssenceFilterAfterBattleNthRecognition) Run(ctx *maa.Context, arg *maa.
 			continue
 		}
 		b := tm.Box
-		st.RowBoxes = append(st.RowBoxes, [4]int{b.X(), b.Y(), b.Width(), b.Height()})
+		st.RowBoxes = append(st.RowBoxes, rowBox{Box: [4]int{b.X(), b.Y(), b.Width(), b.Height()}, Slot: -1})
 	}
 
 	if st.RowIndex >= len(st.RowBoxes) {
 		return nil, false
 	}
 
-	box := st.RowBoxes[st.RowIndex]
+	box := st.RowBoxes[st.RowIndex].Box
 	st.RowIndex++
 	return &maa.CustomRecognitionResult{
 		Box:    maa.Rect{box[0], box[1], box[2], box[3]},
@@ -5,8 +5,6 @@ import (
 )
 
 var (
-	_ maa.ResourceEventSink = &resourcePathSink{}
-
 	_ maa.CustomActionRunner = &EssenceFilterInitAction{}
 	_ maa.CustomActionRunner = &EssenceFilterCheckItemAction{}
 	_ maa.CustomActionRunner = &EssenceFilterCheckItemLevelAction{}
@@ -17,10 +15,10 @@ var (
 	_ maa.CustomActionRunner = &EssenceFilterSwipeCalibrateAction{}
 	_ maa.CustomActionRunner = &EssenceFilterTraceAction{}
 	_ maa.CustomActionRunner = &OCREssenceInventoryNumberAction{}
+	_ maa.CustomActionRunner = &EssenceFilterQueryWeaponAction{}
 )
 
 func Register() {
-	maa.AgentServerAddResourceSink(&resourcePathSink{})
 	maa.AgentServerRegisterCustomAction("EssenceFilterInitAction", &EssenceFilterInitAction{})
 	maa.AgentServerRegisterCustomAction("EssenceFilterCheckItemAction", &EssenceFilterCheckItemAction{})
 	maa.AgentServerRegisterCustomAction("EssenceFilterCheckItemLevelAction", &EssenceFilterCheckItemLevelAction{})
@@ -31,6 +29,7 @@ func Register() {
 	maa.AgentServerRegisterCustomAction("EssenceFilterSwipeCalibrateAction", &EssenceFilterSwipeCalibrateAction{})
 	maa.AgentServerRegisterCustomAction("EssenceFilterTraceAction", &EssenceFilterTraceAction{})
 	maa.AgentServerRegisterCustomAction("OCREssenceInventoryNumberAction", &OCREssenceInventoryNumberAction{})
+	maa.AgentServerRegisterCustomAction("EssenceFilterQueryWeapon", &EssenceFilterQueryWeaponAction{})
 
 	//战斗后识别版本
 	maa.AgentServerRegisterCustomAction("EssenceFilterAfterBattleSkillDecisionAction", &EssenceFilterAfterBattleSkillDecisionAction{})
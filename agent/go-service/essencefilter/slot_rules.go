@@ -0,0 +1,73 @@
+package essencefilter
+
+import (
+	"strings"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/essencefilter/matchapi"
+	maa "github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// 槽位规则：SlotBlacklist 任一槽命中即跳过；SlotWhitelist 对配置了名单的槽要求该槽技能在名单内。
+// 名单填写技能中文名，与各技能池槽位解析出的规范中文名比较，与输入语言和屏幕上的技能顺序无关。
+const (
+	slotRuleBlacklist = "blacklist"
+	slotRuleWhitelist = "whitelist"
+)
+
+// hasSlotRules 是否配置了任一槽位规则
+func hasSlotRules(st *RunState) bool {
+	return len(st.PipelineOpts.SlotBlacklist) > 0 || len(st.PipelineOpts.SlotWhitelist) > 0
+}
+
+// slotRuleSkills 按技能池槽位返回当前物品的规范技能中文名；未配置槽位规则时不解析
+func slotRuleSkills(st *RunState, engine *matchapi.Engine, ocr matchapi.OCRInput, result *matchapi.MatchResult) [3]string {
+	if engine == nil || !hasSlotRules(st) {
+		return [3]string{}
+	}
+	return engine.SlotSkillsChinese(ocr.Skills, result)
+}
+
+// slotRuleBlocks 检查当前物品是否被槽位规则拦截，返回触发的规则、槽位与该槽技能；未拦截时 rule 为空。
+// skills 为按槽位排列的规范中文名，无法解析的槽为空：黑名单不会命中，白名单视为不在名单内。
+func slotRuleBlocks(st *RunState, skills [3]string) (rule string, slot int, skill string) {
+	opts := &st.PipelineOpts
+	if !hasSlotRules(st) {
+		return "", 0, ""
+	}
+	for i, s := range skills {
+		if s != "" && slotSkillListed(opts.SlotBlacklist[i+1], s) {
+			return slotRuleBlacklist, i + 1, s
+		}
+	}
+	for i, s := range skills {
+		if names := opts.SlotWhitelist[i+1]; len(names) > 0 && !slotSkillListed(names, s) {
+			return slotRuleWhitelist, i + 1, s
+		}
+	}
+	return "", 0, ""
+}
+
+func slotSkillListed(names []string, skill string) bool {
+	if skill == "" {
+		return false
+	}
+	for _, name := range names {
+		if strings.TrimSpace(name) == skill {
+			return true
+		}
+	}
+	return false
+}
+
+// applySlotRules 在 lockCurrentItem 中调用：被槽位规则拦截时记录原因并返回 true，当前物品改为跳过
+func applySlotRules(ctx *maa.Context, st *RunState, skills [3]string) bool {
+	rule, slot, skill := slotRuleBlocks(st, skills)
+	if rule == "" {
+		return false
+	}
+	st.SlotRuleSkipCount++
+	log.Info().Str("component", "EssenceFilter").Str("rule", rule).Int("slot", slot).Str("skill", skill).Msg("slot rule: skip item")
+	reportSimpleByKey(ctx, st, "focus.slot_rule."+rule, slot, skill)
+	return true
+}
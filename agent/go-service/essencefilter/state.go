@@ -1,9 +1,12 @@
 package essencefilter
 
 import (
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/essencefilter/matchapi"
+	maa "github.com/MaaXYZ/maa-framework-go/v4"
 )
 
 var (
@@ -11,14 +14,57 @@ var (
 	currentRunMu sync.RWMutex
 )
 
+// rowBox 是待处理的一个格子：识别框，以及 RowCollect 颜色匹配得到的基质类型（EssenceMeta.Key；战利品分支为空）
+type rowBox struct {
+	Box         [4]int
+	EssenceType string
+	Slot        int // 在库存中的序号（从 0 开始），见 inventorySlots；-1 表示无法推算
+}
+
+// sortRowBoxes 按阅读顺序（先上后下、先左后右）排序，类型等信息随格子一起移动
+func sortRowBoxes(boxes []rowBox) {
+	sort.SliceStable(boxes, func(i, j int) bool {
+		bi, bj := boxes[i].Box, boxes[j].Box
+		if bi[1] == bj[1] {
+			return bi[0] < bj[0]
+		}
+		return bi[1] < bj[1]
+	})
+}
+
 // RunState holds all runtime state for a single EssenceFilter run.
 // Init allocates/resets it; Finish clears it. Actions access via getRunState().
 type RunState struct {
+	// StartedAt 本次运行开始时间（Init 写入），用于 MaxRunMs 时间预算
+	StartedAt time.Time
+	// TimeBudgetReached 因 MaxRunMs 提前结束
+	TimeBudgetReached bool
+
 	// Stats
 	VisitedCount            int
 	MatchedCount            int
 	ExtFuturePromisingCount int
 	ExtSlot3PracticalCount  int
+	// DedupeCount 因指纹重复而跳过重复锁定的次数（尾扫与行扫重叠时同一物品可能被访问两次）
+	DedupeCount int
+	// WithheldRevisitCount 再次访问到暂扣（未锁定）物品而跳过的次数，与 DedupeCount 分开统计
+	WithheldRevisitCount int
+	// OCRDefaultNext 因读数置信度过低被改写 next 的 CheckItem 节点及其原本的 next，见 routeOCRFallback
+	OCRDefaultNext map[string][]maa.NextItem
+	// LockedFingerprints 本次运行已锁定物品的指纹，见 itemFingerprint
+	LockedFingerprints map[string]struct{}
+	// WithheldItems 仅保留重复组合模式下，按组合 key 记录尚未锁定的单件，见 duplicates.go
+	WithheldItems map[string][]withheldItem
+	// RetroLockedCount 组合达到重复阈值后回头补锁成功的件数
+	RetroLockedCount int
+	// WarmupMatches / WarmupHeld 锁定预热：RequireMatchesBeforeLock 生效前的可锁定命中数与暂扣物品，见 warmup.go
+	WarmupMatches int
+	WarmupHeld    []withheldItem
+	// TypeLockedCount / TypeLimitSkipCount 按基质类型（EssenceMeta.Key）统计的锁定数与因上限跳过数
+	TypeLockedCount    map[string]int
+	TypeLimitSkipCount map[string]int
+	// SlotRuleSkipCount 因 slot_whitelist / slot_blacklist 跳过的可锁定物品数
+	SlotRuleSkipCount int
 
 	// Target combinations and match summary
 	MatchEngine *matchapi.Engine
@@ -31,24 +77,43 @@ type RunState struct {
 	MaxItemsPerRow      int
 	TotalCount          int // OCR 得到的库存总数，0 表示未知；用于计算剩余是否 <= 45 以决定是否尾扫
 	FirstRowSwipeDone   bool
-	FinalLargeScanUsed  bool
-	InFinalScan         bool // 当前 RowBoxes 来自 EssenceDetectFinal（尾扫大 ROI）
-	PendingFinalScan    bool // 剩余 ≤ 45 时先补一次 swipe，下次进 RowNextItem 再进尾扫
+	FinalScanCount      int                 // EssenceDetectFinal 收集次数；第 1 次只用于预热并重新检测，之后每次为一轮处理
+	FinalScanSeen       map[[2]int]struct{} // 尾扫中已收集过的格子位置（按 fingerprintGrid 量化）
+	InFinalScan         bool                // 当前 RowBoxes 来自 EssenceDetectFinal（尾扫大 ROI）
+	PendingFinalScan    bool                // 剩余 ≤ 45 时先补一次 swipe，下次进 RowNextItem 再进尾扫
 	SwipeCalibrateRetry int
+	// LegacyColorMatchCost 首个格子上逐范围 EssenceColorMatch 的实测耗时，用于估算单次颜色分类节省的时间（见 color_classify.go）
+	LegacyColorMatchCost time.Duration
+	// ResumeCheckpoint 开启 resume 时 Init 读到的检查点，等库存总数 OCR 后再校验/应用；ResumeSkipRows 为尚需直接滑过的行数
+	ResumeCheckpoint *traversalCheckpoint
+	ResumeSkipRows   int
 
 	// Current item's three skills cache
 	CurrentSkills      [3]string
 	CurrentSkillLevels [3]int
 
 	// Row processing
-	RowBoxes [][4]int
+	RowBoxes []rowBox
 	RowIndex int
+	// CurrentMaxRarity 当前物品命中武器中的最高稀有度（无关联武器为 0），用于按稀有度统计锁定数
+	CurrentMaxRarity int
+	// RarityLockedCount 按 CurrentMaxRarity 统计的锁定数
+	RarityLockedCount map[int]int
+	// CurrentEssenceType 当前物品的基质类型，RowNextItem 点击时写入；战利品分支为空
+	CurrentEssenceType string
+	// CurrentComboKey / CurrentCombo 当前物品可锁定时的组合 key 与摘要模板（Count 为 0），锁定成功后才计入 MatchedCombinationSummary
+	CurrentComboKey string
+	CurrentCombo    *matchapi.SkillCombinationSummary
+	// CombinationSeenCount 按组合 key 统计的精准命中次数（含暂扣未锁定的），用于仅保留重复组合的阈值判断
+	CombinationSeenCount map[string]int
 
 	// 记录本行扫描到的真实物理格子总数
 	PhysicalItemCount int
 
 	// Essence types selected for this run (e.g. Flawless, Pure)
 	EssenceTypes []EssenceMeta
+	// BoundaryRange 仅无暇模式下探测高纯边界用的颜色范围（已按 ColorTolerance 放宽）
+	BoundaryRange ColorRange
 	// EssenceMode derived from selection: flawless_only / pure_only / both
 	EssenceMode EssenceMode
 	// EncounteredTierBoundary is set when flawless-only mode encounters pure (inventory scan should stop)
@@ -63,25 +128,48 @@ type RunState struct {
 
 // Reset zeroes all fields for a new run. Call from Init after loading options.
 func (s *RunState) Reset() {
+	s.StartedAt = time.Now()
+	s.TimeBudgetReached = false
 	s.VisitedCount = 0
 	s.MatchedCount = 0
 	s.ExtFuturePromisingCount = 0
 	s.ExtSlot3PracticalCount = 0
+	s.DedupeCount = 0
+	s.WithheldRevisitCount = 0
+	s.OCRDefaultNext = make(map[string][]maa.NextItem)
+	s.LockedFingerprints = make(map[string]struct{})
+	s.WithheldItems = make(map[string][]withheldItem)
+	s.RetroLockedCount = 0
+	s.WarmupMatches = 0
+	s.WarmupHeld = nil
+	s.TypeLockedCount = make(map[string]int)
+	s.TypeLimitSkipCount = make(map[string]int)
+	s.SlotRuleSkipCount = 0
 	s.TargetSkillCombinations = nil
 	s.MatchedCombinationSummary = nil
 	s.MatchEngine = nil
 	s.CurrentRow = 1
-	s.MaxItemsPerRow = 9
+	s.MaxItemsPerRow = defaultItemsPerRow
 	s.TotalCount = 0
 	s.FirstRowSwipeDone = false
-	s.FinalLargeScanUsed = false
+	s.FinalScanCount = 0
+	s.FinalScanSeen = make(map[[2]int]struct{})
 	s.InFinalScan = false
 	s.PendingFinalScan = false
 	s.SwipeCalibrateRetry = 0
+	s.LegacyColorMatchCost = 0
+	s.ResumeCheckpoint = nil
+	s.ResumeSkipRows = 0
 	s.CurrentSkills = [3]string{}
 	s.CurrentSkillLevels = [3]int{}
 	s.RowBoxes = nil
 	s.RowIndex = 0
+	s.CurrentEssenceType = ""
+	s.CurrentMaxRarity = 0
+	s.CurrentComboKey = ""
+	s.CurrentCombo = nil
+	s.CombinationSeenCount = make(map[string]int)
+	s.RarityLockedCount = make(map[int]int)
 	s.PhysicalItemCount = 0
 	s.PipelineOpts = EssenceFilterOptions{}
 	s.InputLanguage = ""
@@ -0,0 +1,93 @@
+package essencefilter
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/essencefilter/matchapi"
+)
+
+// 同一视觉行里混排两种基质：排序后每个格子仍带着自己的类型
+func TestSortRowBoxesKeepsTypes(t *testing.T) {
+	boxes := []rowBox{
+		{Box: [4]int{300, 200, 60, 60}, EssenceType: "pure", Slot: 11},
+		{Box: [4]int{100, 200, 60, 60}, EssenceType: "flawless", Slot: 9},
+		{Box: [4]int{200, 100, 60, 60}, EssenceType: "pure", Slot: 1},
+		{Box: [4]int{200, 200, 60, 60}, EssenceType: "flawless", Slot: 10},
+		{Box: [4]int{100, 100, 60, 60}, EssenceType: "flawless", Slot: 0},
+	}
+	sortRowBoxes(boxes)
+
+	want := []rowBox{
+		{Box: [4]int{100, 100, 60, 60}, EssenceType: "flawless", Slot: 0},
+		{Box: [4]int{200, 100, 60, 60}, EssenceType: "pure", Slot: 1},
+		{Box: [4]int{100, 200, 60, 60}, EssenceType: "flawless", Slot: 9},
+		{Box: [4]int{200, 200, 60, 60}, EssenceType: "flawless", Slot: 10},
+		{Box: [4]int{300, 200, 60, 60}, EssenceType: "pure", Slot: 11},
+	}
+	if !slices.Equal(boxes, want) {
+		t.Errorf("sortRowBoxes() = %+v, want %+v", boxes, want)
+	}
+}
+
+// 旧实现按坐标查 map，坐标相同的两个框会共用一个类型；切片中的每一项各自保留
+func TestRowBoxesWithSameBoxKeepOwnType(t *testing.T) {
+	box := [4]int{100, 200, 60, 60}
+	boxes := []rowBox{
+		{Box: box, EssenceType: "flawless"},
+		{Box: box, EssenceType: "pure"},
+	}
+	sortRowBoxes(boxes)
+	if boxes[0].EssenceType != "flawless" || boxes[1].EssenceType != "pure" {
+		t.Errorf("types after sort = %q, %q, want flawless, pure", boxes[0].EssenceType, boxes[1].EssenceType)
+	}
+}
+
+func TestCurrentWithheldItemUsesRowBox(t *testing.T) {
+	st := &RunState{
+		RowBoxes: []rowBox{
+			{Box: [4]int{100, 200, 60, 60}, EssenceType: "flawless"},
+			{Box: [4]int{200, 200, 60, 60}, EssenceType: "pure"},
+		},
+		// RowNextItem 点击后 RowIndex 已指向下一个格子
+		RowIndex:           2,
+		CurrentEssenceType: "pure",
+		CurrentRow:         3,
+	}
+	item := currentWithheldItem(st)
+	if !item.HasBox || item.Box != st.RowBoxes[1].Box {
+		t.Errorf("withheld box = %v (has=%v), want %v", item.Box, item.HasBox, st.RowBoxes[1].Box)
+	}
+	if item.EssenceType != "pure" {
+		t.Errorf("withheld type = %q, want pure", item.EssenceType)
+	}
+	if !item.inCurrentRow(st) {
+		t.Error("withheld item should be in the current row")
+	}
+
+	st.RowIndex = 0
+	if item := currentWithheldItem(st); item.HasBox {
+		t.Errorf("no box has been clicked yet, got %v", item.Box)
+	}
+}
+
+// 摘要只在锁定成功时计入：addMatchedSummary 复制模板，不改动暂扣件上保存的模板
+func TestAddMatchedSummaryCopiesTemplate(t *testing.T) {
+	st := &RunState{MatchedCombinationSummary: make(map[string]*matchapi.SkillCombinationSummary)}
+	combo := &matchapi.SkillCombinationSummary{SkillIDs: []int{1, 2, 3}}
+
+	addMatchedSummary(st, "", combo)
+	addMatchedSummary(st, "1-2-3", nil)
+	if len(st.MatchedCombinationSummary) != 0 {
+		t.Fatalf("summary = %v, want empty", st.MatchedCombinationSummary)
+	}
+
+	addMatchedSummary(st, "1-2-3", combo)
+	addMatchedSummary(st, "1-2-3", combo)
+	if got := st.MatchedCombinationSummary["1-2-3"].Count; got != 2 {
+		t.Errorf("count = %d, want 2", got)
+	}
+	if combo.Count != 0 {
+		t.Errorf("template count = %d, want 0", combo.Count)
+	}
+}
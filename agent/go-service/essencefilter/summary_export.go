@@ -0,0 +1,104 @@
+package essencefilter
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// defaultSummaryOutputDir 未配置 summary_output_dir 时战利品摘要 JSON 的输出目录
+const defaultSummaryOutputDir = "debug/essencefilter"
+
+// 战利品摘要 JSON：与 logMatchSummary 的 HTML 表格同源（MatchedCombinationSummary），便于保存历次刷取记录
+type exportedSummary struct {
+	FinishedAt    string                 `json:"finished_at"`
+	DataVersion   string                 `json:"data_version"`
+	InputLanguage string                 `json:"input_language"`
+	DryRun        bool                   `json:"dry_run"`
+	VisitedCount  int                    `json:"visited_count"`
+	MatchedCount  int                    `json:"matched_count"`
+	Combos        []exportedSummaryCombo `json:"combos"`
+}
+
+type exportedSummaryCombo struct {
+	SkillIDs  []int                   `json:"skill_ids"`
+	OCRSkills []string                `json:"ocr_skills"`
+	Weapons   []exportedSummaryWeapon `json:"weapons"`
+	Count     int                     `json:"count"`
+}
+
+type exportedSummaryWeapon struct {
+	Name   string `json:"name"`
+	Rarity int    `json:"rarity"`
+}
+
+// summaryOutputDir 返回摘要输出目录：未配置时为默认目录，显式配置为空串时不导出
+func summaryOutputDir(opts *EssenceFilterOptions) string {
+	if opts.SummaryOutputDir == nil {
+		return defaultSummaryOutputDir
+	}
+	return *opts.SummaryOutputDir
+}
+
+// buildExportedSummary 组合按 key 排序，保证同一次运行的输出稳定
+func buildExportedSummary(st *RunState, finishedAt time.Time) exportedSummary {
+	summary := exportedSummary{
+		FinishedAt:    finishedAt.Format(time.RFC3339),
+		InputLanguage: st.InputLanguage,
+		DryRun:        st.PipelineOpts.DryRun,
+		VisitedCount:  st.VisitedCount,
+		MatchedCount:  st.MatchedCount,
+		Combos:        []exportedSummaryCombo{},
+	}
+	if st.MatchEngine != nil {
+		summary.DataVersion = st.MatchEngine.DataVersion()
+	}
+
+	keys := make([]string, 0, len(st.MatchedCombinationSummary))
+	for k := range st.MatchedCombinationSummary {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s := st.MatchedCombinationSummary[k]
+		weapons := make([]exportedSummaryWeapon, 0, len(s.Weapons))
+		for _, w := range s.Weapons {
+			weapons = append(weapons, exportedSummaryWeapon{Name: w.ChineseName, Rarity: w.Rarity})
+		}
+		summary.Combos = append(summary.Combos, exportedSummaryCombo{
+			SkillIDs:  append([]int(nil), s.SkillIDs...),
+			OCRSkills: append([]string(nil), s.OCRSkills...),
+			Weapons:   weapons,
+			Count:     s.Count,
+		})
+	}
+	return summary
+}
+
+// exportMatchSummary 将本次战利品摘要写入输出目录下带时间戳的 JSON 文件；目录为空时不导出
+func exportMatchSummary(st *RunState) (string, error) {
+	dir := summaryOutputDir(&st.PipelineOpts)
+	if dir == "" {
+		return "", nil
+	}
+	now := time.Now()
+	data, err := json.MarshalIndent(buildExportedSummary(st, now), "", "    ")
+	if err != nil {
+		return "", fmt.Errorf("marshal summary: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create summary dir: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("summary_%s.json", now.Format("20060102_150405")))
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return "", fmt.Errorf("write summary: %w", err)
+	}
+	log.Info().Str("component", "EssenceFilter").Str("path", path).Int("combos", len(st.MatchedCombinationSummary)).Msg("match summary exported")
+	return path, nil
+}
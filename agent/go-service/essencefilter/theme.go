@@ -0,0 +1,100 @@
+package essencefilter
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ThemeOptions 是生成 HTML 时使用的配色，来自 attach 的 theme 字段；未填写的项沿用默认配色。
+// rarity_colors 的键为稀有度（"3"~"6"），"default" 为其余稀有度的颜色。
+type ThemeOptions struct {
+	MatchedColor   string            `json:"matched_color"`
+	UnmatchedColor string            `json:"unmatched_color"`
+	RarityColors   map[string]string `json:"rarity_colors"`
+}
+
+var defaultTheme = ThemeOptions{
+	MatchedColor:   "#064d7c",
+	UnmatchedColor: "#00bfff",
+	RarityColors: map[string]string{
+		"6":       "#ff7000",
+		"5":       "#ffba03",
+		"4":       "#9451f8",
+		"3":       "#26bafb",
+		"default": "#493a3a",
+	},
+}
+
+// themeColorRe 只接受 #rrggbb，颜色会直接拼进 HTML style
+var themeColorRe = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// mergeTheme 将 patch 中合法的颜色覆盖到 dst 上，非法颜色记录告警并忽略
+func mergeTheme(dst *ThemeOptions, patch ThemeOptions) {
+	pick := func(field, v string) (string, bool) {
+		if v == "" {
+			return "", false
+		}
+		if !themeColorRe.MatchString(v) {
+			log.Warn().Str("component", "EssenceFilter").Str("field", field).Str("color", v).Msg("invalid theme color, ignored")
+			return "", false
+		}
+		return v, true
+	}
+	if v, ok := pick("matched_color", patch.MatchedColor); ok {
+		dst.MatchedColor = v
+	}
+	if v, ok := pick("unmatched_color", patch.UnmatchedColor); ok {
+		dst.UnmatchedColor = v
+	}
+	if len(patch.RarityColors) > 0 {
+		merged := make(map[string]string, len(dst.RarityColors)+len(patch.RarityColors))
+		for k, v := range dst.RarityColors {
+			merged[k] = v
+		}
+		for k, v := range patch.RarityColors {
+			if c, ok := pick("rarity_colors."+k, v); ok {
+				merged[k] = c
+			}
+		}
+		dst.RarityColors = merged
+	}
+}
+
+// activeTheme 返回当前运行的配色；无运行状态时为默认配色。
+// 各取色方法对空值或非法值回退默认配色（战利品分支的选项不经过 mergeTheme）
+func activeTheme() ThemeOptions {
+	if st := getRunState(); st != nil {
+		return st.PipelineOpts.Theme
+	}
+	return defaultTheme
+}
+
+func themeColorOr(c, fallback string) string {
+	if themeColorRe.MatchString(c) {
+		return c
+	}
+	return fallback
+}
+
+func (t ThemeOptions) rarityColor(rarity int) string {
+	fallback := defaultTheme.RarityColors["default"]
+	if c, ok := defaultTheme.RarityColors[strconv.Itoa(rarity)]; ok {
+		fallback = c
+	}
+	if c, ok := t.RarityColors[strconv.Itoa(rarity)]; ok {
+		return themeColorOr(c, fallback)
+	}
+	if c, ok := t.RarityColors["default"]; ok {
+		return themeColorOr(c, fallback)
+	}
+	return fallback
+}
+
+func (t ThemeOptions) ocrColor(matched bool) string {
+	if matched {
+		return themeColorOr(t.MatchedColor, defaultTheme.MatchedColor)
+	}
+	return themeColorOr(t.UnmatchedColor, defaultTheme.UnmatchedColor)
+}
@@ -12,6 +12,8 @@ type EssenceFilterOptions struct {
 	// 保留未来可期基质：三种词条且总等级 >= n
 	KeepFuturePromising     bool `json:"keep_future_promising"`
 	FuturePromisingMinTotal int  `json:"future_promising_min_total"`
+	// 未来可期至少需要的不同非空词条数（1-3）；<= 0 表示三个词条都必须识别到（旧行为）
+	FuturePromisingMinDistinct int `json:"future_promising_min_distinct"`
 	// 未来可期命中后是否执行锁定；关闭时仅分类命中并跳过（不锁定、不废弃）
 	LockFuturePromising bool `json:"lock_future_promising"`
 	// 保留实用基质：词条3等级 >= n 且为辅助即插即用技能
@@ -21,11 +23,45 @@ type EssenceFilterOptions struct {
 	LockSlot3Practical bool `json:"lock_slot3_practical"`
 	// 未匹配时废弃而非跳过
 	DiscardUnmatched bool `json:"discard_unmatched"`
+	// 试运行：照常匹配并汇总命中，但不锁定、不废弃，命中与未命中一律跳到下一格
+	DryRun bool `json:"dry_run"`
+	// 续扫：Init 读取上次中断时保存的遍历检查点，库存总数一致时跳过已处理的行（见 checkpoint.go）
+	Resume bool `json:"resume"`
 	// 筛选结束后推荐预刻写方案（枚举最优方案并输出到日志）
 	ExportCalculatorScript bool `json:"export_calculator_script"`
 	// 收集每行时对缩略图做已锁定/已废弃标记识别，命中则从本行待处理列表排除（见 RowCollect；双开时用 EssenceThumbMarked，否则单模板节点）
 	SkipThumbLock    bool `json:"skip_thumb_lock"`
 	SkipThumbDiscard bool `json:"skip_thumb_discard"`
+	// 仅保留重复组合：精准匹配的组合在本次运行中累计命中 >= DuplicateMinCount 次才锁定；
+	// 达到阈值前的单件先跳过，阈值达成时若仍在当前行则回头补锁
+	KeepDuplicatesOnly bool `json:"keep_duplicates_only"`
+	DuplicateMinCount  int  `json:"duplicate_min_count"`
+	// 锁定预热：前 n 次可锁定的命中只识别与汇总、不锁定，达到 n 次后开始锁定（同一行内的暂扣物品回头补锁）；0 表示关闭
+	RequireMatchesBeforeLock int `json:"require_matches_before_lock"`
+	// 按基质类型限制本次运行的锁定数量，键为 EssenceMeta.Key（flawless|pure），<= 0 或缺省表示不限；
+	// 达到上限后该类型命中的物品仅跳过
+	PerTypeLockLimit map[string]int `json:"per_type_lock_limit"`
+	// 按槽位（1-3）限制可锁定的技能，值为技能名列表：黑名单任一槽命中即跳过（即使武器组合已匹配），
+	// 白名单对配置了名单的槽要求 OCR 技能在名单内；见 slot_rules.go
+	SlotWhitelist map[int][]string `json:"slot_whitelist"`
+	SlotBlacklist map[int][]string `json:"slot_blacklist"`
+	// 非空时在 Finish 把本次命中的组合导出为预设 JSON（见 preset_export.go）
+	ExportPresetPath string `json:"export_preset_path"`
+	// Finish 时写入战利品摘要 JSON 的目录；缺省为 debug/essencefilter，显式设为空串时不写入（见 summary_export.go）
+	SummaryOutputDir *string `json:"summary_output_dir"`
+	// OCR 置信度下限：技能/等级识别结果的 score 低于该值时视为识别失败，走重试路径；0 表示不校验
+	MinOcrConfidence float64 `json:"min_ocr_confidence"`
+	// 基质颜色识别容差：把各基质 HSV 范围的上下界对称放宽 n（钳制到 H 0-179、S/V 0-255），补偿设备间的渲染/伽马差异；0 表示不放宽
+	ColorTolerance int `json:"color_tolerance"`
+	// 整次运行的时间预算（毫秒），超时后在下一次换格/收集时结束并保留已有统计；<= 0 表示不限
+	MaxRunMs int `json:"max_run_ms"`
+	// 每行显示的格子数（1-12），随分辨率/长宽比变化；<= 0 时为 9。RowCollect 的越界保护与 RowNextItem 的换行判断依赖此值
+	ItemsPerRow int `json:"items_per_row"`
+	// 尾扫最多重复处理的轮数：每轮处理完后重新检测，直到某轮没有新格子或达到上限；1 即旧的单次尾扫
+	FinalScanMaxPasses int `json:"final_scan_max_passes"`
+
+	// Theme 覆盖 MXU HTML 的配色，见 theme.go
+	Theme ThemeOptions `json:"theme"`
 
 	// InputLanguage is game/OCR language for skill matching: CN|TC|EN|JP|KR (default CN).
 	InputLanguage string `json:"input_language"`
@@ -36,11 +72,59 @@ type ColorRange struct {
 	Upper [3]int
 }
 
+// hueMax 是 OpenCV HSV 色相的最大值（H 0-179）
+const hueMax = 179
+
+// HueWraps 表示色相范围跨越 0/180 边界：Lower[0] > Upper[0] 时匹配 [Lower,179] ∪ [0,Upper]
+func (r ColorRange) HueWraps() bool {
+	return r.Lower[0] > r.Upper[0]
+}
+
+// HueSegments 把跨边界的范围拆成两段普通范围（S/V 不变），不跨边界时原样返回一段
+func (r ColorRange) HueSegments() []ColorRange {
+	if !r.HueWraps() {
+		return []ColorRange{r}
+	}
+	high, low := r, r
+	high.Upper[0] = hueMax
+	low.Lower[0] = 0
+	return []ColorRange{high, low}
+}
+
+// Expand 把上下界对称放宽 tol 后返回新范围，结果钳制到 OpenCV HSV 的合法区间（H 0-179，S/V 0-255）；
+// 跨边界的色相范围向两侧放宽，放宽到首尾相接时视为整圈
+func (r ColorRange) Expand(tol int) ColorRange {
+	if tol <= 0 {
+		return r
+	}
+	limits := [3]int{hueMax, 255, 255}
+	out := r
+	for i := range 3 {
+		out.Lower[i] = max(0, r.Lower[i]-tol)
+		out.Upper[i] = min(limits[i], r.Upper[i]+tol)
+	}
+	if r.HueWraps() {
+		out.Lower[0], out.Upper[0] = r.Lower[0]-tol, r.Upper[0]+tol
+		if out.Lower[0] <= out.Upper[0]+1 {
+			out.Lower[0], out.Upper[0] = 0, hueMax
+		}
+	}
+	return out
+}
+
 type EssenceMeta struct {
+	// Key 用于 PerTypeLockLimit 等按类型配置的键：flawless|pure
+	Key   string
 	Name  string
 	Range ColorRange
 }
 
+// 每行格子数：未配置或 <= 0 时为 defaultItemsPerRow，可配置上限为 maxItemsPerRowLimit
+const (
+	defaultItemsPerRow  = 9
+	maxItemsPerRowLimit = 12
+)
+
 // EssenceMode describes which essence tiers are selected for this run.
 type EssenceMode int
 
@@ -54,6 +138,7 @@ const (
 var (
 	// Essence color matching parameters (defaults; per-run selection in RunState.EssenceTypes)
 	FlawlessEssenceMeta = EssenceMeta{
+		Key:  "flawless",
 		Name: "无暇基质",
 		Range: ColorRange{
 			Lower: [3]int{18, 70, 220},
@@ -61,6 +146,7 @@ var (
 		},
 	}
 	PureEssenceMeta = EssenceMeta{
+		Key:  "pure",
 		Name: "高纯基质",
 		Range: ColorRange{
 			Lower: [3]int{130, 55, 80},
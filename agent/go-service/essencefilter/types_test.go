@@ -0,0 +1,105 @@
+package essencefilter
+
+import (
+	"image"
+	"image/color"
+	"slices"
+	"testing"
+
+	maa "github.com/MaaXYZ/maa-framework-go/v4"
+)
+
+// 偏红的精华：色相跨越 0/180 边界，[170,179] ∪ [0,8]
+var wrapRed = ColorRange{Lower: [3]int{170, 120, 120}, Upper: [3]int{8, 255, 255}}
+
+func TestHueWrapsAndSegments(t *testing.T) {
+	if !wrapRed.HueWraps() {
+		t.Fatal("wrap-around red range should report HueWraps")
+	}
+	want := []ColorRange{
+		{Lower: [3]int{170, 120, 120}, Upper: [3]int{179, 255, 255}},
+		{Lower: [3]int{0, 120, 120}, Upper: [3]int{8, 255, 255}},
+	}
+	if got := wrapRed.HueSegments(); !slices.Equal(got, want) {
+		t.Errorf("HueSegments() = %v, want %v", got, want)
+	}
+
+	plain := ColorRange{Lower: [3]int{20, 100, 100}, Upper: [3]int{30, 255, 255}}
+	if plain.HueWraps() {
+		t.Error("plain range should not report HueWraps")
+	}
+	if got := plain.HueSegments(); !slices.Equal(got, []ColorRange{plain}) {
+		t.Errorf("HueSegments() of plain range = %v, want the range itself", got)
+	}
+}
+
+func TestWrapRangeContains(t *testing.T) {
+	cases := []struct {
+		hsv  [3]int
+		want bool
+	}{
+		{[3]int{175, 200, 200}, true},
+		{[3]int{179, 200, 200}, true},
+		{[3]int{0, 200, 200}, true},
+		{[3]int{8, 200, 200}, true},
+		{[3]int{9, 200, 200}, false},
+		{[3]int{169, 200, 200}, false},
+		{[3]int{90, 200, 200}, false},
+		// 色相命中但饱和度不足
+		{[3]int{2, 80, 200}, false},
+	}
+	for _, tc := range cases {
+		if got := wrapRed.contains(tc.hsv); got != tc.want {
+			t.Errorf("contains(%v) = %v, want %v", tc.hsv, got, tc.want)
+		}
+	}
+}
+
+func TestWrapRangeExpand(t *testing.T) {
+	got := wrapRed.Expand(5)
+	want := ColorRange{Lower: [3]int{165, 115, 115}, Upper: [3]int{13, 255, 255}}
+	if got != want {
+		t.Errorf("Expand(5) = %v, want %v", got, want)
+	}
+	// 放宽到首尾相接时视为整圈
+	if got := wrapRed.Expand(90); got.Lower[0] != 0 || got.Upper[0] != hueMax {
+		t.Errorf("Expand(90) hue = [%d,%d], want full circle", got.Lower[0], got.Upper[0])
+	}
+}
+
+func TestClassifyWrapRed(t *testing.T) {
+	// 左半边 H≈175（偏紫红），右半边 H≈3（偏橙红），都应落在 wrapRed 内
+	img := image.NewRGBA(image.Rect(0, 0, 20, 10))
+	for y := range 10 {
+		for x := range 20 {
+			c := color.RGBA{220, 20, 50, 255}
+			if x >= 10 {
+				c = color.RGBA{220, 35, 20, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	if h := opencvHSV(220, 20, 50)[0]; h < 170 {
+		t.Fatalf("fixture left half hue = %d, want >= 170", h)
+	}
+	if h := opencvHSV(220, 35, 20)[0]; h > 8 {
+		t.Fatalf("fixture right half hue = %d, want <= 8", h)
+	}
+
+	blue := ColorRange{Lower: [3]int{100, 120, 120}, Upper: [3]int{130, 255, 255}}
+	for _, tc := range []struct {
+		name string
+		roi  maa.Rect
+	}{
+		{"upper hue", maa.Rect{0, 0, 10, 10}},
+		{"lower hue", maa.Rect{10, 0, 10, 10}},
+		{"both", maa.Rect{0, 0, 20, 10}},
+	} {
+		for _, connected := range []bool{false, true} {
+			hits := classifyEssenceColors(img, tc.roi, []ColorRange{wrapRed, blue}, essenceColorParams{count: 50, connected: connected})
+			if !hits[0] || hits[1] {
+				t.Errorf("%s (connected=%v): hits = %v, want [true false]", tc.name, connected, hits)
+			}
+		}
+	}
+}
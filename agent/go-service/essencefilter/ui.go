@@ -50,18 +50,7 @@ func LogMXUSimpleHTML(ctx *maa.Context, text string) {
 }
 
 func getColorForRarity(rarity int) string {
-	switch rarity {
-	case 6:
-		return "#ff7000" // rarity 6
-	case 5:
-		return "#ffba03" // rarity 5
-	case 4:
-		return "#9451f8" // rarity 4
-	case 3:
-		return "#26bafb" // rarity 3
-	default:
-		return "#493a3a" // Default color
-	}
+	return activeTheme().rarityColor(rarity)
 }
 
 // escapeHTML - 简单封装 html.EscapeString，便于后续统一替换/扩展
@@ -106,6 +95,42 @@ func logMatchSummary(ctx *maa.Context) {
 	}))
 }
 
+type raritySummaryRow struct {
+	Label string
+	Color string
+	Count int
+}
+
+// logRaritySummary - 按命中武器的最高稀有度统计锁定数，快速判断本次运行的质量
+func logRaritySummary(ctx *maa.Context) {
+	st := getRunState()
+	if st == nil || len(st.RarityLockedCount) == 0 {
+		return
+	}
+	rarities := make([]int, 0, len(st.RarityLockedCount))
+	for r, n := range st.RarityLockedCount {
+		if n > 0 {
+			rarities = append(rarities, r)
+		}
+	}
+	if len(rarities) == 0 {
+		return
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(rarities)))
+
+	rows := make([]raritySummaryRow, 0, len(rarities))
+	for _, r := range rarities {
+		label := i18n.T("essencefilter.rarity_summary.other")
+		if r > 0 {
+			label = i18n.T("essencefilter.rarity_summary.rarity", r)
+		}
+		rows = append(rows, raritySummaryRow{Label: label, Color: getColorForRarity(r), Count: st.RarityLockedCount[r]})
+	}
+	LogMXUHTML(ctx, i18n.RenderHTML("essencefilter.rarity_summary", map[string]any{
+		"Items": rows,
+	}))
+}
+
 // --- 预刻写方案推荐（同上 case）---
 
 type calcPlan struct {
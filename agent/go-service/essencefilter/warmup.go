@@ -0,0 +1,39 @@
+package essencefilter
+
+import (
+	maa "github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// holdForWarmup 实现 RequireMatchesBeforeLock 锁定预热：在 lockCurrentItem 中对每次可锁定的命中调用。
+// 前 n-1 次命中暂扣（跳过不锁定）；第 n 次命中时开启锁定，先补锁同一行内暂扣的物品，再锁定当前物品。
+// 返回 true 表示当前物品应跳过。
+func holdForWarmup(ctx *maa.Context, st *RunState) bool {
+	required := st.PipelineOpts.RequireMatchesBeforeLock
+	if required <= 0 || st.WarmupMatches >= required {
+		return false
+	}
+	st.WarmupMatches++
+	if st.WarmupMatches < required {
+		st.WarmupHeld = append(st.WarmupHeld, currentWithheldItem(st))
+		log.Info().Str("component", "EssenceFilter").Int("matches", st.WarmupMatches).Int("required", required).Msg("warm-up: hold match")
+		reportSimpleByKey(ctx, st, "focus.warmup.held", st.WarmupMatches, required)
+		return true
+	}
+
+	remaining, locked := retroLockItems(ctx, st, st.WarmupHeld)
+	st.WarmupHeld = remaining
+	log.Info().Str("component", "EssenceFilter").Int("required", required).Int("retro_locked", locked).Int("unreachable", len(remaining)).
+		Msg("warm-up complete, locking enabled")
+	reportColoredByKey(ctx, st, "#11cf00", "focus.warmup.enabled", required, locked)
+	return false
+}
+
+// reportWarmupHeld 结束时提示预热阶段暂扣、且未能补锁的物品数
+func reportWarmupHeld(ctx *maa.Context, st *RunState) {
+	if st == nil || len(st.WarmupHeld) == 0 {
+		return
+	}
+	log.Info().Str("component", "EssenceFilter").Int("held", len(st.WarmupHeld)).Msg("warm-up held items not locked")
+	reportColoredByKey(ctx, st, "#ffba03", "focus.finish.warmup_unlocked", len(st.WarmupHeld))
+}
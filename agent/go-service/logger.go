@@ -1,15 +1,34 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	// EnvLogLevel 全局日志级别（trace/debug/info/warn/error），默认 debug
+	EnvLogLevel = "LOG_LEVEL"
+	// EnvLogFile 日志文件路径，默认 debug/go-service.log
+	EnvLogFile = "LOG_FILE"
+	// EnvLogMaxSizeMB 单个日志文件的大小上限（MB），超过后轮转，0 表示不轮转
+	EnvLogMaxSizeMB = "LOG_MAX_SIZE_MB"
+
+	defaultLogMaxSizeMB = 10
+	logBackups          = 3
+	// rotateRetryDelay 轮转失败后，间隔该时长再重试，避免每条日志都关闭、重开文件
+	rotateRetryDelay = time.Minute
+)
+
 // levelFilterWriter 根据日志级别过滤输出，只有达到指定级别的日志才会写入
 type levelFilterWriter struct {
 	writer   io.Writer
@@ -27,14 +46,95 @@ func (w *levelFilterWriter) WriteLevel(level zerolog.Level, p []byte) (n int, er
 	return len(p), nil
 }
 
-func initLogger() (*os.File, error) {
-	debugDir := filepath.Join(".", "debug")
-	if err := os.MkdirAll(debugDir, 0755); err != nil {
+// rotatingFile 按大小轮转的日志文件，轮转时 path 依次重命名为 path.1 … path.N
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+	// retryAt 上次轮转失败后允许再次尝试的时间
+	retryAt time.Time
+}
+
+func openRotatingFile(path string, maxSize int64) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize}
+	if err := r.open(); err != nil {
 		return nil, err
 	}
+	return r, nil
+}
 
-	logPath := filepath.Join(debugDir, "go-service.log")
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file = f
+	r.size = info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.maxSize > 0 && r.size+int64(len(p)) > r.maxSize && r.size > 0 && !time.Now().Before(r.retryAt) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	for i := logBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+	}
+	if err := os.Rename(r.path, r.path+".1"); err != nil {
+		// 重命名失败（例如 Windows 上文件被其他进程占用）时重新打开原文件继续追加，
+		// 否则 r.file 保持关闭，之后的日志全部写入失败；rotateRetryDelay 后再次尝试轮转
+		if openErr := r.open(); openErr != nil {
+			return errors.Join(err, openErr)
+		}
+		r.retryAt = time.Now().Add(rotateRetryDelay)
+		fmt.Fprintf(os.Stderr, "log rotation failed, keep writing to %s: %v\n", r.path, err)
+		return nil
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+func initLogger() (io.Closer, error) {
+	logPath := strings.TrimSpace(os.Getenv(EnvLogFile))
+	if logPath == "" {
+		logPath = filepath.Join(".", "debug", "go-service.log")
+	}
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return nil, err
+	}
+
+	maxSizeMB := defaultLogMaxSizeMB
+	if v := strings.TrimSpace(os.Getenv(EnvLogMaxSizeMB)); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			maxSizeMB = n
+		}
+	}
+	logFile, err := openRotatingFile(logPath, int64(maxSizeMB)<<20)
 	if err != nil {
 		return nil, err
 	}
@@ -48,7 +148,7 @@ func initLogger() (*os.File, error) {
 		minLevel: zerolog.ErrorLevel,
 	}
 
-	// 文件输出所有级别的日志
+	// 文件输出全局级别及以上的日志
 	multi := zerolog.MultiLevelWriter(consoleWriter, logFile)
 
 	log.Logger = zerolog.New(multi).
@@ -57,7 +157,21 @@ func initLogger() (*os.File, error) {
 		Caller().
 		Logger()
 
-	zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	level := zerolog.DebugLevel
+	if v := strings.TrimSpace(os.Getenv(EnvLogLevel)); v != "" {
+		if parsed, err := zerolog.ParseLevel(strings.ToLower(v)); err == nil {
+			level = parsed
+		} else {
+			log.Warn().Str("value", v).Msg("Invalid " + EnvLogLevel + ", fall back to debug")
+		}
+	}
+	zerolog.SetGlobalLevel(level)
+
+	log.Info().
+		Str("path", logPath).
+		Str("level", level.String()).
+		Int("maxSizeMB", maxSizeMB).
+		Msg("Logger initialized")
 
 	return logFile, nil
 }
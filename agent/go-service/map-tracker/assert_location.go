@@ -4,9 +4,12 @@ package maptracker
 import (
 	"encoding/json"
 	"fmt"
+	"image"
 	"regexp"
 	"strings"
 
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/events"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/screenshot"
 	"github.com/MaaXYZ/maa-framework-go/v4"
 	"github.com/rs/zerolog/log"
 )
@@ -29,6 +32,10 @@ type MapTrackerAssertLocationParam struct {
 	Threshold float64 `json:"threshold,omitempty"`
 	// Whether to enable fast mode for matching.
 	FastMode bool `json:"fast_mode,omitempty"`
+	// AverageFrames is passed to MapTrackerInfer to average the position over recent frames.
+	AverageFrames int `json:"average_frames,omitempty"`
+	// Retries is the number of extra attempts on fresh screencaps when the given image does not satisfy the assertion.
+	Retries int `json:"retries,omitempty"`
 }
 
 var _ maa.CustomRecognitionRunner = &MapTrackerAssertLocation{}
@@ -61,23 +68,92 @@ func (r *MapTrackerAssertLocation) Run(ctx *maa.Context, arg *maa.CustomRecognit
 		mapNameRegex = "^(" + strings.Join(mapNames, "|") + ")$"
 	}
 
+	// Run on the given image first, then on fresh screencaps if retries are allowed
+	result, hit := r.assertOnce(ctx, param, mapNameRegex, arg.Img, arg.Roi)
+	for attempt := 1; !hit && attempt <= param.Retries; attempt++ {
+		if ctx.GetTasker().Stopping() {
+			return nil, false
+		}
+		img, err := screenshot.FreshFrame(ctx)
+		if err != nil {
+			log.Warn().Err(err).Int("attempt", attempt).Msg("Failed to capture fresh image for location assertion retry")
+			continue
+		}
+		log.Info().Int("attempt", attempt).Int("retries", param.Retries).Msg("Retrying location assertion on fresh screencap")
+		result, hit = r.assertOnce(ctx, param, mapNameRegex, img, arg.Roi)
+	}
+	return result, hit
+}
+
+// assertOnce runs inference on img and checks the result against the expected conditions.
+func (r *MapTrackerAssertLocation) assertOnce(
+	ctx *maa.Context,
+	param *MapTrackerAssertLocationParam,
+	mapNameRegex string,
+	img image.Image,
+	roi maa.Rect,
+) (*maa.CustomRecognitionResult, bool) {
 	// Prepare and run MapTrackerInfer
-	inferConfig := map[string]any{
+	result, detail, ok := runInferForAssertion(ctx, map[string]any{
 		"map_name_regex": mapNameRegex,
 		"precision":      param.Precision,
 		"threshold":      param.Threshold,
+		"average_frames": param.AverageFrames,
+	}, img, roi)
+	if !ok {
+		log.Info().Msg("Location assertion not satisfied, inference not hit")
+		events.Match(ctx, "maptracker", "assert_location", map[string]any{"satisfied": false, "reason": "infer_miss"})
+		return nil, false
+	}
+
+	// Check if current location satisfies any of the expected conditions
+	for _, condition := range param.Expected {
+		if result.MapName == condition.MapName {
+			x, y, w, h := condition.Target[0], condition.Target[1], condition.Target[2], condition.Target[3]
+			if result.X >= x && result.X < x+w && result.Y >= y && result.Y < y+h {
+				log.Info().
+					Interface("expected", condition).
+					Msg("Location assertion satisfied")
+				events.Match(ctx, "maptracker", "assert_location", map[string]any{
+					"satisfied": true,
+					"map":       result.MapName,
+					"x":         result.X,
+					"y":         result.Y,
+					"target":    condition.Target,
+				})
+
+				return &maa.CustomRecognitionResult{
+					Box:    roi,
+					Detail: detail,
+				}, true
+			}
+		}
 	}
 
+	log.Info().Msg("Location assertion not satisfied, no conditions met")
+	events.Match(ctx, "maptracker", "assert_location", map[string]any{
+		"satisfied": false,
+		"reason":    "out_of_target",
+		"map":       result.MapName,
+		"x":         result.X,
+		"y":         result.Y,
+	})
+	return nil, false
+}
+
+// runInferForAssertion runs MapTrackerInfer on img with the given param and unwraps its detail.
+// Returns the parsed result and the raw detail JSON, or false when the inference did not hit.
+func runInferForAssertion(ctx *maa.Context, inferConfig map[string]any, img image.Image, roi maa.Rect) (*MapTrackerInferResult, string, bool) {
 	inferConfigBytes, err := json.Marshal(inferConfig)
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to marshal inference config")
-		return nil, false
+		return nil, "", false
 	}
 
 	taskDetail, err := ctx.GetTaskJob().GetDetail()
 	if err != nil {
 		log.Error().Err(err).Msg("Failed to get task detail")
-		return nil, false
+		return nil, "", false
 	}
 
 	resultWrapper, hit := mapTrackerInferRunner.Run(ctx, &maa.CustomRecognitionArg{
@@ -85,45 +161,19 @@ func (r *MapTrackerAssertLocation) Run(ctx *maa.Context, arg *maa.CustomRecognit
 		CurrentTaskName:        taskDetail.Entry,
 		CustomRecognitionName:  "MapTrackerInfer",
 		CustomRecognitionParam: string(inferConfigBytes),
-		Img:                    arg.Img,
-		Roi:                    arg.Roi,
+		Img:                    img,
+		Roi:                    roi,
 	})
-
-	if !hit {
-		log.Info().Msg("Location assertion not satisfied, inference not hit")
-		return nil, false
-	}
-	if resultWrapper == nil || resultWrapper.Detail == "" {
-		log.Info().Msg("Location assertion not satisfied, inference returned no result")
-		return nil, false
+	if !hit || resultWrapper == nil || resultWrapper.Detail == "" {
+		return nil, "", false
 	}
 
-	// Extract result
 	var result MapTrackerInferResult
 	if err := json.Unmarshal([]byte(resultWrapper.Detail), &result); err != nil {
 		log.Error().Err(err).Msg("Failed to unmarshal MapTrackerInferResult")
-		return nil, false
+		return nil, "", false
 	}
-
-	// Check if current location satisfies any of the expected conditions
-	for _, condition := range param.Expected {
-		if result.MapName == condition.MapName {
-			x, y, w, h := condition.Target[0], condition.Target[1], condition.Target[2], condition.Target[3]
-			if result.X >= x && result.X < x+w && result.Y >= y && result.Y < y+h {
-				log.Info().
-					Interface("expected", condition).
-					Msg("Location assertion satisfied")
-
-				return &maa.CustomRecognitionResult{
-					Box:    arg.Roi,
-					Detail: resultWrapper.Detail,
-				}, true
-			}
-		}
-	}
-
-	log.Info().Msg("Location assertion not satisfied, no conditions met")
-	return nil, false
+	return &result, resultWrapper.Detail, true
 }
 
 func (r *MapTrackerAssertLocation) parseParam(paramStr string) (*MapTrackerAssertLocationParam, error) {
@@ -148,6 +198,9 @@ func (r *MapTrackerAssertLocation) parseParam(paramStr string) (*MapTrackerAsser
 			return nil, fmt.Errorf("width and height in target must be positive for expected condition at index %d", i)
 		}
 	}
+	if param.Retries < 0 {
+		return nil, fmt.Errorf("retries must be non-negative")
+	}
 	// Precision and Threshold will be validated in MapTrackerInfer, omitted here
 
 	return &param, nil
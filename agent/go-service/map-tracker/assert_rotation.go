@@ -0,0 +1,95 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+type MapTrackerAssertRotation struct{}
+
+// MapTrackerAssertRotationParam represents the parameters for AssertRotation
+type MapTrackerAssertRotationParam struct {
+	// ExpectedRotations is a list of expected headings in degrees [0, 360), using OR logic.
+	ExpectedRotations []int `json:"expected_rotations"`
+	// ToleranceDeg is the maximum angular difference from an expected heading, in degrees.
+	ToleranceDeg int `json:"tolerance_deg,omitempty"`
+	// Precision controls the inference precision/speed tradeoff.
+	Precision float64 `json:"precision,omitempty"`
+	// Threshold controls the minimum confidence required to consider the inference successful.
+	Threshold float64 `json:"threshold,omitempty"`
+}
+
+var mapTrackerAssertRotationDefaultParam = MapTrackerAssertRotationParam{
+	ToleranceDeg: 10,
+}
+
+var _ maa.CustomRecognitionRunner = &MapTrackerAssertRotation{}
+
+// Run implements maa.CustomRecognitionRunner
+func (r *MapTrackerAssertRotation) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (*maa.CustomRecognitionResult, bool) {
+	param, err := r.parseParam(arg.CustomRecognitionParam)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse parameters for MapTrackerAssertRotation")
+		return nil, false
+	}
+
+	// Only the heading is needed, so skip location inference
+	result, detail, ok := runInferForAssertion(ctx, map[string]any{
+		"precision": param.Precision,
+		"threshold": param.Threshold,
+		"mode":      INFER_MODE_ROTATION,
+	}, arg.Img, arg.Roi)
+	if !ok {
+		log.Info().Msg("Rotation assertion not satisfied, inference not hit")
+		return nil, false
+	}
+
+	for _, expected := range param.ExpectedRotations {
+		if diff := angleDiff(result.Rot, expected); diff <= param.ToleranceDeg {
+			log.Info().Int("rot", result.Rot).Int("expected", expected).Int("diff", diff).
+				Msg("Rotation assertion satisfied")
+			return &maa.CustomRecognitionResult{
+				Box:    arg.Roi,
+				Detail: detail,
+			}, true
+		}
+	}
+
+	log.Info().Int("rot", result.Rot).Ints("expected", param.ExpectedRotations).Int("tolerance", param.ToleranceDeg).
+		Msg("Rotation assertion not satisfied, no heading matched")
+	return nil, false
+}
+
+// angleDiff returns the smallest difference between two headings in degrees, in [0, 180]
+func angleDiff(a, b int) int {
+	d := ((a-b)%360 + 360) % 360
+	return min(d, 360-d)
+}
+
+func (r *MapTrackerAssertRotation) parseParam(paramStr string) (*MapTrackerAssertRotationParam, error) {
+	param := mapTrackerAssertRotationDefaultParam
+	if paramStr != "" {
+		if err := json.Unmarshal([]byte(paramStr), &param); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal parameters: %w", err)
+		}
+	}
+
+	if len(param.ExpectedRotations) == 0 {
+		return nil, fmt.Errorf("expected_rotations must be provided")
+	}
+	for i, rot := range param.ExpectedRotations {
+		if rot < 0 || rot >= 360 {
+			return nil, fmt.Errorf("expected rotation at index %d must be in [0, 360), got %d", i, rot)
+		}
+	}
+	if param.ToleranceDeg < 0 || param.ToleranceDeg > 180 {
+		return nil, fmt.Errorf("tolerance_deg must be in [0, 180], got %d", param.ToleranceDeg)
+	}
+	// Precision and Threshold will be validated in MapTrackerInfer, omitted here
+
+	return &param, nil
+}
@@ -266,7 +266,7 @@ func (r *MapTrackerBigMapInfer) parseParam(paramStr string) (*MapTrackerBigMapIn
 // initMaps initializes map cache for big-map inference only.
 func (r *MapTrackerBigMapInfer) initMaps(ctx *maa.Context) {
 	r.mapsOnce.Do(func() {
-		mt.Resource.InitRawMaps(ctx)
+		mt.Resource.InitRawMaps(ctx, nil)
 		if mt.Resource.RawMapsErr != nil {
 			r.mapsErr = mt.Resource.RawMapsErr
 			return
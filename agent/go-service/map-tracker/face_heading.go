@@ -0,0 +1,173 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	mt "github.com/MaaXYZ/MaaEnd/agent/go-service/map-tracker/internal"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/control"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/i18n"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/maafocus"
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+type MapTrackerFaceHeading struct{}
+
+// MapTrackerFaceHeadingParam represents the custom_action_param for MapTrackerFaceHeading
+type MapTrackerFaceHeadingParam struct {
+	// Heading is the desired player heading in degrees, 0 is North, increasing clockwise (required).
+	Heading *float64 `json:"heading"`
+	// MapName is the name of the current map; empty means all regular maps are searched.
+	MapName string `json:"map_name,omitempty"`
+	// Tolerance is the maximum heading error in degrees to consider the heading reached.
+	Tolerance float64 `json:"tolerance,omitempty"`
+	// StepBudget is the maximum number of camera adjustments before giving up.
+	StepBudget int `json:"step_budget,omitempty"`
+	// NoPrint controls whether to suppress printing the final heading error to the GUI.
+	NoPrint bool `json:"no_print,omitempty"`
+	// MapNameMatchRule is the regex template used to match recognized map names. Use %s as map_name placeholder.
+	MapNameMatchRule string `json:"map_name_match_rule,omitempty"`
+}
+
+var mapTrackerFaceHeadingDefaultParam = MapTrackerFaceHeadingParam{
+	Tolerance:        5.0,
+	StepBudget:       8,
+	MapNameMatchRule: mapTrackerMoveDefaultParam.MapNameMatchRule,
+}
+
+var _ maa.CustomActionRunner = &MapTrackerFaceHeading{}
+
+// Run implements maa.CustomActionRunner
+func (a *MapTrackerFaceHeading) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
+	param, err := a.parseParam(arg.CustomActionParam)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse parameters for MapTrackerFaceHeading")
+		return false
+	}
+
+	ctrl := ctx.GetTasker().GetController()
+	ca, err := control.NewControlAdaptor(ctx, ctrl, mt.WORK_W, mt.WORK_H)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create control adaptor")
+		return false
+	}
+
+	// Reuse the navigation inference with this action's map filter
+	inferParam := &MapTrackerMoveParam{MapName: param.MapName, MapNameMatchRule: param.MapNameMatchRule}
+	if param.MapName == "" {
+		inferParam.MapNameMatchRule = mapTrackerInferDefaultParam.MapNameRegex
+	}
+
+	target := int(math.Round(*param.Heading)) % 360
+	rotationSpeed := mt.ROTATION_DEFAULT_SPEED
+	lastRot, lastDelta := -1, 0
+	lastApplied := 0.0
+
+	for step := 0; ; step++ {
+		if ctx.GetTasker().Stopping() {
+			log.Warn().Msg("Task is stopping, exiting heading correction")
+			return false
+		}
+
+		result, err := doInfer(ctx, ctrl, inferParam)
+		if err != nil {
+			log.Warn().Err(err).Int("step", step).Msg("Inference failed during heading correction")
+			if step >= param.StepBudget {
+				a.printResult(ctx, param, target, -1, false)
+				return false
+			}
+			time.Sleep(time.Duration(mt.INFER_INTERVAL_MS) * time.Millisecond)
+			continue
+		}
+
+		// calcDeltaRotation picks the shortest turn across the 0/360 boundary
+		delta := calcDeltaRotation(result.Rot, target)
+		log.Debug().Int("step", step).Int("rot", result.Rot).Int("target", target).Int("delta", delta).Msg("Heading correction")
+
+		if math.Abs(float64(delta)) <= param.Tolerance {
+			log.Info().Int("rot", result.Rot).Int("target", target).Int("error", delta).Msg("Target heading reached")
+			a.printResult(ctx, param, target, result.Rot, true)
+			return true
+		}
+		if step >= param.StepBudget {
+			log.Error().Int("rot", result.Rot).Int("target", target).Int("error", delta).Int("stepBudget", param.StepBudget).
+				Msg("Failed to reach target heading within step budget")
+			a.printResult(ctx, param, target, result.Rot, false)
+			return false
+		}
+
+		// Adapt rotation speed from the effect of the previous adjustment, like MapTrackerMove does
+		if lastRot >= 0 && lastApplied != 0 {
+			actual := calcDeltaRotation(lastRot, result.Rot)
+			if actual != 0 && (actual > 0) == (lastDelta > 0) {
+				ideal := lastApplied / float64(actual)
+				if ideal >= mt.ROTATION_MIN_SPEED && ideal <= mt.ROTATION_MAX_SPEED {
+					rotationSpeed = rotationSpeed*0.618 + ideal*0.382
+				}
+			}
+		}
+
+		applied := float64(delta) * rotationSpeed
+		ca.RotateCamera(int(applied), 0)
+		ca.AggressivelyResetCamera()
+		lastRot, lastDelta, lastApplied = result.Rot, delta, applied
+
+		time.Sleep(max(
+			ca.GetPlayerMovement().EtaOfRotation(math.Abs(float64(delta))),
+			time.Duration(mt.INFER_INTERVAL_MS)*time.Millisecond,
+		))
+	}
+}
+
+func (a *MapTrackerFaceHeading) parseParam(paramStr string) (*MapTrackerFaceHeadingParam, error) {
+	var param MapTrackerFaceHeadingParam
+	if err := json.Unmarshal([]byte(paramStr), &param); err != nil {
+		return nil, fmt.Errorf("failed to parse parameters: %w", err)
+	}
+	if param.Heading == nil {
+		return nil, fmt.Errorf("heading is required in parameters")
+	}
+	if math.IsNaN(*param.Heading) || *param.Heading < 0 || *param.Heading >= 360 {
+		return nil, fmt.Errorf("heading must be in [0, 360)")
+	}
+
+	if param.Tolerance < 0 || param.Tolerance > 180 {
+		return nil, fmt.Errorf("tolerance must be between 0 and 180 degrees")
+	} else if param.Tolerance == 0 {
+		param.Tolerance = mapTrackerFaceHeadingDefaultParam.Tolerance
+	}
+
+	if param.StepBudget < 0 {
+		return nil, fmt.Errorf("step_budget must be non-negative")
+	} else if param.StepBudget == 0 {
+		param.StepBudget = mapTrackerFaceHeadingDefaultParam.StepBudget
+	}
+
+	if len(param.MapNameMatchRule) == 0 {
+		param.MapNameMatchRule = mapTrackerFaceHeadingDefaultParam.MapNameMatchRule
+	}
+
+	return &param, nil
+}
+
+// printResult prints the final heading error; rot < 0 means the heading is unknown.
+func (a *MapTrackerFaceHeading) printResult(ctx *maa.Context, param *MapTrackerFaceHeadingParam, target, rot int, ok bool) {
+	if param.NoPrint {
+		return
+	}
+	data := map[string]any{
+		"OK":     ok,
+		"Target": target,
+		"Known":  rot >= 0,
+		"Rot":    rot,
+		"Error":  0,
+	}
+	if rot >= 0 {
+		data["Error"] = calcDeltaRotation(rot, target)
+	}
+	maafocus.Print(ctx, i18n.RenderHTML("maptracker.face_heading", data))
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	mt "github.com/MaaXYZ/MaaEnd/agent/go-service/map-tracker/internal"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/i18n"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/maafocus"
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+type MapTrackerFollowPath struct{}
+
+// FollowPathWaypoint represents a single waypoint of MapTrackerFollowPath
+type FollowPathWaypoint struct {
+	MapName   string  `json:"map"`
+	X         float64 `json:"x"`
+	Y         float64 `json:"y"`
+	Tolerance float64 `json:"tolerance,omitempty"`
+}
+
+// MapTrackerFollowPathParam represents the custom_action_param for MapTrackerFollowPath
+type MapTrackerFollowPathParam struct {
+	// Waypoints is the ordered list of waypoints to walk (required).
+	Waypoints []FollowPathWaypoint `json:"waypoints"`
+	// NoPrint controls whether to suppress printing per-waypoint progress to the GUI.
+	NoPrint bool `json:"no_print,omitempty"`
+	// StepBudget is the maximum number of navigation attempts per waypoint.
+	StepBudget int `json:"step_budget,omitempty"`
+	// MapTransitionTimeout is the maximum time in milliseconds to wait for the player to appear on a waypoint's map.
+	MapTransitionTimeout int64 `json:"map_transition_timeout,omitempty"`
+	// MapNameMatchRule is the regex template used to match recognized map names. Use %s as map placeholder.
+	MapNameMatchRule string `json:"map_name_match_rule,omitempty"`
+}
+
+var mapTrackerFollowPathDefaultParam = MapTrackerFollowPathParam{
+	StepBudget:           3,
+	MapTransitionTimeout: 30000,
+	MapNameMatchRule:     mapTrackerMoveDefaultParam.MapNameMatchRule,
+}
+
+var _ maa.CustomActionRunner = &MapTrackerFollowPath{}
+
+// Run implements maa.CustomActionRunner
+func (a *MapTrackerFollowPath) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
+	param, err := a.parseParam(arg.CustomActionParam)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse parameters for MapTrackerFollowPath")
+		return false
+	}
+
+	ctrl := ctx.GetTasker().GetController()
+	mover := &MapTrackerMove{}
+	total := len(param.Waypoints)
+
+	log.Info().Int("waypointsCount", total).Msg("Starting to follow waypoint chain")
+
+	for i, wp := range param.Waypoints {
+		moveParam, err := a.buildMoveParam(param, i)
+		if err != nil {
+			log.Error().Err(err).Int("index", i).Msg("Failed to build move parameters for waypoint")
+			return false
+		}
+
+		// Waypoint on another map: wait until the player shows up there
+		if i > 0 && param.Waypoints[i-1].MapName != wp.MapName {
+			a.printProgress(ctx, param, i, "transition", 0)
+			if !a.waitMapTransition(ctx, ctrl, moveParam, param.MapTransitionTimeout) {
+				log.Error().Int("index", i).Str("map", wp.MapName).Msg("Map transition timeout")
+				a.printProgress(ctx, param, i, "failed", 0)
+				return false
+			}
+		}
+
+		reached := false
+		for attempt := 1; attempt <= param.StepBudget; attempt++ {
+			if ctx.GetTasker().Stopping() {
+				log.Warn().Msg("Task is stopping, exiting waypoint chain")
+				return false
+			}
+			a.printProgress(ctx, param, i, "moving", attempt)
+			log.Info().Int("index", i).Int("attempt", attempt).Str("map", wp.MapName).
+				Float64("x", wp.X).Float64("y", wp.Y).Msg("Navigating to waypoint")
+
+			mover.navigate(ctx, moveParam)
+
+			// Re-infer position to decide whether the waypoint is really reached
+			result, err := doInfer(ctx, ctrl, moveParam)
+			if err != nil {
+				log.Warn().Err(err).Int("index", i).Msg("Failed to re-infer position after navigation")
+				continue
+			}
+			dist := math.Hypot(result.X-wp.X, result.Y-wp.Y)
+			if dist <= moveParam.ArrivalThreshold {
+				log.Info().Int("index", i).Float64("dist", dist).Msg("Waypoint reached")
+				reached = true
+				break
+			}
+			log.Info().Int("index", i).Int("attempt", attempt).Float64("dist", dist).Msg("Waypoint not reached yet")
+		}
+
+		if !reached {
+			log.Error().Int("index", i).Int("stepBudget", param.StepBudget).Msg("Failed to reach waypoint within step budget")
+			a.printProgress(ctx, param, i, "failed", 0)
+			return false
+		}
+	}
+
+	a.printProgress(ctx, param, total-1, "finished", 0)
+	return true
+}
+
+func (a *MapTrackerFollowPath) parseParam(paramStr string) (*MapTrackerFollowPathParam, error) {
+	var param MapTrackerFollowPathParam
+	if err := json.Unmarshal([]byte(paramStr), &param); err != nil {
+		return nil, fmt.Errorf("failed to parse parameters: %w", err)
+	}
+	if len(param.Waypoints) == 0 {
+		return nil, fmt.Errorf("waypoints is required in parameters, got empty")
+	}
+	for i, wp := range param.Waypoints {
+		if wp.MapName == "" {
+			return nil, fmt.Errorf("map must be provided for waypoint at index %d", i)
+		}
+		if math.IsNaN(wp.X) || math.IsInf(wp.X, 0) || math.IsNaN(wp.Y) || math.IsInf(wp.Y, 0) {
+			return nil, fmt.Errorf("waypoints[%d] contains invalid coordinate", i)
+		}
+		if wp.Tolerance < 0 {
+			return nil, fmt.Errorf("tolerance must be non-negative for waypoint at index %d", i)
+		}
+	}
+
+	if param.StepBudget < 0 {
+		return nil, fmt.Errorf("step_budget must be non-negative")
+	} else if param.StepBudget == 0 {
+		param.StepBudget = mapTrackerFollowPathDefaultParam.StepBudget
+	}
+
+	if param.MapTransitionTimeout < 0 {
+		return nil, fmt.Errorf("map_transition_timeout must be non-negative")
+	} else if param.MapTransitionTimeout == 0 {
+		param.MapTransitionTimeout = mapTrackerFollowPathDefaultParam.MapTransitionTimeout
+	}
+
+	if len(param.MapNameMatchRule) == 0 {
+		param.MapNameMatchRule = mapTrackerFollowPathDefaultParam.MapNameMatchRule
+	}
+
+	return &param, nil
+}
+
+// buildMoveParam builds a validated single-target MapTrackerMove parameter for the waypoint at index.
+func (a *MapTrackerFollowPath) buildMoveParam(param *MapTrackerFollowPathParam, index int) (*MapTrackerMoveParam, error) {
+	wp := param.Waypoints[index]
+	fineApproach := FINE_APPROACH_NEVER
+	if index == len(param.Waypoints)-1 {
+		fineApproach = FINE_APPROACH_FINAL_TARGET
+	}
+	raw, err := json.Marshal(MapTrackerMoveParam{
+		MapName:          wp.MapName,
+		Path:             [][2]float64{{wp.X, wp.Y}},
+		NoPrint:          true,
+		FineApproach:     fineApproach,
+		ArrivalThreshold: wp.Tolerance,
+		MapNameMatchRule: param.MapNameMatchRule,
+	})
+	if err != nil {
+		return nil, err
+	}
+	moveParam, err := (&MapTrackerMove{}).parseParam(string(raw))
+	if err != nil {
+		return nil, err
+	}
+	moveParam.softFail = true
+	return moveParam, nil
+}
+
+// waitMapTransition polls inference until the player is located on the map of moveParam or the timeout elapses.
+func (a *MapTrackerFollowPath) waitMapTransition(ctx *maa.Context, ctrl *maa.Controller, moveParam *MapTrackerMoveParam, timeoutMs int64) bool {
+	log.Info().Str("map", moveParam.MapName).Int64("timeoutMs", timeoutMs).Msg("Waiting for map transition")
+	deadline := time.Now().Add(time.Duration(timeoutMs) * time.Millisecond)
+	for time.Now().Before(deadline) {
+		if ctx.GetTasker().Stopping() {
+			return false
+		}
+		if result, err := doInfer(ctx, ctrl, moveParam); err == nil && result != nil {
+			log.Info().Str("map", result.MapName).Msg("Map transition detected")
+			return true
+		}
+		time.Sleep(time.Duration(mt.INFER_INTERVAL_MS) * time.Millisecond)
+	}
+	return false
+}
+
+func (a *MapTrackerFollowPath) printProgress(ctx *maa.Context, param *MapTrackerFollowPathParam, index int, status string, attempt int) {
+	if param.NoPrint {
+		return
+	}
+	wp := param.Waypoints[index]
+	maafocus.Print(ctx, i18n.RenderHTML("maptracker.follow_path", map[string]any{
+		"Status":     status,
+		"CurrentIdx": index + 1,
+		"Total":      len(param.Waypoints),
+		"Map":        wp.MapName,
+		"TgtX":       wp.X,
+		"TgtY":       wp.Y,
+		"Attempt":    attempt,
+		"Budget":     param.StepBudget,
+	}))
+}
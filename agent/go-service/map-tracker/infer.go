@@ -8,13 +8,16 @@ import (
 	_ "image/png"
 	"math"
 	"regexp"
+	"sort"
 	"sync"
 	"time"
 
 	mt "github.com/MaaXYZ/MaaEnd/agent/go-service/map-tracker/internal"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/control"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/events"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/i18n"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/maafocus"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/metrics"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/minicv"
 	"github.com/MaaXYZ/maa-framework-go/v4"
 	"github.com/rs/zerolog/log"
@@ -32,6 +35,25 @@ type MapTrackerInferResult struct {
 	RotTimeMs   int64   `json:"rotTimeMs"`   // Rotation inference time in ms
 	InferMode   string  `json:"inferMode"`   // Inference mode ("FullSearchHit", "FastSearchHit", "VirtualHit")
 	InferTimeMs int64   `json:"inferTimeMs"` // Total inference time in ms
+
+	MapScores  []MapScore     `json:"mapScores,omitempty"`  // Best score of each tried map, descending (only with debug_scores)
+	Candidates []MapCandidate `json:"candidates,omitempty"` // Best location of the top-N maps, descending (only with top_n > 1)
+	NormX      *float64       `json:"normX,omitempty"`      // X divided by the full map width, in [0, 1] (only with normalize)
+	NormY      *float64       `json:"normY,omitempty"`      // Y divided by the full map height, in [0, 1] (only with normalize)
+}
+
+// MapScore is the best location matching score of a single map
+type MapScore struct {
+	MapName string  `json:"mapName"`
+	Score   float64 `json:"score"`
+}
+
+// MapCandidate is the best location match on a single map
+type MapCandidate struct {
+	MapName string  `json:"mapName"`
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	LocConf float64 `json:"locConf"`
 }
 
 // MapTrackerInferParam represents the custom_recognition_param for MapTrackerInfer
@@ -44,22 +66,57 @@ type MapTrackerInferParam struct {
 	Precision float64 `json:"precision,omitempty"`
 	// Threshold controls the minimum confidence required to consider the inference successful.
 	Threshold float64 `json:"threshold,omitempty"`
+	// Metric selects the location template matching metric. Valid values: "NCC" (default), "SAD".
+	Metric minicv.MatchMetric `json:"metric,omitempty"`
+	// DebugScores controls whether to include the per-map scores in the result.
+	DebugScores bool `json:"debug_scores,omitempty"`
+	// CheckVisibility controls whether to skip inference (not hit) when no mini-map is visible, e.g. in menus.
+	CheckVisibility bool `json:"check_visibility,omitempty"`
+	// CircularMiniMap controls whether to mask out the mini-map crop outside its inscribed circle before matching.
+	CircularMiniMap bool `json:"circular_minimap,omitempty"`
+	// PreloadRegex restricts which maps are loaded into memory; only effective on the first map-tracker call.
+	PreloadRegex string `json:"preload_regex,omitempty"`
+	// AverageFrames reports the average position of the last K successful same-map inferences when K > 1.
+	AverageFrames int `json:"average_frames,omitempty"`
+	// TopN controls how many candidate maps to include in the result; only the best one (no candidates field) when <= 1.
+	TopN int `json:"top_n,omitempty"`
+	// CoarseToFine controls whether the full search first matches all maps at a very low scale,
+	// then re-matches only the best map at full precision around the coarse peak.
+	CoarseToFine bool `json:"coarse_to_fine,omitempty"`
+	// RotRefine controls whether to refine the best rotation step in 1-degree increments plus a parabolic fit.
+	RotRefine bool `json:"rot_refine,omitempty"`
+	// Normalize controls whether to include the location normalized by the full (pre-crop) map size.
+	Normalize bool `json:"normalize,omitempty"`
+	// Mode selects which inference to run. Valid values: "both" (default), "location", "rotation".
+	Mode string `json:"mode,omitempty"`
 }
 
+// Inference modes of MapTrackerInfer
+const (
+	INFER_MODE_BOTH     = "both"
+	INFER_MODE_LOCATION = "location"
+	INFER_MODE_ROTATION = "rotation"
+)
+
 var mapTrackerInferDefaultParam = MapTrackerInferParam{
 	MapNameRegex: "^map\\d+_lv\\d+$",
 	Precision:    0.5,
 	Threshold:    0.4,
+	Metric:       minicv.MetricNCC,
+	Mode:         INFER_MODE_BOTH,
 }
 
 // MapTrackerInfer is the custom recognition component for map tracking
 type MapTrackerInfer struct {
-	// Cache for scaled maps (recomputed per request scale)
-	scaledMapsMu sync.Mutex
-	scaledMaps   []mt.MapCache
-	scaledScale  float64
+	// LRU cache for scaled maps keyed by precision, bounded to SCALED_MAPS_CACHE_SIZE entries
+	scaledMapsMu    sync.Mutex
+	scaledMaps      map[float64][]mt.MapCache
+	scaledMapsOrder []float64 // Cached precisions, least recently used first
 }
 
+// SCALED_MAPS_CACHE_SIZE is the number of precisions whose scaled maps are kept in memory
+const SCALED_MAPS_CACHE_SIZE = 4
+
 type InferState struct {
 	convinced              InferLocationRawResult
 	convincedLastHitTime   int64
@@ -75,6 +132,42 @@ type InferState struct {
 
 var globalInferState InferState
 
+// AverageWindowState keeps the recent same-map positions for average_frames
+type AverageWindowState struct {
+	mapName     string
+	samples     [][2]float64
+	lastHitTime int64
+
+	mu sync.Mutex
+}
+
+var globalAverageWindow AverageWindowState
+
+// push adds a position to the window and returns the average of the last k samples.
+// The window resets on map change or after a gap longer than AVERAGE_WINDOW_GAP_MS.
+func (w *AverageWindowState) push(mapName string, x, y float64, k int, nowMs int64) (float64, float64, int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.mapName != mapName || nowMs-w.lastHitTime > AVERAGE_WINDOW_GAP_MS {
+		w.mapName = mapName
+		w.samples = w.samples[:0]
+	}
+	w.lastHitTime = nowMs
+	w.samples = append(w.samples, [2]float64{x, y})
+	if len(w.samples) > k {
+		w.samples = w.samples[len(w.samples)-k:]
+	}
+
+	sumX, sumY := 0.0, 0.0
+	for _, s := range w.samples {
+		sumX += s[0]
+		sumY += s[1]
+	}
+	n := float64(len(w.samples))
+	return roundTo1Decimal(sumX / n), roundTo1Decimal(sumY / n), len(w.samples)
+}
+
 type InferLocationHitMode string
 
 const (
@@ -89,6 +182,13 @@ const (
 	PENDING_TAKEOVER_COUNT_THRESHOLD = 3
 	CONVINCED_DISTANCE_THRESHOLD     = 20
 	CONVINCED_VALID_TIME_MS          = 2000
+	AVERAGE_WINDOW_GAP_MS            = 2000
+)
+
+// Coarse-to-fine location search configuration
+const (
+	COARSE_SEARCH_SCALE  = 0.2
+	COARSE_WINDOW_RADIUS = 40 // Fine search radius around the coarse peak, in original map pixels
 )
 
 type InferLocationRawResult struct {
@@ -98,9 +198,11 @@ type InferLocationRawResult struct {
 	conf          float64
 	source        InferLocationHitMode
 	elapsedTimeMs int64
+	mapScores     []MapScore
+	candidates    []MapCandidate
 }
 
-var emptyLocationRawResult = InferLocationRawResult{"", 0, 0, 0.0, "", 0}
+var emptyLocationRawResult = InferLocationRawResult{"", 0, 0, 0.0, "", 0, nil, nil}
 
 var mapCoreNameRegexp = regexp.MustCompile(`^(.+?)(?:_tier_\w+)?$`)
 
@@ -136,7 +238,15 @@ func (i *MapTrackerInfer) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (
 	rotStep := max(2, min(8, int(math.Round(8-param.Precision*6))))
 
 	// Initialize map resources
-	mt.Resource.InitRawMaps(ctx)
+	var preloadRegex *regexp.Regexp
+	if param.PreloadRegex != "" {
+		preloadRegex, err = regexp.Compile(param.PreloadRegex)
+		if err != nil {
+			log.Error().Err(err).Str("regex", param.PreloadRegex).Msg("Invalid preload_regex")
+			return nil, false
+		}
+	}
+	mt.Resource.InitRawMaps(ctx, preloadRegex)
 	if mt.Resource.RawMapsErr != nil {
 		log.Error().Err(mt.Resource.RawMapsErr).Msg("Failed to initialize maps")
 		return nil, false
@@ -146,14 +256,37 @@ func (i *MapTrackerInfer) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (
 	screenImg := minicv.ImageConvertRGBA(arg.Img)
 	t0 := time.Now()
 
-	ch := make(chan *InferLocationRawResult, 1)
+	if param.CheckVisibility {
+		visible, mean, std := isMiniMapVisible(ctrlType, screenImg)
+		log.Debug().Bool("visible", visible).Float64("mean", mean).Float64("std", std).Msg("Mini-map visibility check")
+		if !visible {
+			log.Info().Msg("Map tracking inference skipped, mini-map not visible")
+			events.Match(ctx, "maptracker", "infer", map[string]any{"hit": false, "reason": "minimap_hidden"})
+			if param.Print {
+				maafocus.Print(ctx, i18n.RenderHTML("maptracker.inference_failed", nil))
+			}
+			return &maa.CustomRecognitionResult{
+				Box:    arg.Roi,
+				Detail: "",
+			}, false
+		}
+	}
 
-	go func() {
-		ch <- i.inferLocation(ctrlType, screenImg, mapNameRegex, param)
-	}()
+	var loc *InferLocationRawResult
+	var rot *InferRotationRawResult
+	ch := make(chan *InferLocationRawResult, 1)
 
-	rot := i.inferRotation(ctrlType, screenImg, rotStep)
-	loc := <-ch
+	if param.Mode != INFER_MODE_ROTATION {
+		go func() {
+			ch <- i.inferLocation(ctrlType, screenImg, mapNameRegex, param)
+		}()
+	}
+	if param.Mode != INFER_MODE_LOCATION {
+		rot = i.inferRotation(ctrlType, screenImg, rotStep, param.RotRefine)
+	}
+	if param.Mode != INFER_MODE_ROTATION {
+		loc = <-ch
+	}
 
 	// Determine if recognition hit natively
 	internalLocHit := loc != nil && loc.conf > param.Threshold
@@ -238,7 +371,7 @@ func (i *MapTrackerInfer) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (
 		}
 	}
 
-	if finalLoc == nil {
+	if finalLoc == nil && param.Mode != INFER_MODE_ROTATION {
 		if globalInferState.convinced.mapName != "" && nowMs-globalInferState.convincedLastHitTime < CONVINCED_VALID_TIME_MS {
 			// This is a temporary miss, but we can generate a virtual result
 			dt := nowMs - globalInferState.convincedLastHitTime
@@ -265,11 +398,30 @@ func (i *MapTrackerInfer) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (
 
 	globalInferState.mu.Unlock()
 
+	// Only the inference of the active mode counts; the skipped one is reported as zero
+	switch param.Mode {
+	case INFER_MODE_LOCATION:
+		finalRot = &InferRotationRawResult{}
+	case INFER_MODE_ROTATION:
+		finalLoc = &InferLocationRawResult{}
+	}
+
 	finalHit := finalLoc != nil && finalRot != nil
 	finalElapsedTimeMs := time.Since(t0).Milliseconds()
+	metrics.Observe("maptracker.infer", time.Since(t0))
+	if !finalHit {
+		metrics.Inc("maptracker.infer_miss")
+	}
 
 	if !finalHit {
 		log.Info().Bool("finalLocHit", finalLoc != nil).Bool("finalRotHit", finalRot != nil).Msg("Map tracking inference did not hit")
+		events.Match(ctx, "maptracker", "infer", map[string]any{
+			"hit":     false,
+			"reason":  "no_match",
+			"loc_hit": finalLoc != nil,
+			"rot_hit": finalRot != nil,
+			"time_ms": finalElapsedTimeMs,
+		})
 		if param.Print {
 			maafocus.Print(ctx, i18n.RenderHTML("maptracker.inference_failed", nil))
 		}
@@ -294,6 +446,28 @@ func (i *MapTrackerInfer) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (
 		InferMode:   string(finalLoc.source),
 		InferTimeMs: finalElapsedTimeMs,
 	}
+	if param.AverageFrames > 1 && param.Mode != INFER_MODE_ROTATION && finalLoc.source != VIRTUAL_HIT {
+		avgX, avgY, n := globalAverageWindow.push(result.MapName, result.X, result.Y, param.AverageFrames, time.Now().UnixMilli())
+		log.Debug().Float64("rawX", result.X).Float64("rawY", result.Y).
+			Float64("avgX", avgX).Float64("avgY", avgY).Int("frames", n).
+			Msg("Averaged location over frame window")
+		result.X, result.Y = avgX, avgY
+	}
+	if param.DebugScores && loc != nil {
+		// Scores of this frame's search, even if the reported location was taken from the time-series state
+		result.MapScores = loc.mapScores
+	}
+	if param.Normalize && param.Mode != INFER_MODE_ROTATION {
+		if fullW, fullH, ok := getMapFullSize(result.MapName); ok {
+			normX, normY := result.X/float64(fullW), result.Y/float64(fullH)
+			result.NormX, result.NormY = &normX, &normY
+		} else {
+			log.Warn().Str("MapName", result.MapName).Msg("Full map size unknown, normalized location skipped")
+		}
+	}
+	if param.TopN > 1 && loc != nil {
+		result.Candidates = loc.candidates[:min(param.TopN, len(loc.candidates))]
+	}
 
 	// Serialize result to JSON
 	detailJSON, err := json.Marshal(result)
@@ -302,7 +476,11 @@ func (i *MapTrackerInfer) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (
 		return nil, false
 	}
 
-	log.Info().Str("InferMode", result.InferMode).
+	logEvent := log.Info()
+	if len(result.Candidates) > 0 {
+		logEvent = logEvent.Interface("Candidates", result.Candidates)
+	}
+	logEvent.Str("InferMode", result.InferMode).
 		Int64("InferTimeMs", result.InferTimeMs).
 		Str("MapName", result.MapName).
 		Float64("X", result.X).Float64("Y", result.Y).
@@ -310,12 +488,23 @@ func (i *MapTrackerInfer) Run(ctx *maa.Context, arg *maa.CustomRecognitionArg) (
 		Float64("LocConf", result.LocConf).
 		Float64("RotConf", result.RotConf).
 		Msg("Map tracking inference completed")
+	events.Match(ctx, "maptracker", "infer", map[string]any{
+		"hit":      true,
+		"map":      result.MapName,
+		"x":        result.X,
+		"y":        result.Y,
+		"rot":      result.Rot,
+		"loc_conf": result.LocConf,
+		"rot_conf": result.RotConf,
+		"mode":     result.InferMode,
+		"time_ms":  result.InferTimeMs,
+	})
 	if param.Print {
 		maafocus.Print(
 			ctx,
 			i18n.RenderHTML("maptracker.inference_finished", map[string]any{
-				"X":       finalLoc.x,
-				"Y":       finalLoc.y,
+				"X":       result.X,
+				"Y":       result.Y,
 				"Rot":     result.Rot,
 				"MapName": finalLoc.mapName,
 			}),
@@ -348,6 +537,28 @@ func (r *MapTrackerInfer) parseParam(paramStr string) (*MapTrackerInferParam, er
 			} else if param.Threshold < 0.0 || param.Threshold > 1.0 {
 				return nil, fmt.Errorf("invalid threshold value: %f", param.Threshold)
 			}
+
+			if param.TopN < 0 {
+				return nil, fmt.Errorf("invalid top_n value: %d", param.TopN)
+			}
+
+			if param.AverageFrames < 0 {
+				return nil, fmt.Errorf("invalid average_frames value: %d", param.AverageFrames)
+			}
+
+			switch param.Mode {
+			case "":
+				param.Mode = mapTrackerInferDefaultParam.Mode
+			case INFER_MODE_BOTH, INFER_MODE_LOCATION, INFER_MODE_ROTATION:
+			default:
+				return nil, fmt.Errorf("invalid mode value: %q", param.Mode)
+			}
+
+			if param.Metric == "" {
+				param.Metric = mapTrackerInferDefaultParam.Metric
+			} else if !param.Metric.Valid() {
+				return nil, fmt.Errorf("invalid metric value: %q", param.Metric)
+			}
 		} else {
 			return nil, fmt.Errorf("failed to unmarshal parameters: %w", err)
 		}
@@ -372,6 +583,51 @@ func isMapNameCoreMatch(mapName1, mapName2 string) bool {
 	return getMapCoreName(mapName1) == getMapCoreName(mapName2)
 }
 
+// cropMiniMap crops the mini-map area from screen, normalized to the Win32 mini-map size
+func cropMiniMap(ctrlType string, screenImg *image.RGBA) *image.RGBA {
+	switch ctrlType {
+	case control.CONTROL_TYPE_ADB:
+		miniMap := minicv.ImageCropSquareByRadius(screenImg, 136, 131, 50)
+		return minicv.ImageScale(miniMap, 0.8)
+	default: // Win32 and others
+		return minicv.ImageCropSquareByRadius(screenImg, 108, 111, 40)
+	}
+}
+
+// isMiniMapVisible is a lightweight check of whether a mini-map is shown at all.
+// A menu or loading screen over the mini-map area is nearly flat, or nearly black/white,
+// while a real mini-map has textured terrain; so per-pixel mean and std must both be in range.
+func isMiniMapVisible(ctrlType string, screenImg *image.RGBA) (bool, float64, float64) {
+	miniMap := cropMiniMap(ctrlType, screenImg)
+	b := miniMap.Bounds()
+	count := float64(b.Dx() * b.Dy() * 3)
+	if count == 0 {
+		return false, 0, 0
+	}
+	stats := minicv.GetImageStats(miniMap)
+	pixelStd := stats.Std / math.Sqrt(count)
+	visible := pixelStd >= mt.MINIMAP_VISIBLE_MIN_STD &&
+		stats.Mean >= mt.MINIMAP_VISIBLE_MIN_MEAN && stats.Mean <= mt.MINIMAP_VISIBLE_MAX_MEAN
+	return visible, stats.Mean, pixelStd
+}
+
+// prepareMiniMap crops and scales the mini-map area from screen, and precomputes the needle statistics
+// and match options for all matches at that scale. Returns false when the mini-map is flat.
+func prepareMiniMap(ctrlType string, screenImg *image.RGBA, scale float64, param *MapTrackerInferParam) (*image.RGBA, minicv.StatsResult, minicv.MatchOptions, bool) {
+	miniMap := minicv.ImageScale(cropMiniMap(ctrlType, screenImg), scale)
+	matchOpts := minicv.MatchOptions{Metric: param.Metric}
+	var miniStats minicv.StatsResult
+	if param.CircularMiniMap {
+		// The in-game mini-map is round; the square corners are UI chrome
+		miniMap = minicv.ImageApplyCircularMask(miniMap)
+		miniStats = minicv.GetMaskedImageStats(miniMap)
+		matchOpts.Masked = true
+	} else {
+		miniStats = minicv.GetImageStats(miniMap)
+	}
+	return miniMap, miniStats, matchOpts, miniStats.Std >= 1e-6
+}
+
 // inferLocation infers the player's location on the map.
 // Returns a raw result with mapName, x/y (map coordinates), conf, source, and elapsedTimeMs.
 func (i *MapTrackerInfer) inferLocation(ctrlType string, screenImg *image.RGBA, mapNameRegex *regexp.Regexp, param *MapTrackerInferParam) *InferLocationRawResult {
@@ -385,26 +641,12 @@ func (i *MapTrackerInfer) inferLocation(ctrlType string, screenImg *image.RGBA,
 		return nil
 	}
 
-	// Crop and scale mini-map area from screen
-	var miniMap *image.RGBA
-	switch ctrlType {
-	case control.CONTROL_TYPE_ADB:
-		miniMap = minicv.ImageCropSquareByRadius(screenImg, 136, 131, 50)
-		miniMap = minicv.ImageScale(miniMap, 0.8)
-	default: // Win32 and others
-		miniMap = minicv.ImageCropSquareByRadius(screenImg, 108, 111, 40)
-	}
-
-	miniMap = minicv.ImageScale(miniMap, scale)
-	miniMapBounds := miniMap.Bounds()
-	miniMapW, miniMapH := miniMapBounds.Dx(), miniMapBounds.Dy()
-	miniMapHalfW, miniMapHalfH := float64(miniMapW)/2.0, float64(miniMapH)/2.0
-
-	// Precompute needle (minimap) statistics for all matches
-	miniStats := minicv.GetImageStats(miniMap)
-	if miniStats.Std < 1e-6 {
+	miniMap, miniStats, matchOpts, ok := prepareMiniMap(ctrlType, screenImg, scale, param)
+	if !ok {
 		return nil
 	}
+	miniMapBounds := miniMap.Bounds()
+	miniMapHalfW, miniMapHalfH := float64(miniMapBounds.Dx())/2.0, float64(miniMapBounds.Dy())/2.0
 
 	// Time-series empirical optimization
 	// If the user is in a stable state (convinced location updated recently, no pending drifts),
@@ -437,6 +679,8 @@ func (i *MapTrackerInfer) inferLocation(ctrlType string, screenImg *image.RGBA,
 		fastBestVal := -1.0
 		fastBestX, fastBestY := 0.0, 0.0
 		fastBestMapName := ""
+		var fastScores []MapScore
+		var fastCandidates []MapCandidate
 
 		for idx := range scaledMaps {
 			mapData := &scaledMaps[idx]
@@ -454,23 +698,30 @@ func (i *MapTrackerInfer) inferLocation(ctrlType string, screenImg *image.RGBA,
 				searchRadius * 2,
 			}
 
-			matchX, matchY, matchVal := minicv.MatchTemplateInArea(mapData.Img, mapData.GetIntegralArray(), miniMap, miniStats, searchArea)
+			matchX, matchY, matchVal := minicv.MatchTemplateInAreaWithOptions(mapData.Img, mapData.GetIntegralArray(), miniMap, miniStats, searchArea, matchOpts)
+			mx := roundTo1Decimal((matchX+miniMapHalfW)/scale + float64(mapData.OffsetX))
+			my := roundTo1Decimal((matchY+miniMapHalfH)/scale + float64(mapData.OffsetY))
+			fastScores = append(fastScores, MapScore{mapData.Name, matchVal})
+			fastCandidates = append(fastCandidates, MapCandidate{mapData.Name, mx, my, matchVal})
 
 			if matchVal > fastBestVal {
 				fastBestVal = matchVal
-				fastBestX = roundTo1Decimal((matchX+miniMapHalfW)/scale + float64(mapData.OffsetX))
-				fastBestY = roundTo1Decimal((matchY+miniMapHalfH)/scale + float64(mapData.OffsetY))
+				fastBestX = mx
+				fastBestY = my
 				fastBestMapName = mapData.Name
 			}
 		}
 
 		if fastBestVal > param.Threshold {
+			sortMapScores(fastScores)
+			sortMapCandidates(fastCandidates)
 			elapsedTimeMs := time.Since(t0).Milliseconds()
 			log.Debug().Float64("conf", fastBestVal).
 				Str("map", fastBestMapName).
 				Float64("X", fastBestX).
 				Float64("Y", fastBestY).
 				Int64("elapsedTimeMs", elapsedTimeMs).
+				Interface("mapScores", fastScores).
 				Msg("Internal fast search location inference completed")
 
 			return &InferLocationRawResult{
@@ -480,12 +731,28 @@ func (i *MapTrackerInfer) inferLocation(ctrlType string, screenImg *image.RGBA,
 				conf:          fastBestVal,
 				source:        FAST_SEARCH_HIT,
 				elapsedTimeMs: elapsedTimeMs,
+				mapScores:     fastScores,
+				candidates:    fastCandidates,
 			}
 		}
 	} else {
 		log.Debug().Msg("Empirical fast search skipped, not in stable state or regex mismatch")
 	}
 
+	if param.CoarseToFine && scale > COARSE_SEARCH_SCALE {
+		// The coarse stage may pick the wrong map, so a below-threshold result also falls back to the full search
+		res := i.inferLocationCoarseToFine(ctrlType, screenImg, scaledMaps, mapNameRegex, param, t0)
+		if res != nil && res.conf > param.Threshold {
+			return res
+		}
+		if res == nil {
+			log.Debug().Msg("Coarse-to-fine search found nothing, fall back to full search")
+		} else {
+			log.Debug().Str("map", res.mapName).Float64("conf", res.conf).Float64("threshold", param.Threshold).
+				Msg("Coarse-to-fine search below threshold, fall back to full search")
+		}
+	}
+
 	// Match against all maps in parallel
 	type mapResult struct {
 		val     float64
@@ -497,6 +764,8 @@ func (i *MapTrackerInfer) inferLocation(ctrlType string, screenImg *image.RGBA,
 	bestX, bestY := 0.0, 0.0
 	bestMapName := ""
 	triedCount := 0
+	var scores []MapScore
+	var candidates []MapCandidate
 
 	// Special case: if there's only one map to check, run it directly to avoid goroutine overhead
 	var singleMapToTry *mt.MapCache
@@ -513,11 +782,13 @@ func (i *MapTrackerInfer) inferLocation(ctrlType string, screenImg *image.RGBA,
 	}
 
 	if singleMapToTry != nil {
-		matchX, matchY, matchVal := minicv.MatchTemplate(singleMapToTry.Img, singleMapToTry.GetIntegralArray(), miniMap, miniStats)
+		matchX, matchY, matchVal := minicv.MatchTemplateWithOptions(singleMapToTry.Img, singleMapToTry.GetIntegralArray(), miniMap, miniStats, matchOpts)
 		bestVal = matchVal
 		bestX = roundTo1Decimal((matchX+miniMapHalfW)/scale + float64(singleMapToTry.OffsetX))
 		bestY = roundTo1Decimal((matchY+miniMapHalfH)/scale + float64(singleMapToTry.OffsetY))
 		bestMapName = singleMapToTry.Name
+		scores = append(scores, MapScore{singleMapToTry.Name, matchVal})
+		candidates = append(candidates, MapCandidate{bestMapName, bestX, bestY, matchVal})
 	} else if triedCount > 1 {
 		resChan := make(chan mapResult, triedCount)
 		var wg sync.WaitGroup
@@ -531,7 +802,7 @@ func (i *MapTrackerInfer) inferLocation(ctrlType string, screenImg *image.RGBA,
 			wg.Add(1)
 			go func(m *mt.MapCache) {
 				defer wg.Done()
-				matchX, matchY, matchVal := minicv.MatchTemplate(m.Img, m.GetIntegralArray(), miniMap, miniStats)
+				matchX, matchY, matchVal := minicv.MatchTemplateWithOptions(m.Img, m.GetIntegralArray(), miniMap, miniStats, matchOpts)
 				mx := roundTo1Decimal((matchX+miniMapHalfW)/scale + float64(m.OffsetX))
 				my := roundTo1Decimal((matchY+miniMapHalfH)/scale + float64(m.OffsetY))
 				resChan <- mapResult{matchVal, mx, my, m.Name}
@@ -544,6 +815,8 @@ func (i *MapTrackerInfer) inferLocation(ctrlType string, screenImg *image.RGBA,
 		}()
 
 		for res := range resChan {
+			scores = append(scores, MapScore{res.mapName, res.val})
+			candidates = append(candidates, MapCandidate{res.mapName, res.x, res.y, res.val})
 			if res.val > bestVal {
 				bestVal = res.val
 				bestX = res.x
@@ -557,6 +830,8 @@ func (i *MapTrackerInfer) inferLocation(ctrlType string, screenImg *image.RGBA,
 		log.Warn().Str("regex", mapNameRegex.String()).Msg("No maps matched the regex")
 	}
 	elapsedTimeMs := time.Since(t0).Milliseconds()
+	sortMapScores(scores)
+	sortMapCandidates(candidates)
 
 	log.Debug().Int("triedMaps", triedCount).
 		Float64("bestConf", bestVal).
@@ -564,6 +839,7 @@ func (i *MapTrackerInfer) inferLocation(ctrlType string, screenImg *image.RGBA,
 		Float64("X", bestX).
 		Float64("Y", bestY).
 		Int64("elapsedTimeMs", elapsedTimeMs).
+		Interface("mapScores", scores).
 		Msg("Internal location inference completed")
 
 	return &InferLocationRawResult{
@@ -573,12 +849,148 @@ func (i *MapTrackerInfer) inferLocation(ctrlType string, screenImg *image.RGBA,
 		conf:          bestVal,
 		source:        FULL_SEARCH_HIT,
 		elapsedTimeMs: time.Since(t0).Milliseconds(),
+		mapScores:     scores,
+		candidates:    candidates,
+	}
+}
+
+// inferLocationCoarseToFine matches all maps at COARSE_SEARCH_SCALE to find the candidate map and region,
+// then re-matches only that map at full precision within COARSE_WINDOW_RADIUS around the coarse peak.
+func (i *MapTrackerInfer) inferLocationCoarseToFine(ctrlType string, screenImg *image.RGBA, scaledMaps []mt.MapCache, mapNameRegex *regexp.Regexp, param *MapTrackerInferParam, t0 time.Time) *InferLocationRawResult {
+	tCoarse := time.Now()
+	coarseMaps := i.getScaledMaps(COARSE_SEARCH_SCALE)
+	coarseMini, coarseStats, coarseOpts, ok := prepareMiniMap(ctrlType, screenImg, COARSE_SEARCH_SCALE, param)
+	if !ok || len(coarseMaps) != len(scaledMaps) {
+		return nil
+	}
+	coarseHalfW, coarseHalfH := float64(coarseMini.Bounds().Dx())/2.0, float64(coarseMini.Bounds().Dy())/2.0
+
+	// Coarse stage: all maps in parallel at a very low scale
+	type coarseResult struct {
+		idx  int
+		x, y float64
+		val  float64
+	}
+	resChan := make(chan coarseResult, len(coarseMaps))
+	var wg sync.WaitGroup
+	for idx := range coarseMaps {
+		if !mapNameRegex.MatchString(coarseMaps[idx].Name) {
+			continue
+		}
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			m := &coarseMaps[idx]
+			matchX, matchY, matchVal := minicv.MatchTemplateWithOptions(m.Img, m.GetIntegralArray(), coarseMini, coarseStats, coarseOpts)
+			resChan <- coarseResult{idx, matchX + coarseHalfW, matchY + coarseHalfH, matchVal}
+		}(idx)
+	}
+	go func() {
+		wg.Wait()
+		close(resChan)
+	}()
+
+	best := coarseResult{idx: -1, val: -1.0}
+	var scores []MapScore
+	var candidates []MapCandidate
+	for res := range resChan {
+		m := &coarseMaps[res.idx]
+		scores = append(scores, MapScore{m.Name, res.val})
+		candidates = append(candidates, MapCandidate{
+			m.Name,
+			roundTo1Decimal(res.x/COARSE_SEARCH_SCALE + float64(m.OffsetX)),
+			roundTo1Decimal(res.y/COARSE_SEARCH_SCALE + float64(m.OffsetY)),
+			res.val,
+		})
+		if res.val > best.val {
+			best = res
+		}
+	}
+	if best.idx < 0 {
+		return nil
+	}
+	coarseTimeMs := time.Since(tCoarse).Milliseconds()
+
+	// Fine stage: only the best map, in a window around the coarse peak
+	tFine := time.Now()
+	scale := param.Precision
+	fineMap := &scaledMaps[best.idx]
+	fineMini, fineStats, fineOpts, ok := prepareMiniMap(ctrlType, screenImg, scale, param)
+	if !ok || fineMap.Name != coarseMaps[best.idx].Name {
+		return nil
+	}
+	fineHalfW, fineHalfH := float64(fineMini.Bounds().Dx())/2.0, float64(fineMini.Bounds().Dy())/2.0
+	centerX := int(math.Round(best.x / COARSE_SEARCH_SCALE * scale))
+	centerY := int(math.Round(best.y / COARSE_SEARCH_SCALE * scale))
+	radius := max(int(float64(COARSE_WINDOW_RADIUS)*scale), 1)
+	searchArea := [4]int{centerX - radius, centerY - radius, radius * 2, radius * 2}
+	matchX, matchY, matchVal := minicv.MatchTemplateInAreaWithOptions(fineMap.Img, fineMap.GetIntegralArray(), fineMini, fineStats, searchArea, fineOpts)
+	x := roundTo1Decimal((matchX+fineHalfW)/scale + float64(fineMap.OffsetX))
+	y := roundTo1Decimal((matchY+fineHalfH)/scale + float64(fineMap.OffsetY))
+	fineTimeMs := time.Since(tFine).Milliseconds()
+
+	// Report the refined location and confidence for the chosen map; the others keep their coarse results
+	for idx := range candidates {
+		if candidates[idx].MapName == fineMap.Name {
+			candidates[idx] = MapCandidate{fineMap.Name, x, y, matchVal}
+		}
+	}
+	for idx := range scores {
+		if scores[idx].MapName == fineMap.Name {
+			scores[idx].Score = matchVal
+		}
+	}
+	sortMapScores(scores)
+	sortMapCandidates(candidates)
+
+	elapsedTimeMs := time.Since(t0).Milliseconds()
+	log.Debug().Int("triedMaps", len(scores)).
+		Float64("coarseConf", best.val).
+		Float64("bestConf", matchVal).
+		Str("bestMap", fineMap.Name).
+		Float64("X", x).
+		Float64("Y", y).
+		Int64("coarseTimeMs", coarseTimeMs).
+		Int64("fineTimeMs", fineTimeMs).
+		Int64("elapsedTimeMs", elapsedTimeMs).
+		Interface("mapScores", scores).
+		Msg("Internal coarse-to-fine location inference completed")
+
+	return &InferLocationRawResult{
+		mapName:       fineMap.Name,
+		x:             x,
+		y:             y,
+		conf:          matchVal,
+		source:        FULL_SEARCH_HIT,
+		elapsedTimeMs: elapsedTimeMs,
+		mapScores:     scores,
+		candidates:    candidates,
 	}
 }
 
+// sortMapScores sorts scores in descending order, tie-broken by map name for stable output
+func sortMapScores(scores []MapScore) {
+	sort.Slice(scores, func(a, b int) bool {
+		if scores[a].Score != scores[b].Score {
+			return scores[a].Score > scores[b].Score
+		}
+		return scores[a].MapName < scores[b].MapName
+	})
+}
+
+// sortMapCandidates sorts candidates in descending order of confidence, tie-broken by map name
+func sortMapCandidates(candidates []MapCandidate) {
+	sort.Slice(candidates, func(a, b int) bool {
+		if candidates[a].LocConf != candidates[b].LocConf {
+			return candidates[a].LocConf > candidates[b].LocConf
+		}
+		return candidates[a].MapName < candidates[b].MapName
+	})
+}
+
 // inferRotation infers the player's rotation angle
 // Returns (angle, confidence)
-func (i *MapTrackerInfer) inferRotation(ctrlType string, screenImg *image.RGBA, rotStep int) *InferRotationRawResult {
+func (i *MapTrackerInfer) inferRotation(ctrlType string, screenImg *image.RGBA, rotStep int, refine bool) *InferRotationRawResult {
 	t0 := time.Now()
 
 	pointerTemplate, err := mt.Resource.PointerTemplateLoader.Get()
@@ -598,15 +1010,79 @@ func (i *MapTrackerInfer) inferRotation(ctrlType string, screenImg *image.RGBA,
 	}
 
 	// Try all rotation angles in parallel
+	angles := make([]int, 0, 360/rotStep+1)
+	for angle := 0; angle < 360; angle += rotStep {
+		angles = append(angles, angle)
+	}
+	confs := matchRotations(patch, pointerTemplate, angles)
+
+	bestAngle := 0
+	maxVal := -1.0
+	for angle, conf := range confs {
+		if conf > maxVal || (conf == maxVal && angle < bestAngle) {
+			maxVal = conf
+			bestAngle = angle
+		}
+	}
+
+	// Refine around the best step in 1-degree increments, then fit a parabola over the best angle and its neighbors
+	refinedAngle := float64(bestAngle)
+	if refine && rotStep > 1 {
+		coarseAngle := bestAngle
+		fineAngles := make([]int, 0, 2*rotStep)
+		for d := 1 - rotStep; d < rotStep; d++ {
+			if d != 0 {
+				fineAngles = append(fineAngles, (coarseAngle+d+360)%360)
+			}
+		}
+		for a, conf := range matchRotations(patch, pointerTemplate, fineAngles) {
+			confs[a] = conf
+			if conf > maxVal {
+				maxVal = conf
+				bestAngle = a
+			}
+		}
+		refinedAngle = float64(bestAngle)
+		prev, hasPrev := confs[(bestAngle+359)%360]
+		next, hasNext := confs[(bestAngle+1)%360]
+		if denom := prev - 2*maxVal + next; hasPrev && hasNext && denom < 0 {
+			offset := max(-0.5, min(0.5, 0.5*(prev-next)/denom))
+			refinedAngle += offset
+			maxVal -= 0.25 * (prev - next) * offset
+		}
+		log.Debug().Int("coarseAngle", (360-coarseAngle)%360).Float64("refinedAngle", math.Mod(720-refinedAngle, 360)).
+			Msg("Rotation refined")
+	}
+
+	// Convert to clockwise angle
+	bestAngle = int(math.Round(math.Mod(720-refinedAngle, 360))) % 360
+	elapsedTimeMs := time.Since(t0).Milliseconds()
+
+	log.Debug().
+		Float64("bestConf", maxVal).
+		Int("bestAngle", bestAngle).
+		Int64("elapsedTimeMs", elapsedTimeMs).
+		Msg("Internal rotation inference completed")
+
+	return &InferRotationRawResult{
+		rot:           bestAngle,
+		conf:          maxVal,
+		elapsedTimeMs: time.Since(t0).Milliseconds(),
+	}
+}
+
+// matchRotations matches the patch rotated by each angle (counter-clockwise degrees) against the pointer template in parallel.
+// Returns the confidence of each angle.
+func matchRotations(patch *image.RGBA, pointerTemplate *minicv.Template, angles []int) map[int]float64 {
 	type result struct {
 		angle int
 		conf  float64
 	}
 
-	resChan := make(chan result, 360/rotStep+1)
+	resChan := make(chan result, len(angles))
 	var wg sync.WaitGroup
 
-	for angle := 0; angle < 360; angle += rotStep {
+	for _, angle := range angles {
 		wg.Add(1)
 		go func(a int) {
 			defer wg.Done()
@@ -626,43 +1102,36 @@ func (i *MapTrackerInfer) inferRotation(ctrlType string, screenImg *image.RGBA,
 		close(resChan)
 	}()
 
-	bestAngle := 0
-	maxVal := -1.0
+	confs := make(map[int]float64, len(angles))
 	for res := range resChan {
-		if res.conf > maxVal {
-			maxVal = res.conf
-			bestAngle = res.angle
-		}
+		confs[res.angle] = res.conf
 	}
+	return confs
+}
 
-	// Convert to clockwise angle
-	bestAngle = (360 - bestAngle) % 360
-	elapsedTimeMs := time.Since(t0).Milliseconds()
-
-	log.Debug().
-		Float64("bestConf", maxVal).
-		Int("bestAngle", bestAngle).
-		Int64("elapsedTimeMs", elapsedTimeMs).
-		Msg("Internal rotation inference completed")
-
-	return &InferRotationRawResult{
-		rot:           bestAngle,
-		conf:          maxVal,
-		elapsedTimeMs: time.Since(t0).Milliseconds(),
+// getMapFullSize returns the full (pre-crop) size of a loaded map
+func getMapFullSize(mapName string) (int, int, bool) {
+	for _, m := range mt.Resource.RawMaps {
+		if m.Name == mapName && m.FullW > 0 && m.FullH > 0 {
+			return m.FullW, m.FullH, true
+		}
 	}
+	return 0, 0, false
 }
 
 func roundTo1Decimal(value float64) float64 {
 	return math.Round(value*10.0) / 10.0
 }
 
-// getScaledMaps recomputes scaled map cache for the requested scale.
+// getScaledMaps returns the maps scaled to the requested scale, from the LRU cache when present.
 func (i *MapTrackerInfer) getScaledMaps(scale float64) []mt.MapCache {
 	i.scaledMapsMu.Lock()
 	defer i.scaledMapsMu.Unlock()
 
-	if i.scaledMaps != nil && math.Abs(i.scaledScale-scale) < 1e-6 {
-		return i.scaledMaps
+	key := math.Round(scale*1e6) / 1e6
+	if cached, ok := i.scaledMaps[key]; ok {
+		i.touchScaledMaps(key)
+		return cached
 	}
 
 	newScaled := make([]mt.MapCache, 0, len(mt.Resource.RawMaps))
@@ -676,7 +1145,26 @@ func (i *MapTrackerInfer) getScaledMaps(scale float64) []mt.MapCache {
 		})
 	}
 
-	i.scaledMaps = newScaled
-	i.scaledScale = scale
-	return i.scaledMaps
+	if i.scaledMaps == nil {
+		i.scaledMaps = make(map[float64][]mt.MapCache, SCALED_MAPS_CACHE_SIZE)
+	}
+	if len(i.scaledMapsOrder) >= SCALED_MAPS_CACHE_SIZE {
+		evicted := i.scaledMapsOrder[0]
+		i.scaledMapsOrder = i.scaledMapsOrder[1:]
+		delete(i.scaledMaps, evicted)
+		log.Debug().Float64("scale", evicted).Msg("Evicted scaled maps from cache")
+	}
+	i.scaledMaps[key] = newScaled
+	i.scaledMapsOrder = append(i.scaledMapsOrder, key)
+	return newScaled
+}
+
+// touchScaledMaps marks the cached precision as most recently used. Caller must hold scaledMapsMu.
+func (i *MapTrackerInfer) touchScaledMaps(key float64) {
+	for idx, k := range i.scaledMapsOrder {
+		if k == key {
+			i.scaledMapsOrder = append(append(i.scaledMapsOrder[:idx:idx], i.scaledMapsOrder[idx+1:]...), key)
+			return
+		}
+	}
 }
@@ -46,6 +46,13 @@ const (
 	ROTATION_MIN_SPEED     = 1.0
 )
 
+// Mini-map visibility check configuration (per-pixel intensity statistics of the mini-map crop)
+const (
+	MINIMAP_VISIBLE_MIN_STD  = 12.0
+	MINIMAP_VISIBLE_MIN_MEAN = 20.0
+	MINIMAP_VISIBLE_MAX_MEAN = 235.0
+)
+
 // Misc
 const (
 	RAW_MAP_BBOX_EXPAND_PX           = 40 // 2x minimap radius
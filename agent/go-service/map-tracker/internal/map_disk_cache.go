@@ -0,0 +1,142 @@
+// Copyright (c) 2026 Harry Huang
+package maptracker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// On-disk cache of decoded and cropped map images, stored as <name>.intcache under the user cache
+// directory (one subdirectory per map directory), so the resource directory is never written to.
+// A cache is valid only when the PNG size, modification time and requested crop rectangle all match,
+// so editing a PNG or its bbox data invalidates it automatically.
+const (
+	MAP_DISK_CACHE_EXT     = ".intcache"
+	mapDiskCacheMagic      = "MTIC"
+	mapDiskCacheVersion    = 2
+	mapDiskCacheMaxPixels  = 1 << 28
+	mapDiskCacheBufferSize = 1 << 20
+	mapDiskCacheDirName    = "MaaEnd/map-tracker"
+)
+
+// mapDiskCacheDir returns the cache directory for maps under mapDir, creating it if needed.
+// Returns false when no user cache directory is available; callers then skip the disk cache.
+func mapDiskCacheDir(mapDir string) (string, bool) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return "", false
+	}
+	if abs, err := filepath.Abs(mapDir); err == nil {
+		mapDir = abs
+	}
+	h := fnv.New64a()
+	h.Write([]byte(mapDir))
+	dir := filepath.Join(base, mapDiskCacheDirName, fmt.Sprintf("%016x", h.Sum64()))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", false
+	}
+	return dir, true
+}
+
+type mapDiskCacheHeader struct {
+	Magic    [4]byte
+	Version  uint32
+	SrcSize  int64
+	SrcMtime int64
+	Crop     [4]int32 // Requested crop rectangle (min x, min y, max x, max y); zero when not cropped
+	OffsetX  int32
+	OffsetY  int32
+	W        int32
+	H        int32
+	FullW    int32
+	FullH    int32
+}
+
+func newMapDiskCacheHeader(src os.FileInfo, crop image.Rectangle) mapDiskCacheHeader {
+	h := mapDiskCacheHeader{
+		Version:  mapDiskCacheVersion,
+		SrcSize:  src.Size(),
+		SrcMtime: src.ModTime().UnixNano(),
+		Crop:     [4]int32{int32(crop.Min.X), int32(crop.Min.Y), int32(crop.Max.X), int32(crop.Max.Y)},
+	}
+	copy(h.Magic[:], mapDiskCacheMagic)
+	return h
+}
+
+// loadMapDiskCache reads a cached map (without Name). Returns false when the cache is missing, stale or corrupted.
+func loadMapDiskCache(cachePath string, src os.FileInfo, crop image.Rectangle) (*MapCache, bool) {
+	file, err := os.Open(cachePath)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	r := bufio.NewReaderSize(file, mapDiskCacheBufferSize)
+	var header mapDiskCacheHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, false
+	}
+	expected := newMapDiskCacheHeader(src, crop)
+	if header.Magic != expected.Magic || header.Version != expected.Version ||
+		header.SrcSize != expected.SrcSize || header.SrcMtime != expected.SrcMtime || header.Crop != expected.Crop {
+		return nil, false
+	}
+	if header.W <= 0 || header.H <= 0 || int64(header.W)*int64(header.H) > mapDiskCacheMaxPixels {
+		return nil, false
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, int(header.W), int(header.H)))
+	if _, err := io.ReadFull(r, img.Pix); err != nil {
+		return nil, false
+	}
+	return &MapCache{
+		Img:     img,
+		OffsetX: int(header.OffsetX),
+		OffsetY: int(header.OffsetY),
+		FullW:   int(header.FullW),
+		FullH:   int(header.FullH),
+	}, true
+}
+
+// saveMapDiskCache writes a map image cache atomically (write to a temp file, then rename).
+func saveMapDiskCache(cachePath string, src os.FileInfo, crop image.Rectangle, m *MapCache) error {
+	img := m.Img
+	b := img.Bounds()
+	header := newMapDiskCacheHeader(src, crop)
+	header.OffsetX, header.OffsetY = int32(m.OffsetX), int32(m.OffsetY)
+	header.W, header.H = int32(b.Dx()), int32(b.Dy())
+	header.FullW, header.FullH = int32(m.FullW), int32(m.FullH)
+
+	tmpPath := cachePath + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	w := bufio.NewWriterSize(file, mapDiskCacheBufferSize)
+	err = binary.Write(w, binary.LittleEndian, header)
+	for y := b.Min.Y; err == nil && y < b.Max.Y; y++ {
+		off := img.PixOffset(b.Min.X, y)
+		_, err = w.Write(img.Pix[off : off+b.Dx()*4])
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := os.Rename(tmpPath, cachePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename cache file: %w", err)
+	}
+	return nil
+}
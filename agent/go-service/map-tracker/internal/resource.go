@@ -6,6 +6,7 @@ import (
 	"image"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 
@@ -18,13 +19,13 @@ import (
 var (
 	Resource = &MapTrackerResource{
 		PointerTemplateLoader: minicv.NewTemplateLoaderOfDynamicPath(
-			func() string { return resource.FindResource("resource/image/MapTracker/pointer.png") },
+			func() string { return resource.Find("resource/image/MapTracker/pointer.png") },
 		),
 		ZoomInTemplate: minicv.NewTemplateLoaderOfDynamicPath(
-			func() string { return resource.FindResource("resource/image/MapTracker/BigMapZoomIn.png") },
+			func() string { return resource.Find("resource/image/MapTracker/BigMapZoomIn.png") },
 		),
 		ZoomOutTemplate: minicv.NewTemplateLoaderOfDynamicPath(
-			func() string { return resource.FindResource("resource/image/MapTracker/BigMapZoomOut.png") },
+			func() string { return resource.Find("resource/image/MapTracker/BigMapZoomOut.png") },
 		),
 	}
 )
@@ -41,6 +42,11 @@ type MapTrackerResource struct {
 	RawMaps     []MapCache
 	RawMapsErr  error
 
+	// rawMapsPreload is the preload regex the maps were loaded with ("" means all maps).
+	rawMapsPreload string
+	// preloadMismatchWarned records preload regexes already warned about, so each is logged once.
+	preloadMismatchWarned sync.Map
+
 	IntegralCacheMu sync.Mutex
 
 	PointerTemplateLoader *minicv.TemplateLoader
@@ -54,6 +60,8 @@ type MapCache struct {
 	Img     *image.RGBA
 	OffsetX int
 	OffsetY int
+	FullW   int // Width of the full map image before cropping
+	FullH   int // Height of the full map image before cropping
 
 	cachedIntegralArray *minicv.IntegralArray
 }
@@ -71,20 +79,38 @@ func (m *MapCache) GetIntegralArray() minicv.IntegralArray {
 }
 
 // InitRawMaps initializes global raw maps cache exactly once.
-func (r *MapTrackerResource) InitRawMaps(ctx *maa.Context) {
+// If preload is non-nil on that first call, only maps whose names match it are loaded; later calls
+// with a different regex keep the loaded set and log a warning once per regex.
+func (r *MapTrackerResource) InitRawMaps(ctx *maa.Context, preload *regexp.Regexp) {
+	requested := ""
+	if preload != nil {
+		requested = preload.String()
+	}
 	r.RawMapsOnce.Do(func() {
-		r.RawMaps, r.RawMapsErr = r.LoadMaps()
+		r.rawMapsPreload = requested
+		r.RawMaps, r.RawMapsErr = r.LoadMaps(preload)
 		if r.RawMapsErr != nil {
 			log.Error().Err(r.RawMapsErr).Msg("Failed to load maps")
 		} else {
 			log.Info().Int("mapsCount", len(r.RawMaps)).Msg("Map images loaded")
 		}
 	})
+
+	// Maps are loaded only once, so a later call with a different regex keeps the first caller's set
+	if requested != r.rawMapsPreload {
+		if _, warned := r.preloadMismatchWarned.LoadOrStore(requested, struct{}{}); !warned {
+			log.Warn().
+				Str("requested", requested).
+				Str("loaded", r.rawMapsPreload).
+				Msg("preload_regex differs from the one maps were loaded with; ignored")
+		}
+	}
 }
 
-// LoadMaps loads all map images from the resource directory and crops them when map bbox data exists.
-func (r *MapTrackerResource) LoadMaps() ([]MapCache, error) {
-	mapDir := resource.FindResource(MAP_DIR)
+// LoadMaps loads map images from the resource directory and crops them when map bbox data exists.
+// When filter is non-nil, maps whose names do not match it are skipped before decoding.
+func (r *MapTrackerResource) LoadMaps(filter *regexp.Regexp) ([]MapCache, error) {
+	mapDir := resource.Find(MAP_DIR)
 	if mapDir == "" {
 		return nil, fmt.Errorf("map directory not found (searched in cache and standard locations)")
 	}
@@ -114,13 +140,22 @@ func (r *MapTrackerResource) LoadMaps() ([]MapCache, error) {
 		if !strings.HasSuffix(filename, ".png") {
 			continue
 		}
+		if filter != nil && !filter.MatchString(strings.TrimSuffix(filename, ".png")) {
+			continue
+		}
 		files = append(files, indexedFile{idx: len(files), filename: filename})
 	}
 
 	type result struct {
-		idx int
-		m   MapCache
-		ok  bool
+		idx    int
+		m      MapCache
+		ok     bool
+		cached bool
+	}
+
+	cacheDir, cacheOK := mapDiskCacheDir(mapDir)
+	if !cacheOK {
+		log.Debug().Str("mapDir", mapDir).Msg("No user cache directory, map disk cache disabled")
 	}
 
 	results := make([]MapCache, len(files))
@@ -138,6 +173,34 @@ func (r *MapTrackerResource) LoadMaps() ([]MapCache, error) {
 
 			filename := item.filename
 			imgPath := filepath.Join(mapDir, filename)
+			name := strings.TrimSuffix(filename, ".png")
+
+			var cropRect image.Rectangle
+			if bboxRect, ok := rectList[name]; ok && len(bboxRect) == 4 {
+				expand := RAW_MAP_BBOX_EXPAND_PX
+				cropRect = image.Rect(bboxRect[0]-expand, bboxRect[1]-expand, bboxRect[2]+expand, bboxRect[3]+expand)
+			}
+
+			// Try the on-disk cache first to skip PNG decoding
+			cachePath := filepath.Join(cacheDir, name+MAP_DISK_CACHE_EXT)
+			srcInfo, err := os.Stat(imgPath)
+			if err != nil {
+				log.Warn().Err(err).Str("path", imgPath).Msg("Failed to stat map image")
+				return
+			}
+			if cacheOK {
+				if cached, ok := loadMapDiskCache(cachePath, srcInfo, cropRect); ok {
+					cached.Name = name
+					resChan <- result{
+						idx:    item.idx,
+						m:      *cached,
+						ok:     true,
+						cached: true,
+					}
+					return
+				}
+			}
+
 			file, err := os.Open(imgPath)
 			if err != nil {
 				log.Warn().Err(err).Str("path", imgPath).Msg("Failed to open map image")
@@ -151,33 +214,39 @@ func (r *MapTrackerResource) LoadMaps() ([]MapCache, error) {
 				return
 			}
 
-			name := strings.TrimSuffix(filename, ".png")
 			fullRGBA := minicv.ImageConvertRGBA(img)
 
 			imgRGBA := fullRGBA
 			offsetX, offsetY := 0, 0
 
-			if bboxRect, ok := rectList[name]; ok && len(bboxRect) == 4 {
-				rect := image.Rect(bboxRect[0], bboxRect[1], bboxRect[2], bboxRect[3])
-				expand := RAW_MAP_BBOX_EXPAND_PX
-				rect = image.Rect(rect.Min.X-expand, rect.Min.Y-expand, rect.Max.X+expand, rect.Max.Y+expand)
-
-				clipped := rect.Intersect(fullRGBA.Bounds())
-				imgRGBA = minicv.ImageCropRect(fullRGBA, rect)
+			if !cropRect.Empty() {
+				clipped := cropRect.Intersect(fullRGBA.Bounds())
+				imgRGBA = minicv.ImageCropRect(fullRGBA, cropRect)
 				if !clipped.Empty() {
 					offsetX, offsetY = clipped.Min.X, clipped.Min.Y
 				}
 			}
 
+			m := MapCache{
+				Name:    name,
+				Img:     imgRGBA,
+				OffsetX: offsetX,
+				OffsetY: offsetY,
+				FullW:   fullRGBA.Bounds().Dx(),
+				FullH:   fullRGBA.Bounds().Dy(),
+			}
+
+			// The cache is an optimization only; failing to write it is not an error
+			if cacheOK {
+				if err := saveMapDiskCache(cachePath, srcInfo, cropRect, &m); err != nil {
+					log.Debug().Err(err).Str("path", cachePath).Msg("Failed to write map disk cache")
+				}
+			}
+
 			resChan <- result{
 				idx: item.idx,
-				m: MapCache{
-					Name:    name,
-					Img:     imgRGBA,
-					OffsetX: offsetX,
-					OffsetY: offsetY,
-				},
-				ok: true,
+				m:   m,
+				ok:  true,
 			}
 		}(f)
 	}
@@ -187,13 +256,18 @@ func (r *MapTrackerResource) LoadMaps() ([]MapCache, error) {
 		close(resChan)
 	}()
 
+	cachedCount := 0
 	for res := range resChan {
 		if !res.ok {
 			continue
 		}
 		results[res.idx] = res.m
 		okFlags[res.idx] = true
+		if res.cached {
+			cachedCount++
+		}
 	}
+	log.Debug().Int("cachedCount", cachedCount).Int("filesCount", len(files)).Msg("Map images loaded from disk cache")
 
 	maps := make([]MapCache, 0, len(files))
 	for idx := range results {
@@ -203,8 +277,14 @@ func (r *MapTrackerResource) LoadMaps() ([]MapCache, error) {
 	}
 
 	if len(maps) == 0 {
+		if filter != nil {
+			return nil, fmt.Errorf("no valid map images matching %q found in %s", filter.String(), mapDir)
+		}
 		return nil, fmt.Errorf("no valid map images found in %s", mapDir)
 	}
+	if filter != nil {
+		log.Info().Str("preloadRegex", filter.String()).Int("mapsCount", len(maps)).Msg("Map images restricted by preload regex")
+	}
 
 	return maps, nil
 }
@@ -58,6 +58,9 @@ type MapTrackerMoveParam struct {
 	StuckTimeout int64 `json:"stuck_timeout,omitempty"`
 	// MapNameMatchRule is the regex template used to match recognized map names. Use %s as map_name placeholder.
 	MapNameMatchRule string `json:"map_name_match_rule,omitempty"`
+
+	// softFail makes timeouts return false without stopping the tasker (set by composite actions).
+	softFail bool
 }
 
 const (
@@ -108,7 +111,11 @@ func (a *MapTrackerMove) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
 		log.Error().Err(err).Msg("Failed to parse parameters for MapTrackerMove")
 		return false
 	}
+	return a.navigate(ctx, param)
+}
 
+// navigate walks the player along param.Path; param must already be validated by parseParam.
+func (a *MapTrackerMove) navigate(ctx *maa.Context, param *MapTrackerMoveParam) bool {
 	ctrl := ctx.GetTasker().GetController()
 	ca, err := control.NewControlAdaptor(ctx, ctrl, mt.WORK_W, mt.WORK_H)
 	if err != nil {
@@ -201,7 +208,7 @@ func (a *MapTrackerMove) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
 					break
 				} else {
 					log.Error().Msg("Arrival timeout, stopping task")
-					doEmergencyStop(ca, param.NoPrint)
+					a.abort(ca, param)
 					return false
 				}
 			}
@@ -285,7 +292,7 @@ func (a *MapTrackerMove) Run(ctx *maa.Context, arg *maa.CustomActionArg) bool {
 				deltaLocationMs := loopStartTime.Sub(prevLocationTime).Milliseconds()
 				if deltaLocationMs > param.StuckTimeout {
 					log.Error().Msg("Stuck for too long, stopping task")
-					doEmergencyStop(ca, param.NoPrint)
+					a.abort(ca, param)
 					return false
 				}
 				if deltaLocationMs > param.StuckThreshold {
@@ -483,6 +490,16 @@ func (a *MapTrackerMove) parseParam(paramStr string) (*MapTrackerMoveParam, erro
 	return &param, nil
 }
 
+// abort ends a failed navigation: an emergency stop by default, or just stopping the player in soft-fail mode.
+func (a *MapTrackerMove) abort(ca control.ControlAdaptor, param *MapTrackerMoveParam) {
+	if param.softFail {
+		log.Warn().Msg("Navigation failed, stopping player without stopping task")
+		ca.PlayerStop()
+		return
+	}
+	doEmergencyStop(ca, param.NoPrint)
+}
+
 func doEmergencyStop(ca control.ControlAdaptor, noPrint bool) {
 	log.Warn().Msg("Emergency stop triggered")
 	if !noPrint {
@@ -727,7 +744,7 @@ func buildNavigationPreviewDataURL(path [][2]float64, targetIndex int, mapName s
 }
 
 func getCachedPreviewMapRGBA(mapName string) (*image.RGBA, error) {
-	mapPath := resource.FindResource(filepath.ToSlash(filepath.Join(mt.MAP_DIR, mapName+".png")))
+	mapPath := resource.Find(filepath.ToSlash(filepath.Join(mt.MAP_DIR, mapName+".png")))
 	if mapPath == "" {
 		return nil, fmt.Errorf("map image not found")
 	}
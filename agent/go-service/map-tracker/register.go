@@ -10,6 +10,9 @@ func Register() {
 	maa.AgentServerRegisterCustomRecognition("MapTrackerInfer", &MapTrackerInfer{})
 	maa.AgentServerRegisterCustomRecognition("MapTrackerBigMapInfer", &MapTrackerBigMapInfer{})
 	maa.AgentServerRegisterCustomRecognition("MapTrackerAssertLocation", &MapTrackerAssertLocation{})
+	maa.AgentServerRegisterCustomRecognition("MapTrackerAssertRotation", &MapTrackerAssertRotation{})
 	maa.AgentServerRegisterCustomAction("MapTrackerMove", &MapTrackerMove{})
+	maa.AgentServerRegisterCustomAction("MapTrackerFollowPath", &MapTrackerFollowPath{})
+	maa.AgentServerRegisterCustomAction("MapTrackerFaceHeading", &MapTrackerFaceHeading{})
 	maa.AgentServerRegisterCustomAction("MapTrackerBigMapPick", &MapTrackerBigMapPick{})
 }
@@ -0,0 +1,153 @@
+// Package debugimg saves debug screenshots under a shared root with per-category
+// rotation and rate limiting, so that a flapping condition cannot fill the disk.
+package debugimg
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	EnvRoot          = "MAAEND_DEBUG_IMAGE_ROOT"
+	EnvMaxFiles      = "MAAEND_DEBUG_IMAGE_MAX_FILES"
+	EnvMinIntervalMs = "MAAEND_DEBUG_IMAGE_MIN_INTERVAL_MS"
+)
+
+// Settings controls where and how often debug images are written.
+type Settings struct {
+	// Root is the directory under which each category gets its own sub directory.
+	Root string
+	// MaxFiles is the maximum number of images kept per category, 0 means unlimited.
+	MaxFiles int
+	// MinInterval is the minimum interval between two writes of the same category, 0 means unlimited.
+	MinInterval time.Duration
+}
+
+// DefaultSettings returns the built-in settings overridden by environment variables.
+func DefaultSettings() Settings {
+	s := Settings{
+		Root:        "debug",
+		MaxFiles:    50,
+		MinInterval: time.Second,
+	}
+	if v := strings.TrimSpace(os.Getenv(EnvRoot)); v != "" {
+		s.Root = v
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv(EnvMaxFiles))); err == nil && v >= 0 {
+		s.MaxFiles = v
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv(EnvMinIntervalMs))); err == nil && v >= 0 {
+		s.MinInterval = time.Duration(v) * time.Millisecond
+	}
+	return s
+}
+
+var (
+	mu        sync.Mutex
+	settings  = DefaultSettings()
+	lastSaved = map[string]time.Time{}
+)
+
+// Configure replaces the current settings.
+func Configure(s Settings) {
+	mu.Lock()
+	defer mu.Unlock()
+	settings = s
+}
+
+// Dir returns the directory of category under the current root, for debug files that are not images.
+func Dir(category string) string {
+	mu.Lock()
+	defer mu.Unlock()
+	return filepath.Join(settings.Root, category)
+}
+
+// Save writes img to <root>/<category>/<reason>_<timestamp>.png and returns the written path.
+//
+// It returns an empty path without error when the write is skipped by rate limiting.
+// After writing, the oldest images of the category are removed so that at most MaxFiles remain.
+func Save(img image.Image, category, reason string) (string, error) {
+	if img == nil {
+		return "", fmt.Errorf("debug image is nil")
+	}
+
+	mu.Lock()
+	s := settings
+	now := time.Now()
+	if s.MinInterval > 0 {
+		if last, ok := lastSaved[category]; ok && now.Sub(last) < s.MinInterval {
+			mu.Unlock()
+			log.Debug().Str("category", category).Str("reason", reason).Msg("Debug image rate limited, skip")
+			return "", nil
+		}
+	}
+	lastSaved[category] = now
+	mu.Unlock()
+
+	dir := filepath.Join(s.Root, category)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create debug image dir: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s_%s.png", reason, now.Format("20060102_150405.000")))
+	f, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to create debug image: %w", err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to encode debug image: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to close debug image: %w", err)
+	}
+
+	if s.MaxFiles > 0 {
+		rotate(dir, s.MaxFiles)
+	}
+	return path, nil
+}
+
+// rotate removes the oldest png files in dir until at most maxFiles remain.
+func rotate(dir string, maxFiles int) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Debug().Err(err).Str("dir", dir).Msg("Failed to list debug image dir for rotation")
+		return
+	}
+	type file struct {
+		path    string
+		modTime time.Time
+	}
+	files := make([]file, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".png" {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{path: filepath.Join(dir, entry.Name()), modTime: info.ModTime()})
+	}
+	if len(files) <= maxFiles {
+		return
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+	for _, f := range files[:len(files)-maxFiles] {
+		if err := os.Remove(f.path); err != nil {
+			log.Debug().Err(err).Str("path", f.path).Msg("Failed to remove old debug image")
+		}
+	}
+}
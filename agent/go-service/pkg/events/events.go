@@ -0,0 +1,107 @@
+// Package events provides a structured, machine-readable event stream shared by all
+// modules. Modules publish events alongside their existing human-readable output
+// (logs, maafocus, MXU HTML); sinks decide how the events are consumed.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// Kind is the type of an event.
+type Kind string
+
+const (
+	KindProgress Kind = "progress"
+	KindMatch    Kind = "match"
+	KindWarning  Kind = "warning"
+	KindMetric   Kind = "metric"
+)
+
+// Event is a single structured event published by a module.
+type Event struct {
+	Kind   Kind           `json:"kind"`
+	Module string         `json:"module"`
+	Name   string         `json:"name"`
+	Time   time.Time      `json:"time"`
+	Data   map[string]any `json:"data,omitempty"`
+}
+
+// Sink consumes published events. Implementations must be safe for concurrent use.
+type Sink interface {
+	Handle(ctx *maa.Context, e Event)
+}
+
+// SinkFunc adapts a function to [Sink].
+type SinkFunc func(ctx *maa.Context, e Event)
+
+func (f SinkFunc) Handle(ctx *maa.Context, e Event) {
+	f(ctx, e)
+}
+
+// LogSink writes every event as a structured log line. It is registered by default.
+type LogSink struct{}
+
+func (LogSink) Handle(_ *maa.Context, e Event) {
+	log.Info().
+		Str("kind", string(e.Kind)).
+		Str("module", e.Module).
+		Str("name", e.Name).
+		Interface("data", e.Data).
+		Msg("Event published")
+}
+
+var (
+	mu    sync.RWMutex
+	sinks = []Sink{LogSink{}}
+)
+
+// AddSink registers an additional sink.
+func AddSink(s Sink) {
+	mu.Lock()
+	defer mu.Unlock()
+	sinks = append(sinks, s)
+}
+
+// SetSinks replaces all sinks, including the default [LogSink].
+func SetSinks(s ...Sink) {
+	mu.Lock()
+	defer mu.Unlock()
+	sinks = append([]Sink(nil), s...)
+}
+
+// Publish sends e to every registered sink. Time is filled in when zero.
+func Publish(ctx *maa.Context, e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	mu.RLock()
+	current := sinks
+	mu.RUnlock()
+	for _, s := range current {
+		s.Handle(ctx, e)
+	}
+}
+
+// Progress publishes a progress event with done out of total.
+func Progress(ctx *maa.Context, module, name string, done, total int) {
+	Publish(ctx, Event{Kind: KindProgress, Module: module, Name: name, Data: map[string]any{"done": done, "total": total}})
+}
+
+// Match publishes a match event.
+func Match(ctx *maa.Context, module, name string, data map[string]any) {
+	Publish(ctx, Event{Kind: KindMatch, Module: module, Name: name, Data: data})
+}
+
+// Warning publishes a warning event with a message.
+func Warning(ctx *maa.Context, module, name, message string) {
+	Publish(ctx, Event{Kind: KindWarning, Module: module, Name: name, Data: map[string]any{"message": message}})
+}
+
+// Metric publishes a metric event.
+func Metric(ctx *maa.Context, module, name string, data map[string]any) {
+	Publish(ctx, Event{Kind: KindMetric, Module: module, Name: name, Data: data})
+}
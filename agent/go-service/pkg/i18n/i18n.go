@@ -36,7 +36,10 @@ var htmlTemplates = map[string]string{
 	"maptracker.navigation_finished":    "HTML/navigation-finished.html",
 	"maptracker.inference_finished":     "HTML/inference-finished.html",
 	"maptracker.inference_failed":       "HTML/inference-failed.html",
+	"maptracker.follow_path":            "HTML/follow-path.html",
+	"maptracker.face_heading":           "HTML/face-heading.html",
 	"essencefilter.loot_summary":        "HTML/essencefilter-loot-summary.html",
+	"essencefilter.rarity_summary":      "HTML/essencefilter-rarity-summary.html",
 	"essencefilter.init_weapons":        "HTML/essencefilter-init-weapons.html",
 	"essencefilter.init_skills":         "HTML/essencefilter-init-skills.html",
 	"essencefilter.plan_recommend":      "HTML/essencefilter-plan-recommend.html",
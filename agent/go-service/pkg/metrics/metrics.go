@@ -0,0 +1,196 @@
+// Package metrics provides named counters and timers shared by all modules, giving a
+// consistent "session stats" view across essence filtering, fights and map tracking.
+//
+// Modules opt in by calling [Inc], [Add] or [Observe]; the registry can be dumped to the
+// log or to JSON on demand, e.g. through the MetricsDump custom action.
+package metrics
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// Counter is a concurrency-safe monotonically increasing count.
+type Counter struct {
+	v atomic.Int64
+}
+
+// Add increases the counter by n.
+func (c *Counter) Add(n int64) { c.v.Add(n) }
+
+// Value returns the current count.
+func (c *Counter) Value() int64 { return c.v.Load() }
+
+// Timer accumulates observed durations.
+type Timer struct {
+	count atomic.Int64
+	total atomic.Int64 // ns
+	max   atomic.Int64 // ns
+}
+
+// Observe records one duration.
+func (t *Timer) Observe(d time.Duration) {
+	ns := int64(d)
+	t.count.Add(1)
+	t.total.Add(ns)
+	for {
+		cur := t.max.Load()
+		if ns <= cur || t.max.CompareAndSwap(cur, ns) {
+			return
+		}
+	}
+}
+
+// TimerSnapshot is a point-in-time view of a [Timer], in milliseconds.
+type TimerSnapshot struct {
+	Count   int64   `json:"count"`
+	TotalMs float64 `json:"totalMs"`
+	AvgMs   float64 `json:"avgMs"`
+	MaxMs   float64 `json:"maxMs"`
+}
+
+func (t *Timer) snapshot() TimerSnapshot {
+	s := TimerSnapshot{
+		Count:   t.count.Load(),
+		TotalMs: float64(t.total.Load()) / float64(time.Millisecond),
+		MaxMs:   float64(t.max.Load()) / float64(time.Millisecond),
+	}
+	if s.Count > 0 {
+		s.AvgMs = s.TotalMs / float64(s.Count)
+	}
+	return s
+}
+
+// Snapshot is a point-in-time view of the whole registry.
+type Snapshot struct {
+	Counters map[string]int64         `json:"counters"`
+	Timers   map[string]TimerSnapshot `json:"timers"`
+}
+
+var (
+	counters sync.Map // name -> *Counter
+	timers   sync.Map // name -> *Timer
+)
+
+// GetCounter returns the counter of name, creating it on first use.
+// Hot paths may keep the returned pointer to skip the lookup.
+func GetCounter(name string) *Counter {
+	if c, ok := counters.Load(name); ok {
+		return c.(*Counter)
+	}
+	c, _ := counters.LoadOrStore(name, &Counter{})
+	return c.(*Counter)
+}
+
+// GetTimer returns the timer of name, creating it on first use.
+func GetTimer(name string) *Timer {
+	if t, ok := timers.Load(name); ok {
+		return t.(*Timer)
+	}
+	t, _ := timers.LoadOrStore(name, &Timer{})
+	return t.(*Timer)
+}
+
+// Inc increases the counter of name by one.
+func Inc(name string) { GetCounter(name).Add(1) }
+
+// Add increases the counter of name by n.
+func Add(name string, n int64) { GetCounter(name).Add(n) }
+
+// Observe records one duration on the timer of name.
+func Observe(name string, d time.Duration) { GetTimer(name).Observe(d) }
+
+// Take returns a snapshot of all counters and timers.
+func Take() Snapshot {
+	s := Snapshot{
+		Counters: map[string]int64{},
+		Timers:   map[string]TimerSnapshot{},
+	}
+	counters.Range(func(k, v any) bool {
+		s.Counters[k.(string)] = v.(*Counter).Value()
+		return true
+	})
+	timers.Range(func(k, v any) bool {
+		s.Timers[k.(string)] = v.(*Timer).snapshot()
+		return true
+	})
+	return s
+}
+
+// Reset drops every counter and timer.
+func Reset() {
+	counters.Clear()
+	timers.Clear()
+}
+
+// DumpJSON returns the current snapshot as JSON.
+func DumpJSON() ([]byte, error) {
+	return json.Marshal(Take())
+}
+
+// DumpLog writes the current snapshot to the log, one line per metric in name order.
+func DumpLog() {
+	s := Take()
+	names := make([]string, 0, len(s.Counters))
+	for name := range s.Counters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		log.Info().Str("metric", name).Int64("value", s.Counters[name]).Msg("Metrics counter")
+	}
+
+	names = names[:0]
+	for name := range s.Timers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := s.Timers[name]
+		log.Info().Str("metric", name).
+			Int64("count", t.Count).
+			Float64("avgMs", t.AvgMs).
+			Float64("maxMs", t.MaxMs).
+			Float64("totalMs", t.TotalMs).
+			Msg("Metrics timer")
+	}
+}
+
+// DumpAction logs the registry (per-metric lines at info, full JSON at debug).
+// Param: {"reset": true} clears the registry after dumping.
+type DumpAction struct{}
+
+var _ maa.CustomActionRunner = &DumpAction{}
+
+// Run implements maa.CustomActionRunner
+func (a *DumpAction) Run(_ *maa.Context, arg *maa.CustomActionArg) bool {
+	var param struct {
+		Reset bool `json:"reset"`
+	}
+	if arg.CustomActionParam != "" {
+		if err := json.Unmarshal([]byte(arg.CustomActionParam), &param); err != nil {
+			log.Error().Err(err).Msg("Failed to parse parameters for MetricsDump")
+			return false
+		}
+	}
+
+	DumpLog()
+	if raw, err := DumpJSON(); err == nil {
+		log.Debug().RawJSON("metrics", raw).Msg("Metrics snapshot")
+	}
+	if param.Reset {
+		Reset()
+	}
+	return true
+}
+
+// Register registers the MetricsDump custom action.
+func Register() {
+	maa.AgentServerRegisterCustomAction("MetricsDump", &DumpAction{})
+}
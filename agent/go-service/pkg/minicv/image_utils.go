@@ -103,6 +103,30 @@ func ImageScale(img *image.RGBA, scale float64) *image.RGBA {
 	return dst
 }
 
+// ImageApplyCircularMask returns a copy of img with every pixel outside the inscribed circle
+// zeroed, including alpha, so that masked matching (MatchOptions.Masked) ignores it
+func ImageApplyCircularMask(img *image.RGBA) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	cx, cy := float64(w)/2.0, float64(h)/2.0
+	r := math.Min(cx, cy)
+	r2 := r * r
+	for y := range h {
+		sOff := y * img.Stride
+		dOff := y * dst.Stride
+		dy := float64(y) + 0.5 - cy
+		for x := range w {
+			dx := float64(x) + 0.5 - cx
+			if dx*dx+dy*dy <= r2 {
+				copy(dst.Pix[dOff:dOff+4], img.Pix[sOff:sOff+4])
+			}
+			sOff += 4
+			dOff += 4
+		}
+	}
+	return dst
+}
+
 // ImageConvertRGBA converts any image.Image to *image.RGBA
 func ImageConvertRGBA(img image.Image) *image.RGBA {
 	if dst, ok := img.(*image.RGBA); ok {
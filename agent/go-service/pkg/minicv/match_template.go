@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"image"
 	_ "image/png"
+	"math"
 	"os"
 	"sync"
 )
@@ -133,6 +134,197 @@ func ComputeNCC(img *image.RGBA, imgIntArr IntegralArray, tpl *image.RGBA, tplSt
 	return (float64(dot) - count*imgStats.Mean*tplStats.Mean) / stdProd
 }
 
+// ComputeSAD computes a zero-mean sum-of-absolute-differences similarity between a rectangle region
+// in the haystack image and a template image. The area mean comes from the integral array, and the
+// result is normalized into [0, 1] by the Cauchy-Schwarz bound sqrt(n) * (imgStd + tplStd),
+// so that 1 means identical up to brightness offset and higher is better, like ComputeNCC.
+func ComputeSAD(img *image.RGBA, imgIntArr IntegralArray, tpl *image.RGBA, tplStats StatsResult, ox, oy int) float64 {
+	iw, ih := img.Rect.Dx(), img.Rect.Dy()
+	tw, th := tpl.Rect.Dx(), tpl.Rect.Dy()
+	if ox < 0 || oy < 0 || ox+tw > iw || oy+th > ih {
+		return 0.0
+	}
+
+	imgStats := imgIntArr.GetAreaStats(ox, oy, tw, th)
+	count := float64(tw * th * 3)
+	bound := math.Sqrt(count) * (imgStats.Std + tplStats.Std)
+	if bound < 1e-12 {
+		return 0.0
+	}
+	shift := imgStats.Mean - tplStats.Mean
+
+	ipx, is := img.Pix, img.Stride
+	tpx, ts := tpl.Pix, tpl.Stride
+
+	var sad float64
+	iOffBase := oy*is + ox*4
+	for y := range th {
+		iOff := iOffBase
+		tOff := y * ts
+		for range tw {
+			sad += math.Abs(float64(ipx[iOff]) - float64(tpx[tOff]) - shift)
+			sad += math.Abs(float64(ipx[iOff+1]) - float64(tpx[tOff+1]) - shift)
+			sad += math.Abs(float64(ipx[iOff+2]) - float64(tpx[tOff+2]) - shift)
+			iOff += 4
+			tOff += 4
+		}
+		iOffBase += is
+	}
+
+	return max(0.0, 1.0-sad/bound)
+}
+
+// MatchMetric selects the similarity measure used by template matching.
+type MatchMetric string
+
+const (
+	// MetricNCC is normalized cross-correlation, in [-1, 1]. Robust to brightness and contrast changes.
+	MetricNCC MatchMetric = "NCC"
+	// MetricSAD is zero-mean sum of absolute differences, in [0, 1]. Cheaper per pixel and less
+	// dominated by a few high-contrast pixels, but sensitive to contrast changes.
+	MetricSAD MatchMetric = "SAD"
+)
+
+// Valid reports whether m is a known metric.
+func (m MatchMetric) Valid() bool {
+	return m == MetricNCC || m == MetricSAD
+}
+
+// MatchOptions controls how template matching scores each candidate position.
+type MatchOptions struct {
+	// Metric is the similarity metric; empty means MetricNCC.
+	Metric MatchMetric
+	// Masked excludes template pixels with zero alpha from scoring (see ImageApplyCircularMask).
+	// The template stats must then come from GetMaskedImageStats. Slower, as haystack stats
+	// can no longer be read from the integral array.
+	Masked bool
+}
+
+func (o MatchOptions) scorer() func(*image.RGBA, IntegralArray, *image.RGBA, StatsResult, int, int) float64 {
+	switch {
+	case o.Masked && o.Metric == MetricSAD:
+		return computeMaskedSAD
+	case o.Masked:
+		return computeMaskedNCC
+	case o.Metric == MetricSAD:
+		return ComputeSAD
+	default:
+		return ComputeNCC
+	}
+}
+
+// maskedAreaStats returns the pixel count and the mean/std (unnormalized) of the haystack region
+// under the unmasked template pixels.
+func maskedAreaStats(img *image.RGBA, tpl *image.RGBA, ox, oy int) (float64, StatsResult) {
+	tw, th := tpl.Rect.Dx(), tpl.Rect.Dy()
+	ipx, is := img.Pix, img.Stride
+	tpx, ts := tpl.Pix, tpl.Stride
+
+	var sum, sumSq float64
+	count := 0
+	iOffBase := oy*is + ox*4
+	for y := range th {
+		iOff := iOffBase
+		tOff := y * ts
+		for range tw {
+			if tpx[tOff+3] != 0 {
+				r, g, b := float64(ipx[iOff]), float64(ipx[iOff+1]), float64(ipx[iOff+2])
+				sum += r + g + b
+				sumSq += r*r + g*g + b*b
+				count += 3
+			}
+			iOff += 4
+			tOff += 4
+		}
+		iOffBase += is
+	}
+	if count == 0 {
+		return 0, StatsResult{}
+	}
+	n := float64(count)
+	mean := sum / n
+	variance := sumSq - n*(mean*mean)
+	if variance < 1e-12 {
+		return n, StatsResult{Mean: mean, Std: 0}
+	}
+	return n, StatsResult{mean, math.Sqrt(variance)}
+}
+
+// computeMaskedNCC is ComputeNCC restricted to template pixels with non-zero alpha.
+func computeMaskedNCC(img *image.RGBA, _ IntegralArray, tpl *image.RGBA, tplStats StatsResult, ox, oy int) float64 {
+	iw, ih := img.Rect.Dx(), img.Rect.Dy()
+	tw, th := tpl.Rect.Dx(), tpl.Rect.Dy()
+	if ox < 0 || oy < 0 || ox+tw > iw || oy+th > ih {
+		return 0.0
+	}
+
+	count, imgStats := maskedAreaStats(img, tpl, ox, oy)
+	stdProd := imgStats.Std * tplStats.Std
+	if count == 0 || stdProd < 1e-12 {
+		return 0.0
+	}
+
+	ipx, is := img.Pix, img.Stride
+	tpx, ts := tpl.Pix, tpl.Stride
+
+	var dot uint64
+	iOffBase := oy*is + ox*4
+	for y := range th {
+		iOff := iOffBase
+		tOff := y * ts
+		for range tw {
+			if tpx[tOff+3] != 0 {
+				dot += uint64(ipx[iOff]) * uint64(tpx[tOff])
+				dot += uint64(ipx[iOff+1]) * uint64(tpx[tOff+1])
+				dot += uint64(ipx[iOff+2]) * uint64(tpx[tOff+2])
+			}
+			iOff += 4
+			tOff += 4
+		}
+		iOffBase += is
+	}
+
+	return (float64(dot) - count*imgStats.Mean*tplStats.Mean) / stdProd
+}
+
+// computeMaskedSAD is ComputeSAD restricted to template pixels with non-zero alpha.
+func computeMaskedSAD(img *image.RGBA, _ IntegralArray, tpl *image.RGBA, tplStats StatsResult, ox, oy int) float64 {
+	iw, ih := img.Rect.Dx(), img.Rect.Dy()
+	tw, th := tpl.Rect.Dx(), tpl.Rect.Dy()
+	if ox < 0 || oy < 0 || ox+tw > iw || oy+th > ih {
+		return 0.0
+	}
+
+	count, imgStats := maskedAreaStats(img, tpl, ox, oy)
+	bound := math.Sqrt(count) * (imgStats.Std + tplStats.Std)
+	if count == 0 || bound < 1e-12 {
+		return 0.0
+	}
+	shift := imgStats.Mean - tplStats.Mean
+
+	ipx, is := img.Pix, img.Stride
+	tpx, ts := tpl.Pix, tpl.Stride
+
+	var sad float64
+	iOffBase := oy*is + ox*4
+	for y := range th {
+		iOff := iOffBase
+		tOff := y * ts
+		for range tw {
+			if tpx[tOff+3] != 0 {
+				sad += math.Abs(float64(ipx[iOff]) - float64(tpx[tOff]) - shift)
+				sad += math.Abs(float64(ipx[iOff+1]) - float64(tpx[tOff+1]) - shift)
+				sad += math.Abs(float64(ipx[iOff+2]) - float64(tpx[tOff+2]) - shift)
+			}
+			iOff += 4
+			tOff += 4
+		}
+		iOffBase += is
+	}
+
+	return max(0.0, 1.0-sad/bound)
+}
+
 // MatchTemplate performs template matching on the whole image,
 // returns (x, y, val) of the best match, where x and y are subpixel-accurate coordinates.
 func MatchTemplate(
@@ -140,9 +332,20 @@ func MatchTemplate(
 	imgIntArr IntegralArray,
 	tpl *image.RGBA,
 	tplStats StatsResult,
+) (x, y, val float64) {
+	return MatchTemplateWithOptions(img, imgIntArr, tpl, tplStats, MatchOptions{})
+}
+
+// MatchTemplateWithOptions is MatchTemplate using the given match options.
+func MatchTemplateWithOptions(
+	img *image.RGBA,
+	imgIntArr IntegralArray,
+	tpl *image.RGBA,
+	tplStats StatsResult,
+	opts MatchOptions,
 ) (x, y, val float64) {
 	iw, ih := img.Rect.Dx(), img.Rect.Dy()
-	return MatchTemplateInArea(img, imgIntArr, tpl, tplStats, [4]int{0, 0, iw, ih})
+	return MatchTemplateInAreaWithOptions(img, imgIntArr, tpl, tplStats, [4]int{0, 0, iw, ih}, opts)
 }
 
 // MatchTemplateInArea performs template matching such that the center of the template
@@ -155,6 +358,19 @@ func MatchTemplateInArea(
 	tplStats StatsResult,
 	rect [4]int,
 ) (x, y, val float64) {
+	return MatchTemplateInAreaWithOptions(img, imgIntArr, tpl, tplStats, rect, MatchOptions{})
+}
+
+// MatchTemplateInAreaWithOptions is MatchTemplateInArea using the given match options.
+func MatchTemplateInAreaWithOptions(
+	img *image.RGBA,
+	imgIntArr IntegralArray,
+	tpl *image.RGBA,
+	tplStats StatsResult,
+	rect [4]int,
+	opts MatchOptions,
+) (x, y, val float64) {
+	score := opts.scorer()
 	ax, ay, aw, ah := rect[0], rect[1], rect[2], rect[3]
 	iw, ih := img.Rect.Dx(), img.Rect.Dy()
 	tw, th := tpl.Rect.Dx(), tpl.Rect.Dy()
@@ -180,7 +396,7 @@ func MatchTemplateInArea(
 			lx, ly, lm := 0, 0, -1.0
 			for y := minY + id*stepLen; y <= maxY; y += numWorkers * stepLen {
 				for x := minX; x <= maxX; x += stepLen {
-					s := ComputeNCC(img, imgIntArr, tpl, tplStats, x, y)
+					s := score(img, imgIntArr, tpl, tplStats, x, y)
 					if s > lm {
 						lm, lx, ly = s, x, y
 					}
@@ -202,31 +418,31 @@ func MatchTemplateInArea(
 	// Fine-tuning pass around the best result
 	for y := max(minY, bc.y-stepLen+1); y <= min(maxY, bc.y+stepLen-1); y++ {
 		for x := max(minX, bc.x-stepLen+1); x <= min(maxX, bc.x+stepLen-1); x++ {
-			s := ComputeNCC(img, imgIntArr, tpl, tplStats, x, y)
+			s := score(img, imgIntArr, tpl, tplStats, x, y)
 			if s > fm {
 				fm, fx, fy = s, x, y
 			}
 		}
 	}
 
-	upNCC, downNCC := fm, fm
-	leftNCC, rightNCC := fm, fm
+	upVal, downVal := fm, fm
+	leftVal, rightVal := fm, fm
 
 	if fy-1 >= minY {
-		upNCC = ComputeNCC(img, imgIntArr, tpl, tplStats, fx, fy-1)
+		upVal = score(img, imgIntArr, tpl, tplStats, fx, fy-1)
 	}
 	if fy+1 <= maxY {
-		downNCC = ComputeNCC(img, imgIntArr, tpl, tplStats, fx, fy+1)
+		downVal = score(img, imgIntArr, tpl, tplStats, fx, fy+1)
 	}
 	if fx-1 >= minX {
-		leftNCC = ComputeNCC(img, imgIntArr, tpl, tplStats, fx-1, fy)
+		leftVal = score(img, imgIntArr, tpl, tplStats, fx-1, fy)
 	}
 	if fx+1 <= maxX {
-		rightNCC = ComputeNCC(img, imgIntArr, tpl, tplStats, fx+1, fy)
+		rightVal = score(img, imgIntArr, tpl, tplStats, fx+1, fy)
 	}
 
-	subX := float64(fx) + subpixelOffset(leftNCC, rightNCC)
-	subY := float64(fy) + subpixelOffset(upNCC, downNCC)
+	subX := float64(fx) + subpixelOffset(leftVal, rightVal)
+	subY := float64(fy) + subpixelOffset(upVal, downVal)
 
 	return subX, subY, fm
 }
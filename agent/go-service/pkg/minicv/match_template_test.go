@@ -0,0 +1,143 @@
+package minicv
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+// textureFixture builds a deterministic pseudo-random RGB texture, box-blurred so that the score
+// peak is a few pixels wide like in real screenshots (the search samples every third position
+// before refining), while every template cut from it still has a single best position.
+func textureFixture(w, h int) *image.RGBA {
+	noise := make([]float64, w*h*3)
+	seed := uint32(12345)
+	for i := range noise {
+		seed = seed*1664525 + 1013904223
+		noise[i] = float64((seed >> 24) % 256)
+	}
+
+	const r = 2
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			for c := range 3 {
+				sum, n := 0.0, 0.0
+				for dy := -r; dy <= r; dy++ {
+					for dx := -r; dx <= r; dx++ {
+						sx, sy := x+dx, y+dy
+						if sx < 0 || sy < 0 || sx >= w || sy >= h {
+							continue
+						}
+						sum += noise[(sy*w+sx)*3+c]
+						n++
+					}
+				}
+				// Stretch the blurred values back to a usable contrast range
+				v := 128 + (sum/n-128)*3
+				img.Pix[y*img.Stride+x*4+c] = uint8(min(235, max(20, v)))
+			}
+			img.Pix[y*img.Stride+x*4+3] = 255
+		}
+	}
+	return img
+}
+
+// mapTemplate returns a copy of img cropped to rect with f applied to every color channel.
+func mapTemplate(img *image.RGBA, rect image.Rectangle, f func(v float64) float64) *image.RGBA {
+	tpl := ImageCropRect(img, rect)
+	out := image.NewRGBA(tpl.Rect)
+	copy(out.Pix, tpl.Pix)
+	for i := 0; i < len(out.Pix); i += 4 {
+		for c := range 3 {
+			out.Pix[i+c] = uint8(math.Round(min(255, max(0, f(float64(out.Pix[i+c]))))))
+		}
+	}
+	return out
+}
+
+func TestMetricsOnFixture(t *testing.T) {
+	img := textureFixture(96, 72)
+	intArr := GetIntegralArray(img)
+	ox, oy := 37, 21
+	rect := image.Rect(ox, oy, ox+16, oy+12)
+
+	cases := []struct {
+		name string
+		f    func(v float64) float64
+		// minimum score at the true position for each metric
+		minNCC, minSAD float64
+	}{
+		{"exact", func(v float64) float64 { return v }, 0.999, 0.999},
+		{"brightness offset", func(v float64) float64 { return v + 20 }, 0.999, 0.99},
+		// halving the contrast keeps NCC at 1 but costs SAD a large share of its score
+		{"contrast halved", func(v float64) float64 { return 128 + (v-128)/2 }, 0.99, 0.5},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl := mapTemplate(img, rect, tc.f)
+			stats := GetImageStats(tpl)
+
+			ncc := ComputeNCC(img, intArr, tpl, stats, ox, oy)
+			sad := ComputeSAD(img, intArr, tpl, stats, ox, oy)
+			if ncc < tc.minNCC {
+				t.Errorf("NCC at true position = %.4f, want >= %.3f", ncc, tc.minNCC)
+			}
+			if sad < tc.minSAD || sad > 1 {
+				t.Errorf("SAD at true position = %.4f, want in [%.3f, 1]", sad, tc.minSAD)
+			}
+
+			// Any other position must score clearly lower under both metrics
+			for _, off := range [][2]int{{ox + 5, oy}, {ox, oy + 7}, {3, 3}} {
+				if s := ComputeNCC(img, intArr, tpl, stats, off[0], off[1]); s >= ncc-0.3 {
+					t.Errorf("NCC at %v = %.4f, not clearly below %.4f", off, s, ncc)
+				}
+				if s := ComputeSAD(img, intArr, tpl, stats, off[0], off[1]); s >= sad-0.2 {
+					t.Errorf("SAD at %v = %.4f, not clearly below %.4f", off, s, sad)
+				}
+			}
+
+			for _, metric := range []MatchMetric{MetricNCC, MetricSAD} {
+				x, y, _ := MatchTemplateWithOptions(img, intArr, tpl, stats, MatchOptions{Metric: metric})
+				if math.Abs(x-float64(ox)) > 1 || math.Abs(y-float64(oy)) > 1 {
+					t.Errorf("%s best match at (%.2f, %.2f), want (%d, %d)", metric, x, y, ox, oy)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsContrastTradeoff(t *testing.T) {
+	img := textureFixture(64, 48)
+	intArr := GetIntegralArray(img)
+	ox, oy := 20, 10
+	rect := image.Rect(ox, oy, ox+16, oy+12)
+
+	exact := mapTemplate(img, rect, func(v float64) float64 { return v })
+	low := mapTemplate(img, rect, func(v float64) float64 { return 128 + (v-128)/2 })
+
+	nccExact := ComputeNCC(img, intArr, exact, GetImageStats(exact), ox, oy)
+	nccLow := ComputeNCC(img, intArr, low, GetImageStats(low), ox, oy)
+	sadExact := ComputeSAD(img, intArr, exact, GetImageStats(exact), ox, oy)
+	sadLow := ComputeSAD(img, intArr, low, GetImageStats(low), ox, oy)
+
+	if math.Abs(nccExact-nccLow) > 0.01 {
+		t.Errorf("NCC should be contrast invariant: exact %.4f, low contrast %.4f", nccExact, nccLow)
+	}
+	if sadExact-sadLow < 0.1 {
+		t.Errorf("SAD should drop under a contrast change: exact %.4f, low contrast %.4f", sadExact, sadLow)
+	}
+}
+
+func TestMatchMetricValid(t *testing.T) {
+	for _, m := range []MatchMetric{MetricNCC, MetricSAD} {
+		if !m.Valid() {
+			t.Errorf("%q should be valid", m)
+		}
+	}
+	for _, m := range []MatchMetric{"", "ncc", "SSD"} {
+		if m.Valid() {
+			t.Errorf("%q should be invalid", m)
+		}
+	}
+}
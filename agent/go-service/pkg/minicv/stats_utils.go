@@ -46,6 +46,40 @@ func GetImageStats(img *image.RGBA) StatsResult {
 	return StatsResult{mean, math.Sqrt(variance)}
 }
 
+// GetMaskedImageStats is GetImageStats restricted to pixels with non-zero alpha
+func GetMaskedImageStats(img *image.RGBA) StatsResult {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	ipx, is := img.Pix, img.Stride
+
+	sum := 0.0
+	sumSq := 0.0
+	count := 0
+
+	for y := range h {
+		off := y * is
+		for range w {
+			if ipx[off+3] != 0 {
+				r, g, b := float64(ipx[off]), float64(ipx[off+1]), float64(ipx[off+2])
+				sum += r + g + b
+				sumSq += r*r + g*g + b*b
+				count += 3
+			}
+			off += 4
+		}
+	}
+
+	if count == 0 {
+		return StatsResult{}
+	}
+	n := float64(count)
+	mean := sum / n
+	variance := sumSq - n*(mean*mean)
+	if variance < 1e-12 {
+		return StatsResult{Mean: mean, Std: 0}
+	}
+	return StatsResult{mean, math.Sqrt(variance)}
+}
+
 // GetIntegralArray computes the integral array for an image
 func GetIntegralArray(img *image.RGBA) IntegralArray {
 	w, h := img.Rect.Dx(), img.Rect.Dy()
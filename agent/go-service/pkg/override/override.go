@@ -0,0 +1,61 @@
+// Package override provides a builder for pipeline overrides passed to
+// ctx.RunTask / ctx.RunRecognition, replacing hand-written nested map literals.
+package override
+
+import (
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Builder builds a pipeline override map of the form {node: {key: value}}.
+type Builder struct {
+	root    map[string]any
+	current map[string]any
+}
+
+// Node starts a new override for the given node.
+//
+//	override.Node("NodeClick").Set("action.param.target", box).Map()
+func Node(name string) *Builder {
+	b := &Builder{root: map[string]any{}}
+	return b.Node(name)
+}
+
+// Node switches the builder to the given node, so that one override can cover several nodes.
+func (b *Builder) Node(name string) *Builder {
+	node, ok := b.root[name].(map[string]any)
+	if !ok {
+		node = map[string]any{}
+		b.root[name] = node
+	}
+	b.current = node
+	return b
+}
+
+// Set sets value at the dotted path under the current node, creating intermediate maps as needed.
+//
+// A path segment that already holds a non-map value is replaced with a map and a warning is logged,
+// since this almost always means two keys in the override conflict.
+func (b *Builder) Set(path string, value any) *Builder {
+	keys := strings.Split(path, ".")
+	m := b.current
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]any)
+		if !ok {
+			if existing, exists := m[key]; exists {
+				log.Warn().Str("path", path).Str("key", key).Interface("existing", existing).Msg("Override path conflicts with existing value, replacing")
+			}
+			next = map[string]any{}
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = value
+	return b
+}
+
+// Map returns the built override.
+func (b *Builder) Map() map[string]any {
+	return b.root
+}
@@ -0,0 +1,76 @@
+package override
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+)
+
+// The literals below are the hand-written overrides the builder replaced.
+func TestBuilderMatchesLiterals(t *testing.T) {
+	roi := maa.Rect{1010, 535, 270, 65}
+	box := [4]int{100, 200, 60, 60}
+
+	cases := []struct {
+		name string
+		got  map[string]any
+		want map[string]any
+	}{
+		{
+			name: "roi",
+			got:  Node("__AutoFightRecognitionEndSkill").Set("roi", roi).Map(),
+			want: map[string]any{
+				"__AutoFightRecognitionEndSkill": map[string]any{
+					"roi": roi,
+				},
+			},
+		},
+		{
+			name: "nested action target",
+			got:  Node("NodeClick").Set("action.param.target", box).Map(),
+			want: map[string]any{
+				"NodeClick": map[string]any{
+					"action": map[string]any{"param": map[string]any{"target": box}},
+				},
+			},
+		},
+		{
+			name: "sibling keys share intermediate maps",
+			got:  Node("NodeClick").Set("action.type", "Click").Set("action.param.target", box).Set("enabled", true).Map(),
+			want: map[string]any{
+				"NodeClick": map[string]any{
+					"action":  map[string]any{"type": "Click", "param": map[string]any{"target": box}},
+					"enabled": true,
+				},
+			},
+		},
+		{
+			name: "several nodes",
+			got:  Node("A").Set("roi", roi).Node("B").Set("next", []string{"C"}).Node("A").Set("enabled", false).Map(),
+			want: map[string]any{
+				"A": map[string]any{"roi": roi, "enabled": false},
+				"B": map[string]any{"next": []string{"C"}},
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tc.got, tc.want) {
+				t.Errorf("builder produced %#v, want %#v", tc.got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBuilderReplacesConflictingValue(t *testing.T) {
+	got := Node("NodeClick").Set("action", "Click").Set("action.param.target", 1).Map()
+	want := map[string]any{
+		"NodeClick": map[string]any{
+			"action": map[string]any{"param": map[string]any{"target": 1}},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("builder produced %#v, want %#v", got, want)
+	}
+}
@@ -0,0 +1,55 @@
+// Package recognition provides shared helpers around ctx.RunRecognition.
+package recognition
+
+import (
+	"errors"
+	"image"
+	"time"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultAttempts keeps the previous single-call behavior.
+const DefaultAttempts = 1
+
+// retryBackoff is the delay before the n-th retry, multiplied by n.
+const retryBackoff = 50 * time.Millisecond
+
+// ErrNoDetail is returned when the framework reports no error but also no detail.
+var ErrNoDetail = errors.New("recognition returned no detail")
+
+// RunRecognitionRetry runs the named recognition on img, retrying up to attempts times when it fails.
+//
+// A nil override runs the node without override. Attempts below 1 are treated as [DefaultAttempts].
+// Only errors are retried; a valid detail without hit is returned immediately.
+// The returned hit is true only when the recognition succeeded and hit.
+func RunRecognitionRetry(ctx *maa.Context, name string, img image.Image, override any, attempts int) (*maa.RecognitionDetail, bool, error) {
+	if attempts < 1 {
+		attempts = DefaultAttempts
+	}
+
+	var overrides []any
+	if override != nil {
+		overrides = []any{override}
+	}
+
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(retryBackoff * time.Duration(i))
+		}
+		detail, err := ctx.RunRecognition(name, img, overrides...)
+		if err == nil && detail == nil {
+			err = ErrNoDetail
+		}
+		if err == nil {
+			return detail, detail.Hit, nil
+		}
+		lastErr = err
+		if i+1 < attempts {
+			log.Debug().Err(err).Str("name", name).Int("attempt", i+1).Int("attempts", attempts).Msg("Recognition failed, retry")
+		}
+	}
+	return nil, false, lastErr
+}
@@ -0,0 +1,69 @@
+// Package rect provides helpers for adapting coordinates designed at the 1280x720
+// baseline resolution to other resolutions.
+package rect
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+)
+
+// Baseline resolution that all hard-coded ROIs and targets in resources and Go code are designed for.
+const (
+	BaseWidth  = 1280
+	BaseHeight = 720
+)
+
+// ScaleRect scales r from a fromW x fromH space to a toW x toH space.
+//
+// Edges are scaled and rounded individually before the width and height are derived,
+// so adjacent rects stay adjacent after scaling. The two axes are scaled independently:
+// when the aspect ratios differ the rect is stretched, not letterboxed; use [ScaleRectFit]
+// for letterboxed targets. Non-positive sizes return r unchanged.
+func ScaleRect(r maa.Rect, fromW, fromH, toW, toH int) maa.Rect {
+	if fromW <= 0 || fromH <= 0 || toW <= 0 || toH <= 0 || (fromW == toW && fromH == toH) {
+		return r
+	}
+	sx := float64(toW) / float64(fromW)
+	sy := float64(toH) / float64(fromH)
+	return scale(r, sx, sy, 0, 0)
+}
+
+// ScaleRectFit scales r from a fromW x fromH space into a toW x toH space with the same
+// scale on both axes, centering the content and leaving black bars (letterbox or pillarbox)
+// on the longer axis. Non-positive sizes return r unchanged.
+func ScaleRectFit(r maa.Rect, fromW, fromH, toW, toH int) maa.Rect {
+	if fromW <= 0 || fromH <= 0 || toW <= 0 || toH <= 0 || (fromW == toW && fromH == toH) {
+		return r
+	}
+	s := math.Min(float64(toW)/float64(fromW), float64(toH)/float64(fromH))
+	offsetX := (float64(toW) - float64(fromW)*s) / 2
+	offsetY := (float64(toH) - float64(fromH)*s) / 2
+	return scale(r, s, s, offsetX, offsetY)
+}
+
+// ScaleFromBase scales r from the baseline resolution to width x height.
+func ScaleFromBase(r maa.Rect, width, height int) maa.Rect {
+	return ScaleRect(r, BaseWidth, BaseHeight, width, height)
+}
+
+// ScaleToController scales r from the baseline resolution to the resolution of the controller bound to ctx.
+func ScaleToController(ctx *maa.Context, r maa.Rect) (maa.Rect, error) {
+	if ctx == nil {
+		return r, fmt.Errorf("context is nil")
+	}
+	w, h, err := ctx.GetTasker().GetController().GetResolution()
+	if err != nil {
+		return r, fmt.Errorf("failed to get controller resolution: %w", err)
+	}
+	return ScaleFromBase(r, int(w), int(h)), nil
+}
+
+func scale(r maa.Rect, sx, sy, offsetX, offsetY float64) maa.Rect {
+	x0 := int(math.Round(float64(r.X())*sx + offsetX))
+	y0 := int(math.Round(float64(r.Y())*sy + offsetY))
+	x1 := int(math.Round(float64(r.X()+r.Width())*sx + offsetX))
+	y1 := int(math.Round(float64(r.Y()+r.Height())*sy + offsetY))
+	return maa.Rect{x0, y0, x1 - x0, y1 - y0}
+}
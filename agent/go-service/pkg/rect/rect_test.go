@@ -0,0 +1,81 @@
+package rect
+
+import (
+	"testing"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+)
+
+func TestScaleRect(t *testing.T) {
+	cases := []struct {
+		name                   string
+		r                      maa.Rect
+		fromW, fromH, toW, toH int
+		want                   maa.Rect
+	}{
+		{"same size", maa.Rect{1010, 535, 270, 65}, 1280, 720, 1280, 720, maa.Rect{1010, 535, 270, 65}},
+		{"1080p", maa.Rect{1010, 535, 270, 65}, 1280, 720, 1920, 1080, maa.Rect{1515, 803, 405, 97}},
+		{"1440p", maa.Rect{1010, 535, 270, 65}, 1280, 720, 2560, 1440, maa.Rect{2020, 1070, 540, 130}},
+		// Halves round away from zero: 1.5 -> 2, 3 -> 3
+		{"round half up", maa.Rect{1, 1, 1, 1}, 1280, 720, 1920, 1080, maa.Rect{2, 2, 1, 1}},
+		// Edges are rounded, not the size: 7.5 -> 8 and 8.25 -> 8, so a 1px rect can collapse
+		{"downscale collapse", maa.Rect{10, 10, 1, 1}, 1280, 720, 960, 540, maa.Rect{8, 8, 0, 0}},
+		// Different aspect ratio: axes are stretched independently
+		{"stretch to 4:3", maa.Rect{100, 100, 200, 100}, 1280, 720, 1280, 960, maa.Rect{100, 133, 200, 134}},
+		{"zero source", maa.Rect{1, 2, 3, 4}, 0, 720, 1920, 1080, maa.Rect{1, 2, 3, 4}},
+		{"negative target", maa.Rect{1, 2, 3, 4}, 1280, 720, -1, 1080, maa.Rect{1, 2, 3, 4}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ScaleRect(tc.r, tc.fromW, tc.fromH, tc.toW, tc.toH); got != tc.want {
+				t.Errorf("ScaleRect(%v, %dx%d -> %dx%d) = %v, want %v", tc.r, tc.fromW, tc.fromH, tc.toW, tc.toH, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScaleRectKeepsAdjacentRectsAdjacent(t *testing.T) {
+	left := ScaleRect(maa.Rect{0, 0, 3, 3}, 1280, 720, 1920, 1080)
+	right := ScaleRect(maa.Rect{3, 0, 3, 3}, 1280, 720, 1920, 1080)
+	if left.X()+left.Width() != right.X() {
+		t.Errorf("scaled rects %v and %v are no longer adjacent", left, right)
+	}
+	if left.Width()+right.Width() != 9 {
+		t.Errorf("scaled widths %d + %d, want 9 in total", left.Width(), right.Width())
+	}
+}
+
+func TestScaleRectFit(t *testing.T) {
+	cases := []struct {
+		name     string
+		r        maa.Rect
+		toW, toH int
+		want     maa.Rect
+	}{
+		// Same aspect ratio: no bars, same as ScaleRect
+		{"1080p", maa.Rect{1010, 535, 270, 65}, 1920, 1080, maa.Rect{1515, 803, 405, 97}},
+		// 4:3 target: scale 1, 120px bars top and bottom
+		{"letterbox full frame", maa.Rect{0, 0, 1280, 720}, 1280, 960, maa.Rect{0, 120, 1280, 720}},
+		{"letterbox", maa.Rect{100, 100, 200, 100}, 1280, 960, maa.Rect{100, 220, 200, 100}},
+		// 21:9 target: scale 1.5, 320px bars left and right
+		{"pillarbox full frame", maa.Rect{0, 0, 1280, 720}, 2560, 1080, maa.Rect{320, 0, 1920, 1080}},
+		{"pillarbox", maa.Rect{100, 100, 200, 100}, 2560, 1080, maa.Rect{470, 150, 300, 150}},
+		// Odd bar size: the 0.5px offset is rounded with the edges
+		{"odd letterbox", maa.Rect{0, 0, 1280, 720}, 1280, 721, maa.Rect{0, 1, 1280, 720}},
+		{"zero target", maa.Rect{1, 2, 3, 4}, 0, 0, maa.Rect{1, 2, 3, 4}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ScaleRectFit(tc.r, BaseWidth, BaseHeight, tc.toW, tc.toH); got != tc.want {
+				t.Errorf("ScaleRectFit(%v, -> %dx%d) = %v, want %v", tc.r, tc.toW, tc.toH, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScaleFromBase(t *testing.T) {
+	r := maa.Rect{28, 657, 56, 4}
+	if got, want := ScaleFromBase(r, 1920, 1080), ScaleRect(r, BaseWidth, BaseHeight, 1920, 1080); got != want {
+		t.Errorf("ScaleFromBase(%v) = %v, want %v", r, got, want)
+	}
+}
@@ -11,9 +11,9 @@ import (
 
 // ReadResource tries to find and read the specified resource file as bytes array.
 //
-// To understand how the resource file is located, please refer to the [FindResource] function.
+// To understand how the resource file is located, please refer to the [Find] function.
 func ReadResource(relativePath string) ([]byte, error) {
-	resolvedPath := FindResource(relativePath)
+	resolvedPath := Find(relativePath)
 	if resolvedPath == "" {
 		log.Error().Str("relativePath", relativePath).Msg("Resource cannot be found")
 		return nil, os.ErrNotExist
@@ -29,7 +29,7 @@ func ReadResource(relativePath string) ([]byte, error) {
 
 // ReadJsonResource tries to find and read the specified resource file as JSON and unmarshal it into the provided variable.
 //
-// To understand how the resource file is located, please refer to the [FindResource] function.
+// To understand how the resource file is located, please refer to the [Find] function.
 func ReadJsonResource(relativePath string, out any) error {
 	content, err := ReadResource(relativePath)
 	if err != nil {
@@ -42,7 +42,7 @@ func ReadJsonResource(relativePath string, out any) error {
 	return nil
 }
 
-// FindResource tries to find a file in the cached resource path or standard fallback paths.
+// Find tries to find a file in the cached resource path or standard fallback paths.
 //
 // The path will be resolved in the following order:
 //
@@ -51,7 +51,7 @@ func ReadJsonResource(relativePath string, out any) error {
 // 2. Searching in the resource base path set by resource sink.
 //
 // 3. Searching in "resource" and "assets" directories in the current working directory and its parent/grandparent directories.
-func FindResource(relativePath string) string {
+func Find(relativePath string) string {
 	tryPath := func(path string) string {
 		if path == "" {
 			return ""
@@ -82,7 +82,7 @@ func FindResource(relativePath string) string {
 	rel := strings.TrimPrefix(rawPath, string(filepath.Separator))
 
 	findPath := func(rel string) string {
-		if base := getResourceBase(); base != "" {
+		if base := Base(); base != "" {
 			base = filepath.Clean(base)
 			if found := tryPath(filepath.Join(base, rel)); found != "" {
 				return found
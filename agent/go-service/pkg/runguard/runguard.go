@@ -0,0 +1,84 @@
+// Package runguard provides module-scoped run tokens that stop a second task from
+// mutating a module's package globals while another task is still using them.
+//
+// A token is acquired by a module's init action and released by its finish action.
+// Tokens held by a task are also released when the task ends, so an aborted run
+// never leaves a module locked.
+package runguard
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	mu     sync.Mutex
+	owners = map[string]int64{} // module -> task ID holding the token
+
+	registerSinkOnce sync.Once
+)
+
+// ErrHeld is returned by [Acquire] when another task holds the module's token.
+type ErrHeld struct {
+	Module string
+	Owner  int64
+}
+
+func (e *ErrHeld) Error() string {
+	return fmt.Sprintf("module %s is already running in task %d", e.Module, e.Owner)
+}
+
+// Acquire takes the token of module for taskID. Acquiring again from the same task succeeds.
+func Acquire(module string, taskID int64) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if owner, ok := owners[module]; ok && owner != taskID {
+		// Callers may retry every pipeline tick, so they decide how loudly to report the rejection
+		log.Debug().Str("module", module).Int64("taskID", taskID).Int64("owner", owner).Msg("Run rejected, module is busy")
+		return &ErrHeld{Module: module, Owner: owner}
+	}
+	owners[module] = taskID
+	return nil
+}
+
+// Release gives back the token of module if it is held by taskID.
+func Release(module string, taskID int64) {
+	mu.Lock()
+	defer mu.Unlock()
+	if owner, ok := owners[module]; ok && owner == taskID {
+		delete(owners, module)
+	}
+}
+
+// releaseTask gives back every token held by taskID.
+func releaseTask(taskID int64) {
+	mu.Lock()
+	defer mu.Unlock()
+	for module, owner := range owners {
+		if owner == taskID {
+			delete(owners, module)
+			log.Info().Str("module", module).Int64("taskID", taskID).Msg("Run token released on task end")
+		}
+	}
+}
+
+type taskEndSink struct{}
+
+// OnTaskerTask releases tokens when their task ends, including aborted tasks.
+func (taskEndSink) OnTaskerTask(_ *maa.Tasker, status maa.EventStatus, detail maa.TaskerTaskDetail) {
+	if status == maa.EventStatusStarting {
+		return
+	}
+	releaseTask(int64(detail.TaskID))
+}
+
+// EnsureTaskerSink registers the tasker sink that releases tokens on task end.
+func EnsureTaskerSink() {
+	registerSinkOnce.Do(func() {
+		maa.AgentServerAddTaskerSink(taskEndSink{})
+		log.Debug().Msg("Run guard tasker sink registered")
+	})
+}
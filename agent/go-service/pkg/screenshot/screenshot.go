@@ -0,0 +1,51 @@
+// Package screenshot provides a shared helper for capturing a fresh frame from the
+// controller bound to the current task.
+package screenshot
+
+import (
+	"fmt"
+	"image"
+	"sync"
+
+	"github.com/MaaXYZ/maa-framework-go/v4"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	mu sync.Mutex
+	// sizes holds the last captured frame size per controller, used to log resolution changes
+	sizes = map[maa.Controller]image.Point{}
+)
+
+// FreshFrame captures a new frame from the current task controller and waits for it.
+//
+// Callers use it when the frame passed to a recognition or action is known to be stale,
+// e.g. to re-check a screen after a miss, so it never returns a previously captured frame.
+func FreshFrame(ctx *maa.Context) (image.Image, error) {
+	ctrl := ctx.GetTasker().GetController()
+	if ctrl == nil {
+		return nil, fmt.Errorf("controller is nil")
+	}
+
+	ctrl.PostScreencap().Wait()
+	img, err := ctrl.CacheImage()
+	if err != nil {
+		return nil, err
+	}
+	if img == nil {
+		return nil, fmt.Errorf("cached image is nil")
+	}
+
+	size := img.Bounds().Size()
+	mu.Lock()
+	last, ok := sizes[*ctrl]
+	sizes[*ctrl] = size
+	mu.Unlock()
+	if ok && last != size {
+		log.Info().
+			Int("fromW", last.X).Int("fromH", last.Y).
+			Int("toW", size.X).Int("toH", size.Y).
+			Msg("Screenshot resolution changed")
+	}
+	return img, nil
+}
@@ -1,6 +1,9 @@
 package main
 
 import (
+	"os"
+	"strings"
+
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/autoecofarm"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/autofight"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/autosell"
@@ -18,7 +21,9 @@ import (
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/expressionrecognition"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/itemtransfer"
 	maptracker "github.com/MaaXYZ/MaaEnd/agent/go-service/map-tracker"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/metrics"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/resource"
+	"github.com/MaaXYZ/MaaEnd/agent/go-service/pkg/runguard"
 	puzzle "github.com/MaaXYZ/MaaEnd/agent/go-service/puzzle-solver"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/scenemanager"
 	"github.com/MaaXYZ/MaaEnd/agent/go-service/taskersink/aspectratio"
@@ -32,6 +37,7 @@ import (
 func registerAll() {
 	// Resource Sink
 	resource.EnsureResourcePathSink()
+	runguard.EnsureTaskerSink()
 
 	// Pre-Check Custom
 	aspectratio.Register()
@@ -45,23 +51,77 @@ func registerAll() {
 	expressionrecognition.Register()
 	autoaltclick.Register()
 	charactercontroller.Register()
+	metrics.Register()
 
 	// Business Custom
-	autosell.Register()
-	blueprintimport.Register()
-	puzzle.Register()
-	bettersliding.Register()
-	essencefilter.Register()
-	dailyrewards.Register()
-	creditshopping.Register()
-	maptracker.Register()
-	batchaddfriends.Register()
-	autoecofarm.Register()
-	autofight.Register()
-	visitfriends.Register()
-	scenemanager.Register()
-	autostockpile.Register()
-	itemtransfer.Register()
+	enabled := enabledModules()
+	registered := make([]string, 0, len(businessModules))
+	for _, m := range businessModules {
+		if enabled != nil && !enabled[m.name] {
+			continue
+		}
+		m.register()
+		registered = append(registered, m.name)
+	}
 	log.Info().
+		Strs("modules", registered).
+		Bool("allModules", enabled == nil).
 		Msg("All custom components and sinks registered successfully")
 }
+
+// EnvEnabledModules lists business modules to register, separated by commas.
+// Empty or unset means all modules are registered.
+const EnvEnabledModules = "MAAEND_ENABLED_MODULES"
+
+type businessModule struct {
+	name     string
+	register func()
+}
+
+var businessModules = []businessModule{
+	{"autosell", autosell.Register},
+	{"blueprintimport", blueprintimport.Register},
+	{"puzzle-solver", puzzle.Register},
+	{"bettersliding", bettersliding.Register},
+	{"essencefilter", essencefilter.Register},
+	{"dailyrewards", dailyrewards.Register},
+	{"creditshopping", creditshopping.Register},
+	{"map-tracker", maptracker.Register},
+	{"batchaddfriends", batchaddfriends.Register},
+	{"autoecofarm", autoecofarm.Register},
+	{"autofight", autofight.Register},
+	{"visitfriends", visitfriends.Register},
+	{"scenemanager", scenemanager.Register},
+	{"autostockpile", autostockpile.Register},
+	{"itemtransfer", itemtransfer.Register},
+}
+
+// enabledModules parses EnvEnabledModules. It returns nil (enable everything) when the
+// variable is unset, empty, or names no known module.
+func enabledModules() map[string]bool {
+	raw := strings.TrimSpace(os.Getenv(EnvEnabledModules))
+	if raw == "" {
+		return nil
+	}
+	known := make(map[string]bool, len(businessModules))
+	for _, m := range businessModules {
+		known[m.name] = true
+	}
+	enabled := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			log.Warn().Str("module", name).Msg("Unknown module in " + EnvEnabledModules + ", ignored")
+			continue
+		}
+		enabled[name] = true
+	}
+	if len(enabled) == 0 {
+		log.Warn().Str("value", raw).Msg("No known module in " + EnvEnabledModules + ", enable all modules")
+		return nil
+	}
+	return enabled
+}